@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the actuator settings, read from the control loop variables
+// in .env.
+type Config struct {
+	PrometheusURL    string
+	ControlFilePath  string
+	TemplateFilePath string
+	ListenAddr       string
+
+	Interval            time.Duration
+	StabilityPeriod     time.Duration
+	QueryTimeout        time.Duration
+	QueryRetries        int
+	QueryRetryDelay     time.Duration
+	CorrelationIDPrefix string
+
+	// Mode selects the control policy: threshold, pid, cost_budget or rules.
+	Mode       string
+	Thresholds Thresholds
+	// TargetOptimisedTS is the set point for the optimised pipeline's series
+	// count in pid mode; other policies only report it.
+	TargetOptimisedTS float64
+	PID               PIDConfig
+	CostBudget        CostBudgetConfig
+	// RulesFilePath is the rule file of the rules policy.
+	RulesFilePath string
+
+	// JournalPath is the decision journal ("off" disables it); it is rotated
+	// once it would grow past JournalMaxBytes, keeping JournalMaxBackups
+	// rotated files.
+	JournalPath       string
+	JournalMaxBytes   int64
+	JournalMaxBackups int
+
+	// OverrideMaxTTL caps the TTL of manual overrides.
+	OverrideMaxTTL time.Duration
+	// OverrideListenAddr serves /override, apart from the admin endpoints on
+	// ListenAddr. Off loopback it requires OverrideToken, which clients send
+	// as a bearer token.
+	OverrideListenAddr string
+	OverrideToken      string
+
+	Queries KPIQueries
+}
+
+// Thresholds drive the profile decision on the optimised pipeline's series
+// count.
+type Thresholds struct {
+	ConservativeMaxTS float64
+	AggressiveMinTS   float64
+	// HysteresisFactor widens the band a profile has to cross before it is
+	// left, e.g. 0.1 = 10% around the thresholds.
+	HysteresisFactor float64
+	// RiskProcessesLimit is the number of high-risk processes above which the
+	// aggressive profile is forced.
+	RiskProcessesLimit float64
+}
+
+// KPIQueries are the PromQL queries the controller reads every cycle.
+type KPIQueries struct {
+	FullTS          string
+	OptimisedTS     string
+	ExperimentalTS  string
+	ExplosionAlerts string
+	RiskProcesses   string
+}
+
+func loadConfig() (*Config, error) {
+	cfg := &Config{
+		PrometheusURL:       envString("PROMETHEUS_URL", "http://prometheus:9090"),
+		ControlFilePath:     envString("CONTROL_SIGNAL_FILE", "/app/control_signals/optimization_mode.yaml"),
+		TemplateFilePath:    envString("OPT_MODE_TEMPLATE_PATH", "/app/optimization_mode_template.yaml"),
+		ListenAddr:          envString("ACTUATOR_LISTEN_ADDR", ":9100"),
+		OverrideListenAddr:  envString("OVERRIDE_LISTEN_ADDR", defaultOverrideAddr),
+		OverrideToken:       os.Getenv("OVERRIDE_TOKEN"),
+		CorrelationIDPrefix: envString("CORRELATION_ID_PREFIX", "pv3ux"),
+		Mode:                strings.ToLower(envString("ADAPTIVE_CONTROLLER_MODE", modeThreshold)),
+		RulesFilePath:       envString("ADAPTIVE_CONTROLLER_RULES_FILE", "/app/control_signals/policy_rules.yaml"),
+		JournalPath:         envString("DECISION_JOURNAL_FILE", "/app/control_signals/decision_journal.jsonl"),
+		QueryTimeout:        10 * time.Second,
+		QueryRetries:        3,
+		QueryRetryDelay:     2 * time.Second,
+		Queries: KPIQueries{
+			FullTS:          envString("METRIC_FULL_TS_QUERY", `phoenix_observer_kpi_store_phoenix_pipeline_output_cardinality_estimate{phoenix_pipeline_label="full_fidelity",job="otelcol-observer-metrics"}`),
+			OptimisedTS:     envString("METRIC_OPTIMISED_TS_QUERY", `phoenix_observer_kpi_store_phoenix_pipeline_output_cardinality_estimate{phoenix_pipeline_label="optimised",job="otelcol-observer-metrics"}`),
+			ExperimentalTS:  envString("METRIC_EXPERIMENTAL_TS_QUERY", `phoenix_observer_kpi_store_phoenix_pipeline_output_cardinality_estimate{phoenix_pipeline_label="experimental",job="otelcol-observer-metrics"}`),
+			ExplosionAlerts: envString("METRIC_CARDINALITY_EXPLOSION_ALERT", `phoenix_observer_kpi_store_phoenix_cardinality_explosion_alert_count{job="otelcol-observer-metrics"}`),
+			RiskProcesses:   envString("METRIC_CARDINALITY_RISK_QUERY", `phoenix_observer_kpi_store_phoenix_cardinality_risk_processes{alert_type="cardinality_explosion",job="otelcol-observer-metrics"}`),
+		},
+	}
+
+	var err error
+	if cfg.Interval, err = envSeconds("ADAPTIVE_CONTROLLER_INTERVAL_SECONDS", 60); err != nil {
+		return nil, err
+	}
+	if cfg.StabilityPeriod, err = envSeconds("ADAPTIVE_CONTROLLER_STABILITY_SECONDS", 120); err != nil {
+		return nil, err
+	}
+	if cfg.OverrideMaxTTL, err = envSeconds("OVERRIDE_MAX_TTL_SECONDS", 86400); err != nil {
+		return nil, err
+	}
+	if cfg.TargetOptimisedTS, err = envFloat("TARGET_OPTIMIZED_PIPELINE_TS_COUNT", 20000); err != nil {
+		return nil, err
+	}
+	if cfg.Thresholds.ConservativeMaxTS, err = envFloat("THRESHOLD_OPTIMIZATION_CONSERVATIVE_MAX_TS", 15000); err != nil {
+		return nil, err
+	}
+	if cfg.Thresholds.AggressiveMinTS, err = envFloat("THRESHOLD_OPTIMIZATION_AGGRESSIVE_MIN_TS", 25000); err != nil {
+		return nil, err
+	}
+	if cfg.Thresholds.HysteresisFactor, err = envFloat("HYSTERESIS_FACTOR", 0.1); err != nil {
+		return nil, err
+	}
+	if cfg.Thresholds.RiskProcessesLimit, err = envFloat("CARDINALITY_RISK_PROCESSES_LIMIT", 10); err != nil {
+		return nil, err
+	}
+	if cfg.PID.Kp, err = envFloat("PID_KP", 0.2); err != nil {
+		return nil, err
+	}
+	if cfg.PID.Ki, err = envFloat("PID_KI", 0.05); err != nil {
+		return nil, err
+	}
+	if cfg.PID.Kd, err = envFloat("PID_KD", 0.1); err != nil {
+		return nil, err
+	}
+	if cfg.PID.DerivativeFilter, err = envFloat("PID_DERIVATIVE_FILTER_INTERVALS", 2); err != nil {
+		return nil, err
+	}
+	if cfg.PID.BalancedMinOutput, err = envFloat("PID_BALANCED_MIN_OUTPUT", 0.35); err != nil {
+		return nil, err
+	}
+	if cfg.PID.AggressiveMinOutput, err = envFloat("PID_AGGRESSIVE_MIN_OUTPUT", 0.65); err != nil {
+		return nil, err
+	}
+	if cfg.PID.OutputHysteresis, err = envFloat("PID_OUTPUT_HYSTERESIS", 0.05); err != nil {
+		return nil, err
+	}
+	if cfg.PID.TopKMin, err = envInt("PID_TOPK_MIN", 5); err != nil {
+		return nil, err
+	}
+	if cfg.PID.TopKMax, err = envInt("PID_TOPK_MAX", 50); err != nil {
+		return nil, err
+	}
+	if cfg.CostBudget.MonthlyBudgetUSD, err = envFloat("COST_BUDGET_MONTHLY_USD", 0); err != nil {
+		return nil, err
+	}
+	if cfg.CostBudget.SeriesPerUSD, err = envFloat("COST_BUDGET_SERIES_PER_USD", 0); err != nil {
+		return nil, err
+	}
+	if cfg.CostBudget.BalancedHeadroom, err = envFloat("COST_BUDGET_BALANCED_HEADROOM", 0.2); err != nil {
+		return nil, err
+	}
+	journalMaxBytes, err := envInt("DECISION_JOURNAL_MAX_BYTES", 10<<20)
+	if err != nil {
+		return nil, err
+	}
+	cfg.JournalMaxBytes = int64(journalMaxBytes)
+	if cfg.JournalMaxBackups, err = envInt("DECISION_JOURNAL_MAX_BACKUPS", 5); err != nil {
+		return nil, err
+	}
+	cfg.CostBudget.BilledPipelines = envList("COST_BUDGET_BILLED_PIPELINES", []string{pipelineOptimised})
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("ADAPTIVE_CONTROLLER_INTERVAL_SECONDS must be positive")
+	}
+	if c.StabilityPeriod < 0 {
+		return fmt.Errorf("ADAPTIVE_CONTROLLER_STABILITY_SECONDS must not be negative")
+	}
+	if c.Thresholds.ConservativeMaxTS <= 0 || c.Thresholds.AggressiveMinTS <= c.Thresholds.ConservativeMaxTS {
+		return fmt.Errorf("thresholds must satisfy 0 < THRESHOLD_OPTIMIZATION_CONSERVATIVE_MAX_TS (%g) < THRESHOLD_OPTIMIZATION_AGGRESSIVE_MIN_TS (%g)",
+			c.Thresholds.ConservativeMaxTS, c.Thresholds.AggressiveMinTS)
+	}
+	if c.Thresholds.HysteresisFactor < 0 || c.Thresholds.HysteresisFactor >= 1 {
+		return fmt.Errorf("HYSTERESIS_FACTOR must be within [0, 1), got %g", c.Thresholds.HysteresisFactor)
+	}
+	if c.OverrideMaxTTL <= 0 {
+		return fmt.Errorf("OVERRIDE_MAX_TTL_SECONDS must be positive")
+	}
+	if c.OverrideListenAddr == c.ListenAddr {
+		return fmt.Errorf("OVERRIDE_LISTEN_ADDR must differ from ACTUATOR_LISTEN_ADDR (%s)", c.ListenAddr)
+	}
+	if !isLoopback(c.OverrideListenAddr) && c.OverrideToken == "" {
+		return fmt.Errorf("OVERRIDE_TOKEN is required when OVERRIDE_LISTEN_ADDR (%s) is not a loopback address", c.OverrideListenAddr)
+	}
+	if c.JournalMaxBytes <= 0 || c.JournalMaxBackups < 0 {
+		return fmt.Errorf("DECISION_JOURNAL_MAX_BYTES must be positive and DECISION_JOURNAL_MAX_BACKUPS not negative")
+	}
+	switch c.Mode {
+	case modePID:
+		if c.TargetOptimisedTS <= 0 {
+			return fmt.Errorf("TARGET_OPTIMIZED_PIPELINE_TS_COUNT must be positive in pid mode")
+		}
+		return c.PID.validate()
+	case modeCostBudget:
+		return c.CostBudget.validate()
+	}
+	return nil
+}
+
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envList reads a comma-separated list.
+func envList(key string, def []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func envFloat(key string, def float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value '%s': %w", key, v, err)
+	}
+	return f, nil
+}
+
+func envInt(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value '%s': %w", key, v, err)
+	}
+	return n, nil
+}
+
+func envSeconds(key string, def int) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return time.Duration(def) * time.Second, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value '%s': %w", key, v, err)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// isLoopback reports whether addr (host:port) only accepts local connections.
+// An empty host listens on every interface.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
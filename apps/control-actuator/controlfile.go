@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// Optimisation profiles, from least to most aggressive.
+const (
+	profileConservative = controlfile.ProfileConservative
+	profileBalanced     = controlfile.ProfileBalanced
+	profileAggressive   = controlfile.ProfileAggressive
+)
+
+// writeControlFile validates next against the schema, including that
+// config_version increased on prev, and atomically replaces the control file,
+// keeping the template's layout and comments.
+func writeControlFile(cfg *Config, prev, next *controlfile.File) error {
+	if err := controlfile.Validate(next, prev); err != nil {
+		return fmt.Errorf("refusing to write control file: %w", err)
+	}
+	return controlfile.Write(cfg.ControlFilePath, cfg.TemplateFilePath, next)
+}
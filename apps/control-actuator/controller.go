@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// Decision is the outcome of one control cycle.
+type Decision struct {
+	// Proposed is the profile the KPIs call for; Profile is the one applied
+	// after the stability period is taken into account.
+	Proposed string
+	Profile  string
+	Reason   string
+	// Changed is set when Profile differs from the previous profile.
+	Changed bool
+	// Held is set when a change to Proposed was suppressed by the stability
+	// period.
+	Held bool
+	// Emergency is set when cardinality alerts overrode the policy.
+	Emergency bool
+	// Overridden is set when a manual override pinned Profile; Override is
+	// the override record to write, active or not.
+	Overridden bool
+	Override   *controlfile.Override
+	// CostReductionRatio is 1 - optimised/full, clamped to [0, 1].
+	CostReductionRatio float64
+	// PID and Parameters are set by policies that write them.
+	PID        *controlfile.PIDState
+	Parameters *controlfile.AdvancedParameters
+}
+
+// decide picks the profile for this cycle: an active manual override wins,
+// cardinality alerts force the aggressive profile, otherwise policy proposes
+// one, and the stability period may hold the previous profile.
+func decide(ctx context.Context, policy Policy, cfg *Config, prev *controlfile.File, kpis KPIs, now time.Time) (Decision, error) {
+	d := Decision{CostReductionRatio: costReductionRatio(kpis.FullTS, kpis.OptimisedTS)}
+	prevProfile := prev.OptimizationProfile
+
+	// The policy runs even under an emergency override so stateful policies
+	// (PID) stay current for when the override ends
+	res, err := policy.Decide(ctx, PolicyInput{
+		KPIs: Snapshot{
+			FullTS:                     kpis.FullTS,
+			OptimizedTS:                kpis.OptimisedTS,
+			ExperimentalTS:             kpis.ExperimentalTS,
+			CostReductionRatio:         d.CostReductionRatio,
+			CardinalityExplosionAlerts: kpis.ExplosionAlerts,
+			CardinalityRiskProcesses:   kpis.RiskProcesses,
+		},
+		PreviousProfile: prevProfile,
+		Previous:        prev,
+		Now:             now,
+	})
+	if err != nil {
+		return d, fmt.Errorf("%s policy failed: %w", policy.Name(), err)
+	}
+	if !controlfile.IsProfile(res.Profile) {
+		return d, fmt.Errorf("%s policy proposed unknown profile %q", policy.Name(), res.Profile)
+	}
+	d.Proposed, d.PID, d.Parameters = res.Profile, res.PID, res.Parameters
+	reason := res.Reason
+	if profile, emergency, ok := emergencyProfile(cfg.Thresholds, kpis); ok {
+		d.Proposed, reason = profile, emergency
+		d.Emergency = true
+	}
+	d.Profile, d.Reason = d.Proposed, reason
+	// A manual override also bypasses the stability period
+	if applyOverride(&d, prev, now) {
+		return d, nil
+	}
+
+	if d.Proposed == prevProfile {
+		return d, nil
+	}
+	if lastChange := prev.LastProfileChange(); !lastChange.IsZero() {
+		since := now.Sub(lastChange)
+		if since < cfg.StabilityPeriod {
+			d.Profile = prevProfile
+			d.Held = true
+			d.Reason = fmt.Sprintf("Stability hold (%d s < %d s). Maintained '%s'. Original intent: '%s' (%s)",
+				int64(since.Seconds()), int64(cfg.StabilityPeriod.Seconds()), prevProfile, d.Proposed, d.Reason)
+			return d, nil
+		}
+	}
+	d.Changed = true
+	return d, nil
+}
+
+// emergencyProfile reports whether cardinality alerts force the aggressive
+// profile regardless of the series counts.
+func emergencyProfile(th Thresholds, kpis KPIs) (profile, reason string, ok bool) {
+	if kpis.ExplosionAlerts > 0 {
+		return profileAggressive, fmt.Sprintf("EMERGENCY: Cardinality explosion detected (%.0f alerts). Auto-remediation triggered.", kpis.ExplosionAlerts), true
+	}
+	if kpis.RiskProcesses > th.RiskProcessesLimit {
+		return profileAggressive, fmt.Sprintf("HIGH_RISK: Multiple high-risk cardinality processes detected (%.0f). Preventive aggressive optimization.", kpis.RiskProcesses), true
+	}
+	return "", "", false
+}
+
+// proposeProfile compares the optimised pipeline's series count ts with the
+// thresholds, widened by the hysteresis factor around the profile currently
+// in force.
+func proposeProfile(th Thresholds, prev string, ts float64) (profile, reason string) {
+	consMax, aggrMin := th.ConservativeMaxTS, th.AggressiveMinTS
+	consMaxHyst := consMax * (1 + th.HysteresisFactor)
+	aggrMinHyst := aggrMin * (1 - th.HysteresisFactor)
+
+	switch prev {
+	case profileConservative:
+		// Require more evidence to leave the conservative profile
+		if ts <= consMaxHyst {
+			return profileConservative, fmt.Sprintf("Optimised TS (%g) < Conservative Max TS with hysteresis (%g)", ts, consMaxHyst)
+		}
+		if ts > aggrMin {
+			return profileAggressive, fmt.Sprintf("Optimised TS (%g) > Aggressive Min TS (%g)", ts, aggrMin)
+		}
+		return profileBalanced, fmt.Sprintf("Optimised TS (%g) > Conservative Max TS with hysteresis (%g)", ts, consMaxHyst)
+	case profileAggressive:
+		// Require more evidence to leave the aggressive profile
+		if ts >= aggrMinHyst {
+			return profileAggressive, fmt.Sprintf("Optimised TS (%g) > Aggressive Min TS with hysteresis (%g)", ts, aggrMinHyst)
+		}
+		if ts < consMax {
+			return profileConservative, fmt.Sprintf("Optimised TS (%g) < Conservative Max TS (%g)", ts, consMax)
+		}
+		return profileBalanced, fmt.Sprintf("Optimised TS (%g) < Aggressive Min TS with hysteresis (%g)", ts, aggrMinHyst)
+	default:
+		if ts > aggrMin {
+			return profileAggressive, fmt.Sprintf("Optimised TS (%g) > Aggressive Min TS (%g)", ts, aggrMin)
+		}
+		if ts < consMax {
+			return profileConservative, fmt.Sprintf("Optimised TS (%g) < Conservative Max TS (%g)", ts, consMax)
+		}
+		return profileBalanced, fmt.Sprintf("Optimised TS (%g) in balanced range [%g - %g]", ts, consMax, aggrMin)
+	}
+}
+
+// costReductionRatio is 1 - optimised/full truncated to three decimals and
+// clamped to [0, 1]; it is 0 while the full pipeline reports nothing.
+func costReductionRatio(full, optimised float64) float64 {
+	if full <= 0 {
+		return 0
+	}
+	r := math.Trunc((1-optimised/full)*1000) / 1000
+	return math.Max(0, math.Min(1, r))
+}
+
+// nextControlFile is the control file recording decision d, made at now on
+// top of prev.
+func nextControlFile(cfg *Config, prev *controlfile.File, d Decision, kpis KPIs, now time.Time) *controlfile.File {
+	lastChange := prev.LastProfileChangeTimestamp
+	if lastChange == "" {
+		lastChange = controlfile.EpochTimestamp
+	}
+	if d.Changed {
+		lastChange = now.Format(time.RFC3339)
+	}
+	// Policies that do not set the advanced parameters leave them as they
+	// were; the keep lists are operator-set and always carried over
+	params := prev.AdvancedParameters
+	if d.Parameters != nil {
+		params = d.Parameters
+	}
+	version := prev.ConfigVersion + 1
+	return &controlfile.File{
+		SchemaVersion:       controlfile.SchemaVersion,
+		OptimizationProfile: d.Profile,
+		ConfigVersion:       version,
+		CorrelationID:       fmt.Sprintf("%s-%d-v%d", cfg.CorrelationIDPrefix, now.Unix(), version),
+		LastUpdated:         now.Format(time.RFC3339),
+		TriggerReason:       d.Reason,
+		CurrentMetrics: controlfile.CurrentMetrics{
+			FullTS:                     int64(kpis.FullTS),
+			OptimizedTS:                int64(kpis.OptimisedTS),
+			ExperimentalTS:             int64(kpis.ExperimentalTS),
+			CostReductionRatio:         d.CostReductionRatio,
+			CardinalityExplosionAlerts: int64(kpis.ExplosionAlerts),
+			CardinalityRiskProcesses:   int64(kpis.RiskProcesses),
+		},
+		Thresholds: controlfile.Thresholds{
+			ConservativeMaxTS: int64(cfg.Thresholds.ConservativeMaxTS),
+			AggressiveMinTS:   int64(cfg.Thresholds.AggressiveMinTS),
+		},
+		// Full and optimised always run; the experimental pipeline only
+		// under the aggressive profile
+		Pipelines: controlfile.Pipelines{
+			FullFidelityEnabled: true,
+			OptimizedEnabled:    true,
+			ExperimentalEnabled: d.Profile == profileAggressive,
+		},
+		LastProfileChangeTimestamp: lastChange,
+		PIDState:                   d.PID,
+		AdvancedParameters:         params,
+		AttributeKeepLists:         prev.AttributeKeepLists,
+		Override:                   d.Override,
+	}
+}
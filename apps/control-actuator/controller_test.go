@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// testThresholds are the .env defaults: the hysteresis band is 16500 above
+// the conservative threshold and 22500 below the aggressive one.
+var testThresholds = Thresholds{
+	ConservativeMaxTS:  15000,
+	AggressiveMinTS:    25000,
+	HysteresisFactor:   0.1,
+	RiskProcessesLimit: 10,
+}
+
+// The cases follow the branches of the update-control-file.sh script the
+// actuator replaced, at and around each boundary.
+func TestProposeProfile(t *testing.T) {
+	tests := []struct {
+		prev string
+		ts   float64
+		want string
+	}{
+		// Conservative is only left above 15000 * 1.1
+		{profileConservative, 0, profileConservative},
+		{profileConservative, 16500, profileConservative},
+		{profileConservative, 16501, profileBalanced},
+		{profileConservative, 25000, profileBalanced},
+		{profileConservative, 25001, profileAggressive},
+		// Aggressive is only left below 25000 * 0.9
+		{profileAggressive, 100000, profileAggressive},
+		{profileAggressive, 22500, profileAggressive},
+		{profileAggressive, 22499, profileBalanced},
+		{profileAggressive, 15000, profileBalanced},
+		{profileAggressive, 14999, profileConservative},
+		// Balanced uses the bare thresholds
+		{profileBalanced, 14999, profileConservative},
+		{profileBalanced, 15000, profileBalanced},
+		{profileBalanced, 25000, profileBalanced},
+		{profileBalanced, 25001, profileAggressive},
+		// So does a control file without a known profile
+		{"", 14999, profileConservative},
+		{"", 20000, profileBalanced},
+		{"", 25001, profileAggressive},
+	}
+	for _, tt := range tests {
+		got, reason := proposeProfile(testThresholds, tt.prev, tt.ts)
+		if got != tt.want {
+			t.Errorf("proposeProfile(%q, %g) = %q (%s), want %q", tt.prev, tt.ts, got, reason, tt.want)
+		}
+	}
+}
+
+func TestProposeProfileWithoutHysteresis(t *testing.T) {
+	th := testThresholds
+	th.HysteresisFactor = 0
+	tests := []struct {
+		prev string
+		ts   float64
+		want string
+	}{
+		{profileConservative, 15000, profileConservative},
+		{profileConservative, 15001, profileBalanced},
+		{profileAggressive, 25000, profileAggressive},
+		{profileAggressive, 24999, profileBalanced},
+	}
+	for _, tt := range tests {
+		if got, _ := proposeProfile(th, tt.prev, tt.ts); got != tt.want {
+			t.Errorf("proposeProfile(%q, %g) = %q, want %q", tt.prev, tt.ts, got, tt.want)
+		}
+	}
+}
+
+func TestEmergencyProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		kpis       KPIs
+		want       bool
+		wantReason string
+	}{
+		{"none", KPIs{}, false, ""},
+		{"explosion alert", KPIs{ExplosionAlerts: 1}, true, "EMERGENCY: Cardinality explosion detected (1 alerts)."},
+		{"risk at limit", KPIs{RiskProcesses: 10}, false, ""},
+		{"risk above limit", KPIs{RiskProcesses: 11}, true, "HIGH_RISK: Multiple high-risk cardinality processes detected (11)."},
+		{"explosion wins", KPIs{ExplosionAlerts: 2, RiskProcesses: 50}, true, "EMERGENCY:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, reason, ok := emergencyProfile(testThresholds, tt.kpis)
+			if ok != tt.want {
+				t.Fatalf("emergencyProfile() ok = %v, want %v", ok, tt.want)
+			}
+			if !ok {
+				return
+			}
+			if profile != profileAggressive {
+				t.Errorf("emergencyProfile() profile = %q, want aggressive", profile)
+			}
+			if !strings.HasPrefix(reason, tt.wantReason) {
+				t.Errorf("emergencyProfile() reason = %q, want prefix %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestDecide(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cfg := &Config{Thresholds: testThresholds, StabilityPeriod: 120 * time.Second}
+	changedAgo := func(d time.Duration) string { return now.Add(-d).Format(time.RFC3339) }
+
+	tests := []struct {
+		name       string
+		prev       string
+		lastChange string
+		kpis       KPIs
+		wantProp   string
+		want       string
+		changed    bool
+		held       bool
+		emergency  bool
+		wantReason string
+	}{
+		{
+			name: "unchanged", prev: profileBalanced, lastChange: changedAgo(time.Second),
+			kpis: KPIs{OptimisedTS: 20000}, wantProp: profileBalanced, want: profileBalanced,
+		},
+		{
+			name: "first change", prev: profileConservative, lastChange: controlfile.EpochTimestamp,
+			kpis: KPIs{OptimisedTS: 30000}, wantProp: profileAggressive, want: profileAggressive, changed: true,
+		},
+		{
+			name: "unparsable last change", prev: profileConservative, lastChange: "never",
+			kpis: KPIs{OptimisedTS: 30000}, wantProp: profileAggressive, want: profileAggressive, changed: true,
+		},
+		{
+			name: "within stability period", prev: profileConservative, lastChange: changedAgo(119 * time.Second),
+			kpis: KPIs{OptimisedTS: 30000}, wantProp: profileAggressive, want: profileConservative, held: true,
+			wantReason: "Stability hold (119 s < 120 s). Maintained 'conservative'. Original intent: 'aggressive'",
+		},
+		{
+			name: "stability period over", prev: profileConservative, lastChange: changedAgo(120 * time.Second),
+			kpis: KPIs{OptimisedTS: 30000}, wantProp: profileAggressive, want: profileAggressive, changed: true,
+		},
+		{
+			name: "hysteresis keeps profile", prev: profileAggressive, lastChange: controlfile.EpochTimestamp,
+			kpis: KPIs{OptimisedTS: 23000}, wantProp: profileAggressive, want: profileAggressive,
+		},
+		{
+			name: "emergency", prev: profileConservative, lastChange: changedAgo(time.Hour),
+			kpis: KPIs{OptimisedTS: 1000, ExplosionAlerts: 1}, wantProp: profileAggressive, want: profileAggressive,
+			changed: true, emergency: true, wantReason: "EMERGENCY:",
+		},
+		{
+			// As in the script, the stability period holds emergencies too
+			name: "emergency within stability period", prev: profileConservative, lastChange: changedAgo(time.Minute),
+			kpis: KPIs{OptimisedTS: 1000, RiskProcesses: 11}, wantProp: profileAggressive, want: profileConservative,
+			held: true, emergency: true, wantReason: "Stability hold (60 s < 120 s)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := &controlfile.File{OptimizationProfile: tt.prev, LastProfileChangeTimestamp: tt.lastChange}
+			d, err := decide(context.Background(), &thresholdPolicy{th: cfg.Thresholds}, cfg, prev, tt.kpis, now)
+			if err != nil {
+				t.Fatalf("decide() error = %v", err)
+			}
+			if d.Proposed != tt.wantProp || d.Profile != tt.want {
+				t.Errorf("decide() proposed %q applied %q, want %q and %q", d.Proposed, d.Profile, tt.wantProp, tt.want)
+			}
+			if d.Changed != tt.changed || d.Held != tt.held || d.Emergency != tt.emergency {
+				t.Errorf("decide() changed=%v held=%v emergency=%v, want %v %v %v",
+					d.Changed, d.Held, d.Emergency, tt.changed, tt.held, tt.emergency)
+			}
+			if !strings.HasPrefix(d.Reason, tt.wantReason) {
+				t.Errorf("decide() reason = %q, want prefix %q", d.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCostReductionRatio(t *testing.T) {
+	tests := []struct {
+		full, optimised, want float64
+	}{
+		{0, 100, 0},
+		{-1, 100, 0},
+		{1000, 500, 0.5},
+		{3, 2, 0.333},
+		{1000, 2000, 0},
+		{1000, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := costReductionRatio(tt.full, tt.optimised); got != tt.want {
+			t.Errorf("costReductionRatio(%g, %g) = %g, want %g", tt.full, tt.optimised, got, tt.want)
+		}
+	}
+}
+
+func TestNextControlFile(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cfg := &Config{CorrelationIDPrefix: "test", Thresholds: testThresholds}
+	prev := &controlfile.File{
+		OptimizationProfile:        profileBalanced,
+		ConfigVersion:              7,
+		LastProfileChangeTimestamp: "2025-06-01T11:00:00Z",
+	}
+
+	held := nextControlFile(cfg, prev, Decision{Profile: profileBalanced, Held: true}, KPIs{}, now)
+	if held.ConfigVersion != 8 || held.CorrelationID != "test-1748779200-v8" {
+		t.Errorf("version %d correlation %q, want 8 and test-1748779200-v8", held.ConfigVersion, held.CorrelationID)
+	}
+	if held.LastProfileChangeTimestamp != prev.LastProfileChangeTimestamp {
+		t.Errorf("held decision moved last_profile_change_timestamp to %q", held.LastProfileChangeTimestamp)
+	}
+	if held.Pipelines.ExperimentalEnabled {
+		t.Error("experimental pipeline enabled outside the aggressive profile")
+	}
+
+	changed := nextControlFile(cfg, prev, Decision{Profile: profileAggressive, Changed: true}, KPIs{}, now)
+	if changed.LastProfileChangeTimestamp != "2025-06-01T12:00:00Z" {
+		t.Errorf("last_profile_change_timestamp = %q, want the decision time", changed.LastProfileChangeTimestamp)
+	}
+	if !changed.Pipelines.ExperimentalEnabled || !changed.Pipelines.FullFidelityEnabled || !changed.Pipelines.OptimizedEnabled {
+		t.Errorf("pipelines = %+v, want all enabled under aggressive", changed.Pipelines)
+	}
+}
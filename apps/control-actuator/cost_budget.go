@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Pipelines whose series counts can be billed.
+const (
+	pipelineFullFidelity = "full_fidelity"
+	pipelineOptimised    = "optimised"
+	pipelineExperimental = "experimental"
+)
+
+// CostBudgetConfig keeps the exported series within what a monthly budget
+// buys.
+type CostBudgetConfig struct {
+	// MonthlyBudgetUSD is the spend the exported series must stay under.
+	MonthlyBudgetUSD float64
+	// SeriesPerUSD is how many active series one dollar buys per month.
+	SeriesPerUSD float64
+	// BalancedHeadroom is the fraction of the budget below it where the
+	// balanced profile already starts, e.g. 0.2 = from 80% of the budget.
+	BalancedHeadroom float64
+	// BilledPipelines are the pipelines whose series are exported and paid
+	// for (full_fidelity, optimised, experimental).
+	BilledPipelines []string
+}
+
+func (c *CostBudgetConfig) validate() error {
+	if c.MonthlyBudgetUSD <= 0 || c.SeriesPerUSD <= 0 {
+		return fmt.Errorf("cost_budget mode needs positive COST_BUDGET_MONTHLY_USD and COST_BUDGET_SERIES_PER_USD")
+	}
+	if c.BalancedHeadroom <= 0 || c.BalancedHeadroom >= 1 {
+		return fmt.Errorf("COST_BUDGET_BALANCED_HEADROOM must be within (0, 1), got %g", c.BalancedHeadroom)
+	}
+	if len(c.BilledPipelines) == 0 {
+		return fmt.Errorf("COST_BUDGET_BILLED_PIPELINES must name at least one pipeline")
+	}
+	for _, p := range c.BilledPipelines {
+		switch p {
+		case pipelineFullFidelity, pipelineOptimised, pipelineExperimental:
+		default:
+			return fmt.Errorf("unknown pipeline %q in COST_BUDGET_BILLED_PIPELINES (supported: full_fidelity, optimised, experimental)", p)
+		}
+	}
+	return nil
+}
+
+// costBudgetPolicy projects the monthly spend of the billed pipelines'
+// series: over budget calls for the aggressive profile, within the headroom
+// below it for balanced, and conservative otherwise. Like the threshold
+// policy, the band of the current profile is widened by the hysteresis factor.
+type costBudgetPolicy struct {
+	cfg        CostBudgetConfig
+	hysteresis float64
+}
+
+func (p *costBudgetPolicy) Name() string { return modeCostBudget }
+
+func (p *costBudgetPolicy) Decide(_ context.Context, in PolicyInput) (PolicyResult, error) {
+	var series float64
+	for _, pipeline := range p.cfg.BilledPipelines {
+		switch pipeline {
+		case pipelineFullFidelity:
+			series += in.KPIs.FullTS
+		case pipelineOptimised:
+			series += in.KPIs.OptimizedTS
+		case pipelineExperimental:
+			series += in.KPIs.ExperimentalTS
+		}
+	}
+	spend := series / p.cfg.SeriesPerUSD
+	budget := p.cfg.MonthlyBudgetUSD
+	balancedFrom := budget * (1 - p.cfg.BalancedHeadroom)
+
+	lo, hi := balancedFrom, budget
+	switch in.PreviousProfile {
+	case profileConservative:
+		lo *= 1 + p.hysteresis
+	case profileAggressive:
+		hi *= 1 - p.hysteresis
+	}
+	profile := profileConservative
+	switch {
+	case spend > hi:
+		profile = profileAggressive
+	case spend > lo:
+		profile = profileBalanced
+	}
+	return PolicyResult{
+		Profile: profile,
+		Reason: fmt.Sprintf("Projected spend $%.2f/month for %.0f series (%s) vs budget $%.2f (balanced from $%.2f)",
+			spend, series, strings.Join(p.cfg.BilledPipelines, "+"), budget, balancedFrom),
+	}, nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// JournalEntry records one control cycle in the decision journal.
+type JournalEntry struct {
+	Time          time.Time `json:"time"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	ConfigVersion int       `json:"config_version,omitempty"`
+	Policy        string    `json:"policy"`
+
+	Inputs     JournalInputs     `json:"inputs"`
+	Thresholds JournalThresholds `json:"thresholds"`
+
+	PreviousProfile string `json:"previous_profile,omitempty"`
+	// ProposedProfile is what the policy (or a cardinality emergency) called
+	// for; EffectiveProfile is what was written after the stability period.
+	ProposedProfile  string                `json:"proposed_profile,omitempty"`
+	EffectiveProfile string                `json:"effective_profile,omitempty"`
+	Reason           string                `json:"reason,omitempty"`
+	Changed          bool                  `json:"changed"`
+	StabilityHold    bool                  `json:"stability_hold"`
+	Emergency        bool                  `json:"emergency"`
+	Overridden       bool                  `json:"overridden"`
+	PID              *controlfile.PIDState `json:"pid,omitempty"`
+
+	// Written reports whether the control file was updated; Error is why the
+	// cycle failed otherwise.
+	Written         bool    `json:"written"`
+	Error           string  `json:"error,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// JournalInputs are the KPIs a cycle decided on.
+type JournalInputs struct {
+	FullTS                     float64 `json:"full_ts"`
+	OptimizedTS                float64 `json:"optimized_ts"`
+	ExperimentalTS             float64 `json:"experimental_ts"`
+	CardinalityExplosionAlerts float64 `json:"cardinality_explosion_alerts"`
+	CardinalityRiskProcesses   float64 `json:"cardinality_risk_processes"`
+	CostReductionRatio         float64 `json:"cost_reduction_ratio"`
+	// UnavailableKPIs is the number of KPIs that fell back to the previous
+	// control file's value.
+	UnavailableKPIs int `json:"unavailable_kpis"`
+}
+
+// JournalThresholds are the settings in force for a cycle.
+type JournalThresholds struct {
+	ConservativeMaxTS      float64 `json:"conservative_max_ts"`
+	AggressiveMinTS        float64 `json:"aggressive_min_ts"`
+	HysteresisFactor       float64 `json:"hysteresis_factor"`
+	RiskProcessesLimit     float64 `json:"risk_processes_limit"`
+	StabilityPeriodSeconds float64 `json:"stability_period_seconds"`
+	TargetOptimizedTS      float64 `json:"target_optimized_ts"`
+}
+
+func journalThresholds(cfg *Config) JournalThresholds {
+	return JournalThresholds{
+		ConservativeMaxTS:      cfg.Thresholds.ConservativeMaxTS,
+		AggressiveMinTS:        cfg.Thresholds.AggressiveMinTS,
+		HysteresisFactor:       cfg.Thresholds.HysteresisFactor,
+		RiskProcessesLimit:     cfg.Thresholds.RiskProcessesLimit,
+		StabilityPeriodSeconds: cfg.StabilityPeriod.Seconds(),
+		TargetOptimizedTS:      cfg.TargetOptimisedTS,
+	}
+}
+
+// journal is the append-only decision journal: one JSON object per line,
+// rotated by size into path.1 ... path.N (path.1 is the newest).
+type journal struct {
+	mu         sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+}
+
+// Query limits of the /decisions endpoint.
+const (
+	defaultJournalQueryLimit = 500
+	maxJournalQueryLimit     = 10000
+)
+
+// newJournal returns nil when the journal is disabled.
+func newJournal(cfg *Config) *journal {
+	if cfg.JournalPath == "" || cfg.JournalPath == "off" {
+		return nil
+	}
+	return &journal{path: cfg.JournalPath, maxBytes: cfg.JournalMaxBytes, maxBackups: cfg.JournalMaxBackups}
+}
+
+func (j *journal) append(e *JournalEntry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(e); err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+	line := buf.Bytes()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if fi, err := os.Stat(j.path); err == nil && fi.Size() > 0 && fi.Size()+int64(len(line)) > j.maxBytes {
+		if err := j.rotate(); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open decision journal: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to append to decision journal: %w", err)
+	}
+	return f.Close()
+}
+
+// rotate shifts path.i to path.i+1, dropping the oldest, and path to path.1.
+func (j *journal) rotate() error {
+	if j.maxBackups <= 0 {
+		if err := os.Remove(j.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate decision journal: %w", err)
+		}
+		return nil
+	}
+	for i := j.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(j.backup(i), j.backup(i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate decision journal: %w", err)
+		}
+	}
+	if err := os.Rename(j.path, j.backup(1)); err != nil {
+		return fmt.Errorf("failed to rotate decision journal: %w", err)
+	}
+	return nil
+}
+
+func (j *journal) backup(i int) string {
+	return j.path + "." + strconv.Itoa(i)
+}
+
+// journalQuery selects entries; zero fields match everything.
+type journalQuery struct {
+	From, To      time.Time
+	CorrelationID string
+	// Limit keeps the newest matching entries.
+	Limit int
+}
+
+func (q journalQuery) matches(e *JournalEntry) bool {
+	if q.CorrelationID != "" && e.CorrelationID != q.CorrelationID {
+		return false
+	}
+	if !q.From.IsZero() && e.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && e.Time.After(q.To) {
+		return false
+	}
+	return true
+}
+
+// query returns the matching entries, oldest first, across the rotated
+// files. Lines that do not parse are skipped.
+func (j *journal) query(q journalQuery) ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	files := []string{j.path}
+	for i := 1; i <= j.maxBackups; i++ {
+		files = append([]string{j.backup(i)}, files...)
+	}
+	var out []JournalEntry
+	for _, path := range files {
+		f, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read decision journal: %w", err)
+		}
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for sc.Scan() {
+			var e JournalEntry
+			if json.Unmarshal(sc.Bytes(), &e) != nil || !q.matches(&e) {
+				continue
+			}
+			out = append(out, e)
+			if q.Limit > 0 && len(out) > q.Limit {
+				out = out[1:]
+			}
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read decision journal %s: %w", path, err)
+		}
+	}
+	return out, nil
+}
+
+// serveDecisions answers GET /decisions?from=&to=&correlation_id=&limit=,
+// where from and to are RFC3339 or unix seconds.
+func (j *journal) serveDecisions(w http.ResponseWriter, r *http.Request) {
+	if j == nil {
+		http.Error(w, "decision journal is disabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params := r.URL.Query()
+	q := journalQuery{CorrelationID: params.Get("correlation_id"), Limit: defaultJournalQueryLimit}
+	var err error
+	if q.From, err = parseQueryTime(params.Get("from")); err != nil {
+		http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.To, err = parseQueryTime(params.Get("to")); err != nil {
+		http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if v := params.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 || q.Limit > maxJournalQueryLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxJournalQueryLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := j.query(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []JournalEntry{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Count     int            `json:"count"`
+		Decisions []JournalEntry `json:"decisions"`
+	}{len(entries), entries})
+}
+
+func parseQueryTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.UnixMilli(int64(secs * 1000)).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var journalStart = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func journalEntry(i int) *JournalEntry {
+	return &JournalEntry{
+		Time:          journalStart.Add(time.Duration(i) * time.Minute),
+		CorrelationID: fmt.Sprintf("cid-%02d", i),
+		Policy:        "rules",
+	}
+}
+
+// newTestJournal returns a journal whose files hold perFile entries.
+func newTestJournal(t *testing.T, perFile, maxBackups int) *journal {
+	line, err := json.Marshal(journalEntry(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &journal{
+		path:       filepath.Join(t.TempDir(), "decisions.jsonl"),
+		maxBytes:   int64(perFile * (len(line) + 1)),
+		maxBackups: maxBackups,
+	}
+}
+
+// ids returns the correlation IDs of the entries in the file at path, or
+// "missing".
+func ids(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "missing"
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e JournalEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		got = append(got, e.CorrelationID)
+	}
+	return strings.Join(got, " ")
+}
+
+func TestJournalRotation(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxBackups int
+		want       []string // path, path.1, ...
+	}{
+		{"backups shift and the oldest is dropped", 2, []string{"cid-09", "cid-06 cid-07 cid-08", "cid-03 cid-04 cid-05", "missing"}},
+		{"no backups", 0, []string{"cid-09", "missing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newTestJournal(t, 3, tt.maxBackups)
+			for i := 0; i < 10; i++ {
+				if err := j.append(journalEntry(i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i, want := range tt.want {
+				path := j.path
+				if i > 0 {
+					path = j.backup(i)
+				}
+				if got := ids(t, path); got != want {
+					t.Errorf("%s = %s, want %s", filepath.Base(path), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestJournalQuery(t *testing.T) {
+	j := newTestJournal(t, 3, 2)
+	for i := 0; i < 10; i++ {
+		if err := j.append(journalEntry(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A torn line in a rotated file is skipped
+	f, err := os.OpenFile(j.backup(1), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"time": "2025-06`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	at := func(i int) time.Time { return journalStart.Add(time.Duration(i) * time.Minute) }
+	tests := []struct {
+		name string
+		q    journalQuery
+		want string
+	}{
+		{"everything, across rotated files", journalQuery{}, "cid-03 cid-04 cid-05 cid-06 cid-07 cid-08 cid-09"},
+		{"from and to are inclusive", journalQuery{From: at(4), To: at(7)}, "cid-04 cid-05 cid-06 cid-07"},
+		{"from only", journalQuery{From: at(8)}, "cid-08 cid-09"},
+		{"correlation ID", journalQuery{CorrelationID: "cid-05"}, "cid-05"},
+		{"dropped with the oldest file", journalQuery{CorrelationID: "cid-01"}, ""},
+		{"limit keeps the newest", journalQuery{Limit: 2}, "cid-08 cid-09"},
+		{"limit after filtering", journalQuery{To: at(6), Limit: 3}, "cid-04 cid-05 cid-06"},
+	}
+	for _, tt := range tests {
+		entries, err := j.query(tt.q)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.CorrelationID)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestServeDecisions(t *testing.T) {
+	j := newTestJournal(t, 100, 1)
+	for i := 0; i < 5; i++ {
+		if err := j.append(journalEntry(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		query     string
+		wantCode  int
+		wantCount int
+	}{
+		{"", http.StatusOK, 5},
+		{fmt.Sprintf("from=%d&limit=1", journalStart.Add(3*time.Minute).Unix()), http.StatusOK, 1},
+		{"to=" + journalStart.Add(time.Minute).Format(time.RFC3339), http.StatusOK, 2},
+		{"correlation_id=none", http.StatusOK, 0},
+		{"limit=0", http.StatusBadRequest, 0},
+		{"from=yesterday", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		j.serveDecisions(rec, httptest.NewRequest(http.MethodGet, "/decisions?"+tt.query, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%q: status %d, want %d", tt.query, rec.Code, tt.wantCode)
+			continue
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+		var body struct {
+			Count     int            `json:"count"`
+			Decisions []JournalEntry `json:"decisions"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%q: %v", tt.query, err)
+		}
+		if body.Count != tt.wantCount || len(body.Decisions) != tt.wantCount {
+			t.Errorf("%q: %d decisions, want %d", tt.query, body.Count, tt.wantCount)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Command control-actuator is the Phoenix adaptive controller. Every interval
+// it reads the pipeline KPIs from Prometheus, picks an optimisation profile
+// with the configured control policy and the stability period, and atomically
+// rewrites the control file that otelcol-main watches.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io/fs"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+type actuator struct {
+	// mu serialises control cycles and manual overrides, which both rewrite
+	// the control file.
+	mu sync.Mutex
+
+	cfg     *Config
+	kpis    *kpiSource
+	policy  Policy
+	ready   *readiness
+	journal *journal
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "simulate":
+			os.Exit(runSimulate(os.Args[2:]))
+		case "override":
+			os.Exit(runOverrideCommand(os.Args[2:]))
+		}
+	}
+
+	once := flag.Bool("once", false, "Run a single control cycle and exit (non-zero on failure)")
+	flag.Parse()
+
+	log.Println("INFO (Actuator): Phoenix control-loop actuator starting up...")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("ERROR (Actuator): Invalid configuration: %v", err)
+	}
+	src, err := newKPISource(cfg)
+	if err != nil {
+		log.Fatalf("ERROR (Actuator): %v", err)
+	}
+	policy, err := newPolicy(cfg, src.queryVector)
+	if err != nil {
+		log.Fatalf("ERROR (Actuator): Failed to set up control policy: %v", err)
+	}
+	a := &actuator{cfg: cfg, kpis: src, policy: policy, ready: &readiness{maxAge: 3 * cfg.Interval}, journal: newJournal(cfg)}
+
+	log.Printf("INFO (Actuator): Control file: %s, template: %s", cfg.ControlFilePath, cfg.TemplateFilePath)
+	log.Printf("INFO (Actuator): Optimised TS thresholds -> conservative max: %g, aggressive min: %g, hysteresis: %g",
+		cfg.Thresholds.ConservativeMaxTS, cfg.Thresholds.AggressiveMinTS, cfg.Thresholds.HysteresisFactor)
+	log.Printf("INFO (Actuator): Interval: %s, stability period: %s, policy: %s", cfg.Interval, cfg.StabilityPeriod, policy.Name())
+	if a.journal != nil {
+		log.Printf("INFO (Actuator): Decision journal: %s (rotated at %d bytes, %d backups kept)", cfg.JournalPath, cfg.JournalMaxBytes, cfg.JournalMaxBackups)
+	}
+	switch cfg.Mode {
+	case modePID:
+		log.Printf("INFO (Actuator): PID target: %g, Kp: %g, Ki: %g, Kd: %g, output bands: [%g, %g)",
+			cfg.TargetOptimisedTS, cfg.PID.Kp, cfg.PID.Ki, cfg.PID.Kd, cfg.PID.BalancedMinOutput, cfg.PID.AggressiveMinOutput)
+	case modeCostBudget:
+		log.Printf("INFO (Actuator): Cost budget: $%g/month at %g series per $, billed pipelines: %v",
+			cfg.CostBudget.MonthlyBudgetUSD, cfg.CostBudget.SeriesPerUSD, cfg.CostBudget.BilledPipelines)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *once {
+		if err := a.runCycle(ctx); err != nil {
+			log.Printf("ERROR (Actuator): Control cycle failed: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	srv := &http.Server{Addr: cfg.ListenAddr, Handler: newAdminMux(a.ready, a.journal), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		log.Printf("INFO (Actuator): HTTP server listening on %s", cfg.ListenAddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("ERROR (Actuator): HTTP server failed: %v", err)
+		}
+	}()
+	overrideSrv := &http.Server{Addr: cfg.OverrideListenAddr, Handler: a.newOverrideMux(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		log.Printf("INFO (Actuator): Override endpoint listening on %s (token required: %t)", cfg.OverrideListenAddr, cfg.OverrideToken != "")
+		if err := overrideSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("ERROR (Actuator): Override server failed: %v", err)
+		}
+	}()
+
+	a.run(ctx)
+
+	log.Println("INFO (Actuator): Shutdown signal received, stopping.")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(shutdownCtx)
+	_ = overrideSrv.Shutdown(shutdownCtx)
+	log.Println("INFO (Actuator): Shutdown complete.")
+}
+
+// run executes a control cycle immediately and then every interval until ctx
+// is cancelled. A cycle in progress is allowed to finish.
+func (a *actuator) run(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := a.runCycle(ctx); err != nil {
+			log.Printf("ERROR (Actuator): Control cycle failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *actuator) runCycle(ctx context.Context) (err error) {
+	started := time.Now()
+	entry := &JournalEntry{
+		Time:       started.UTC().Truncate(time.Second),
+		Policy:     a.policy.Name(),
+		Thresholds: journalThresholds(a.cfg),
+	}
+	defer func() {
+		cycleDuration.Observe(time.Since(started).Seconds())
+		a.record(entry, err, time.Since(started))
+		if err != nil {
+			cyclesTotal.WithLabelValues("failure").Inc()
+			return
+		}
+		cyclesTotal.WithLabelValues("success").Inc()
+		a.ready.markSuccess(time.Now())
+	}()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// 1. Previous state
+	prev := a.readPrevious()
+
+	// 2. KPIs
+	kpis, failed := a.kpis.fetch(ctx, prev)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	entry.Inputs = JournalInputs{
+		FullTS:                     kpis.FullTS,
+		OptimizedTS:                kpis.OptimisedTS,
+		ExperimentalTS:             kpis.ExperimentalTS,
+		CardinalityExplosionAlerts: kpis.ExplosionAlerts,
+		CardinalityRiskProcesses:   kpis.RiskProcesses,
+		UnavailableKPIs:            failed,
+	}
+	log.Printf("INFO (Actuator): KPIs - full_ts: %.0f, optimized_ts: %.0f, experimental_ts: %.0f, explosion alerts: %.0f, high-risk processes: %.0f (%d unavailable)",
+		kpis.FullTS, kpis.OptimisedTS, kpis.ExperimentalTS, kpis.ExplosionAlerts, kpis.RiskProcesses, failed)
+
+	// 3. Decision
+	now := time.Now().UTC().Truncate(time.Second)
+	entry.Time, entry.PreviousProfile = now, prev.OptimizationProfile
+	d, err := decide(ctx, a.policy, a.cfg, prev, kpis, now)
+	if err != nil {
+		return err
+	}
+	entry.Inputs.CostReductionRatio = d.CostReductionRatio
+	entry.ProposedProfile, entry.EffectiveProfile, entry.Reason = d.Proposed, d.Profile, d.Reason
+	entry.Changed, entry.StabilityHold, entry.Emergency, entry.PID = d.Changed, d.Held, d.Emergency, d.PID
+	entry.Overridden = d.Overridden
+	recordKPIs(kpis, d.CostReductionRatio)
+
+	switch {
+	case d.Overridden:
+		if d.Changed {
+			profileChangesTotal.Inc()
+		}
+		log.Printf("INFO (Actuator): Profile '%s': %s", d.Profile, d.Reason)
+	case d.Held:
+		stabilityHoldsTotal.Inc()
+		log.Printf("INFO (Actuator): %s", d.Reason)
+	case d.Changed:
+		profileChangesTotal.Inc()
+		log.Printf("INFO (Actuator): Profile changing from '%s' to '%s': %s", prev.OptimizationProfile, d.Profile, d.Reason)
+	default:
+		log.Printf("INFO (Actuator): Profile '%s' unchanged: %s", d.Profile, d.Reason)
+	}
+
+	// 4. Control file
+	next := nextControlFile(a.cfg, prev, d, kpis, now)
+	version := next.ConfigVersion
+	entry.CorrelationID, entry.ConfigVersion = next.CorrelationID, version
+	if err := writeControlFile(a.cfg, prev, next); err != nil {
+		return err
+	}
+	entry.Written = true
+	recordProfile(next.OptimizationProfile)
+	recordOverride(next.Override, now)
+	configVersion.Set(float64(version))
+	log.Printf("INFO (Actuator): Control file updated - profile: %s, version: %d, correlation: %s, experimental enabled: %t",
+		next.OptimizationProfile, version, next.CorrelationID, next.Pipelines.ExperimentalEnabled)
+	return nil
+}
+
+// readPrevious loads the control file; a missing or malformed file starts
+// from the defaults. A malformed file keeps the highest config_version that
+// can be recovered so the next write still increases it.
+func (a *actuator) readPrevious() *controlfile.File {
+	prev, version, err := controlfile.Read(a.cfg.ControlFilePath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		log.Printf("INFO (Actuator): Control file %s not found. Initializing with default previous state.", a.cfg.ControlFilePath)
+		return controlfile.Default()
+	case err != nil:
+		prev = controlfile.Default()
+		prev.ConfigVersion = a.recoverConfigVersion()
+		log.Printf("WARN (Actuator): %v. Using default previous state at config_version %d.", err, prev.ConfigVersion)
+		return prev
+	case version < controlfile.SchemaVersion:
+		log.Printf("INFO (Actuator): Control file is schema version %d; it will be rewritten as version %d.", version, controlfile.SchemaVersion)
+	}
+	if prev.ConfigVersion < 0 {
+		prev.ConfigVersion = 0
+	}
+	return prev
+}
+
+// configVersionLine matches config_version in a file too malformed to parse.
+var configVersionLine = regexp.MustCompile(`(?m)^config_version:[ \t]*["']?(\d+)`)
+
+// recoverConfigVersion returns the highest config_version in the raw control
+// file or among the written entries of the decision journal.
+func (a *actuator) recoverConfigVersion() int {
+	version := 0
+	if data, err := os.ReadFile(a.cfg.ControlFilePath); err == nil {
+		for _, m := range configVersionLine.FindAllSubmatch(data, -1) {
+			if v, err := strconv.Atoi(string(m[1])); err == nil {
+				version = max(version, v)
+			}
+		}
+	}
+	if a.journal != nil {
+		entries, err := a.journal.query(journalQuery{})
+		if err != nil {
+			log.Printf("WARN (Actuator): %v", err)
+		}
+		for _, e := range entries {
+			if e.Written {
+				version = max(version, e.ConfigVersion)
+			}
+		}
+	}
+	return version
+}
+
+// record appends the cycle's entry to the decision journal. A journal that
+// cannot be written is logged but does not fail the cycle.
+func (a *actuator) record(entry *JournalEntry, err error, took time.Duration) {
+	if a.journal == nil {
+		return
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	entry.DurationSeconds = math.Round(took.Seconds()*1000) / 1000
+	if err := a.journal.append(entry); err != nil {
+		journalWriteFailuresTotal.Inc()
+		log.Printf("WARN (Actuator): %v", err)
+	}
+}
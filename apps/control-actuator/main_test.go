@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A malformed control file must not reset config_version, or the next write
+// would go backwards.
+func TestReadPreviousConfigVersion(t *testing.T) {
+	const malformed = "schema_version: 2\noptimization_profile: balanced\nconfig_version: 41\npipelines: [unclosed\n"
+	tests := []struct {
+		name    string
+		file    string // "" = no control file
+		journal []int  // config_version of written journal entries; negative = not written
+		want    int
+	}{
+		{"missing file", "", nil, 0},
+		{"valid file", "schema_version: 2\noptimization_profile: balanced\nconfig_version: 7\n", []int{9}, 7},
+		{"malformed file", malformed, nil, 41},
+		{"journal ahead of the file", malformed, []int{40, 50, -60}, 50},
+		{"nothing recoverable from the file", "config_version: [", []int{12, -15}, 12},
+		{"nothing recoverable", "{{{", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := &Config{
+				ControlFilePath:   filepath.Join(dir, "optimization_mode.yaml"),
+				JournalPath:       filepath.Join(dir, "decisions.jsonl"),
+				JournalMaxBytes:   1 << 20,
+				JournalMaxBackups: 1,
+			}
+			if tt.file != "" {
+				if err := os.WriteFile(cfg.ControlFilePath, []byte(tt.file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			a := &actuator{cfg: cfg, journal: newJournal(cfg)}
+			for i, v := range tt.journal {
+				e := journalEntry(i)
+				e.ConfigVersion, e.Written = v, v > 0
+				if v < 0 {
+					e.ConfigVersion = -v
+				}
+				if err := a.journal.append(e); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := a.readPrevious().ConfigVersion; got != tt.want {
+				t.Errorf("readPrevious() config_version = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
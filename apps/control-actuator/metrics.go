@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// Actuator self-telemetry, served at /metrics on the listen address.
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	cyclesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "phoenix_actuator_cycles_total",
+		Help: "Control cycles run, by result (success, failure).",
+	}, []string{"result"})
+	profileInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "phoenix_actuator_profile",
+		Help: "1 for the optimisation profile currently in force, 0 for the others.",
+	}, []string{"profile"})
+	configVersion = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "phoenix_actuator_config_version",
+		Help: "config_version of the last control file written.",
+	})
+	kpiValue = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "phoenix_actuator_kpi",
+		Help: "KPI values used by the latest control cycle.",
+	}, []string{"kpi"})
+	profileChangesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "phoenix_actuator_profile_changes_total",
+		Help: "Optimisation profile changes written to the control file.",
+	})
+	stabilityHoldsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "phoenix_actuator_stability_holds_total",
+		Help: "Profile changes suppressed by the stability period.",
+	})
+	queryFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "phoenix_actuator_query_failures_total",
+		Help: "KPI queries that failed after all retries and fell back to the previous value, by KPI.",
+	}, []string{"kpi"})
+	cycleDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "phoenix_actuator_cycle_duration_seconds",
+		Help:    "Duration of control cycles, including Prometheus queries.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+	})
+	pidTerms = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "phoenix_actuator_pid",
+		Help: "PID controller state in pid mode, by term (output, error, integral, derivative).",
+	}, []string{"term"})
+	overrideActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "phoenix_actuator_override_active",
+		Help: "1 while a manual override pins the optimisation profile.",
+	})
+	overrideExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "phoenix_actuator_override_expiry_timestamp_seconds",
+		Help: "Unix time the active manual override expires; 0 when none is active.",
+	})
+	journalWriteFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "phoenix_actuator_journal_write_failures_total",
+		Help: "Decision journal entries that could not be written.",
+	})
+	lastSuccessTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "phoenix_actuator_last_success_timestamp_seconds",
+		Help: "Unix time of the last control cycle that wrote the control file.",
+	})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		cyclesTotal, profileInfo, configVersion, kpiValue, profileChangesTotal,
+		stabilityHoldsTotal, queryFailuresTotal, cycleDuration, pidTerms, lastSuccessTimestamp,
+		overrideActive, overrideExpiry, journalWriteFailuresTotal,
+	)
+	cyclesTotal.WithLabelValues("success")
+	cyclesTotal.WithLabelValues("failure")
+}
+
+func recordProfile(profile string) {
+	for _, p := range []string{profileConservative, profileBalanced, profileAggressive} {
+		v := 0.0
+		if p == profile {
+			v = 1
+		}
+		profileInfo.WithLabelValues(p).Set(v)
+	}
+}
+
+func recordOverride(o *controlfile.Override, now time.Time) {
+	if !o.ActiveAt(now) {
+		overrideActive.Set(0)
+		overrideExpiry.Set(0)
+		return
+	}
+	expires, _ := time.Parse(time.RFC3339, o.ExpiresAt)
+	overrideActive.Set(1)
+	overrideExpiry.Set(float64(expires.Unix()))
+}
+
+func recordKPIs(kpis KPIs, costReduction float64) {
+	kpiValue.WithLabelValues("full_ts").Set(kpis.FullTS)
+	kpiValue.WithLabelValues("optimized_ts").Set(kpis.OptimisedTS)
+	kpiValue.WithLabelValues("experimental_ts").Set(kpis.ExperimentalTS)
+	kpiValue.WithLabelValues("cardinality_explosion_alerts").Set(kpis.ExplosionAlerts)
+	kpiValue.WithLabelValues("cardinality_risk_processes").Set(kpis.RiskProcesses)
+	kpiValue.WithLabelValues("cost_reduction_ratio").Set(costReduction)
+}
+
+func recordPID(state *controlfile.PIDState, relErr float64) {
+	pidTerms.WithLabelValues("output").Set(state.Output)
+	pidTerms.WithLabelValues("error").Set(relErr)
+	pidTerms.WithLabelValues("integral").Set(state.Integral)
+	pidTerms.WithLabelValues("derivative").Set(state.Derivative)
+}
+
+// readiness tracks when the control file was last written successfully.
+type readiness struct {
+	mu          sync.Mutex
+	lastSuccess time.Time
+	maxAge      time.Duration
+}
+
+func (r *readiness) markSuccess(t time.Time) {
+	r.mu.Lock()
+	r.lastSuccess = t
+	r.mu.Unlock()
+	lastSuccessTimestamp.Set(float64(t.Unix()))
+}
+
+// ready reports whether a cycle succeeded recently enough that the control
+// file can be trusted to reflect current KPIs.
+func (r *readiness) ready(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lastSuccess.IsZero() {
+		return fmt.Errorf("no successful control cycle yet")
+	}
+	if age := now.Sub(r.lastSuccess); age > r.maxAge {
+		return fmt.Errorf("last successful control cycle was %s ago", age.Round(time.Second))
+	}
+	return nil
+}
+
+// newAdminMux serves /metrics, /healthz (liveness), /readyz (a control
+// cycle succeeded within the last few intervals) and /decisions (the
+// decision journal).
+func newAdminMux(ready *readiness, j *journal) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := ready.ready(time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/decisions", j.serveDecisions)
+	return mux
+}
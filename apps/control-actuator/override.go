@@ -0,0 +1,243 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// endOverride returns a copy of o marked inactive. Overrides are set through
+// POST /override (or `control-actuator override set`) and kept in the control
+// file, so they survive actuator restarts.
+func endOverride(o *controlfile.Override) *controlfile.Override {
+	ended := *o
+	ended.Active = false
+	return &ended
+}
+
+// applyOverride lets an active override in prev replace decision d, and
+// retires an override that has expired. It reports whether d was replaced.
+func applyOverride(d *Decision, prev *controlfile.File, now time.Time) bool {
+	o := prev.Override
+	if o == nil {
+		return false
+	}
+	if !o.ActiveAt(now) {
+		if o.Active {
+			log.Printf("INFO (Actuator): Manual override by %s (%s) expired at %s. Automatic control resumed.", o.By, o.Profile, o.ExpiresAt)
+			o = endOverride(o)
+		}
+		d.Override = o
+		return false
+	}
+	d.Override = o
+	d.Overridden = true
+	d.Reason = fmt.Sprintf("Manual override by %s until %s: %s (automatic: '%s')", o.By, o.ExpiresAt, o.Reason, d.Proposed)
+	d.Profile = o.Profile
+	d.Changed = o.Profile != prev.OptimizationProfile
+	return true
+}
+
+// defaultOverrideAddr keeps /override reachable only from inside the
+// actuator's container (docker-compose exec), unlike the published admin port.
+const defaultOverrideAddr = "127.0.0.1:9101"
+
+// newOverrideMux serves /override only. When a token is configured every
+// request must carry it as "Authorization: Bearer <token>".
+func (a *actuator) newOverrideMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/override", func(w http.ResponseWriter, r *http.Request) {
+		if t := a.cfg.OverrideToken; t != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(t)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "missing or invalid override token", http.StatusUnauthorized)
+				return
+			}
+		}
+		a.serveOverride(w, r)
+	})
+	return mux
+}
+
+// overrideRequest is the body of POST /override.
+type overrideRequest struct {
+	// Profile to pin; empty freezes the profile currently in force.
+	Profile string `json:"profile"`
+	// TTL is a Go duration such as "30m" or "2h".
+	TTL    string `json:"ttl"`
+	Reason string `json:"reason"`
+	By     string `json:"by"`
+}
+
+// overrideStatus is the response of every /override method.
+type overrideStatus struct {
+	Profile       string                `json:"optimization_profile"`
+	ConfigVersion int                   `json:"config_version"`
+	Override      *controlfile.Override `json:"override"`
+}
+
+// serveOverride handles GET (status), POST (set) and DELETE (clear) on
+// /override. Changes are written to the control file straight away, under
+// the same lock as the control cycle so neither overwrites the other.
+func (a *actuator) serveOverride(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.mu.Lock()
+		prev := a.readPrevious()
+		a.mu.Unlock()
+		writeOverrideStatus(w, prev)
+	case http.MethodPost:
+		var req overrideRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 || ttl > a.cfg.OverrideMaxTTL {
+			http.Error(w, fmt.Sprintf("ttl must be a duration between 0 and %s, e.g. \"30m\"", a.cfg.OverrideMaxTTL), http.StatusBadRequest)
+			return
+		}
+		req.Reason, req.By = strings.TrimSpace(req.Reason), strings.TrimSpace(req.By)
+		if req.Reason == "" || req.By == "" {
+			http.Error(w, "reason and by are required", http.StatusBadRequest)
+			return
+		}
+		if req.Profile != "" && !controlfile.IsProfile(req.Profile) {
+			http.Error(w, "profile must be conservative, balanced or aggressive", http.StatusBadRequest)
+			return
+		}
+		cf, err := a.setOverride(req, ttl)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeOverrideStatus(w, cf)
+	case http.MethodDelete:
+		by := strings.TrimSpace(r.URL.Query().Get("by"))
+		if by == "" {
+			http.Error(w, "by is required", http.StatusBadRequest)
+			return
+		}
+		cf, err := a.clearOverride(by)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeOverrideStatus(w, cf)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeOverrideStatus(w http.ResponseWriter, cf *controlfile.File) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(overrideStatus{Profile: cf.OptimizationProfile, ConfigVersion: cf.ConfigVersion, Override: cf.Override})
+}
+
+func (a *actuator) setOverride(req overrideRequest, ttl time.Duration) (*controlfile.File, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	prev := a.readPrevious()
+	profile := req.Profile
+	if profile == "" {
+		profile = prev.OptimizationProfile
+	}
+	o := &controlfile.Override{
+		Active:    true,
+		Profile:   profile,
+		Reason:    req.Reason,
+		By:        req.By,
+		SetAt:     now.Format(time.RFC3339),
+		ExpiresAt: now.Add(ttl).Format(time.RFC3339),
+	}
+	d := Decision{
+		Proposed:   prev.OptimizationProfile,
+		Profile:    profile,
+		Reason:     fmt.Sprintf("Manual override by %s until %s: %s", o.By, o.ExpiresAt, o.Reason),
+		Changed:    profile != prev.OptimizationProfile,
+		Overridden: true,
+		Override:   o,
+	}
+	log.Printf("INFO (Actuator): Manual override set by %s: profile '%s' for %s (%s)", o.By, profile, ttl, o.Reason)
+	return a.writeManual(prev, d, now)
+}
+
+func (a *actuator) clearOverride(by string) (*controlfile.File, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	prev := a.readPrevious()
+	if !prev.Override.ActiveAt(now) {
+		return prev, nil
+	}
+	o := endOverride(prev.Override)
+	o.ExpiresAt = now.Format(time.RFC3339)
+	d := Decision{
+		Proposed: prev.OptimizationProfile,
+		Profile:  prev.OptimizationProfile,
+		Reason:   fmt.Sprintf("Manual override by %s cleared by %s. Automatic control resumes next cycle.", prev.Override.By, by),
+		Override: o,
+	}
+	log.Printf("INFO (Actuator): %s", d.Reason)
+	return a.writeManual(prev, d, now)
+}
+
+// writeManual writes an override change outside the control cycle, keeping
+// the previous KPIs and controller state. The caller holds a.mu.
+func (a *actuator) writeManual(prev *controlfile.File, d Decision, now time.Time) (*controlfile.File, error) {
+	kpis := KPIs{
+		FullTS:          float64(prev.CurrentMetrics.FullTS),
+		OptimisedTS:     float64(prev.CurrentMetrics.OptimizedTS),
+		ExperimentalTS:  float64(prev.CurrentMetrics.ExperimentalTS),
+		ExplosionAlerts: float64(prev.CurrentMetrics.CardinalityExplosionAlerts),
+		RiskProcesses:   float64(prev.CurrentMetrics.CardinalityRiskProcesses),
+	}
+	d.CostReductionRatio = prev.CurrentMetrics.CostReductionRatio
+	d.PID, d.Parameters = prev.PIDState, prev.AdvancedParameters
+
+	next := nextControlFile(a.cfg, prev, d, kpis, now)
+	entry := &JournalEntry{
+		Time:          now,
+		CorrelationID: next.CorrelationID,
+		ConfigVersion: next.ConfigVersion,
+		Policy:        "manual_override",
+		Thresholds:    journalThresholds(a.cfg),
+		Inputs: JournalInputs{
+			FullTS:                     kpis.FullTS,
+			OptimizedTS:                kpis.OptimisedTS,
+			ExperimentalTS:             kpis.ExperimentalTS,
+			CardinalityExplosionAlerts: kpis.ExplosionAlerts,
+			CardinalityRiskProcesses:   kpis.RiskProcesses,
+			CostReductionRatio:         d.CostReductionRatio,
+		},
+		PreviousProfile:  prev.OptimizationProfile,
+		ProposedProfile:  d.Proposed,
+		EffectiveProfile: d.Profile,
+		Reason:           d.Reason,
+		Changed:          d.Changed,
+		Overridden:       d.Overridden,
+	}
+	err := writeControlFile(a.cfg, prev, next)
+	entry.Written = err == nil
+	a.record(entry, err, 0)
+	if err != nil {
+		return nil, err
+	}
+	recordProfile(next.OptimizationProfile)
+	recordOverride(next.Override, now)
+	configVersion.Set(float64(next.ConfigVersion))
+	if d.Changed {
+		profileChangesTotal.Inc()
+	}
+	return next, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOverrideListenAddr(t *testing.T) {
+	tests := []struct {
+		addr, token string
+		wantErr     string
+	}{
+		{defaultOverrideAddr, "", ""},
+		{"localhost:9101", "", ""},
+		{"[::1]:9101", "", ""},
+		{":9101", "", "OVERRIDE_TOKEN is required"},
+		{"0.0.0.0:9101", "", "OVERRIDE_TOKEN is required"},
+		{"0.0.0.0:9101", "secret", ""},
+		{":9100", "secret", "must differ from ACTUATOR_LISTEN_ADDR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			cfg := &Config{
+				Interval: time.Minute, ListenAddr: ":9100", OverrideListenAddr: tt.addr, OverrideToken: tt.token,
+				OverrideMaxTTL: time.Hour, JournalMaxBytes: 1, Thresholds: testThresholds,
+			}
+			err := cfg.validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("validate() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Requests that fail the token check never reach serveOverride, so a method
+// it rejects tells the two apart.
+func TestOverrideToken(t *testing.T) {
+	tests := []struct {
+		name, token, header string
+		want                int
+	}{
+		{"no token configured", "", "", http.StatusMethodNotAllowed},
+		{"missing", "secret", "", http.StatusUnauthorized},
+		{"wrong", "secret", "Bearer guess", http.StatusUnauthorized},
+		{"not bearer", "secret", "secret", http.StatusUnauthorized},
+		{"valid", "secret", "Bearer secret", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &actuator{cfg: &Config{OverrideToken: tt.token}}
+			req := httptest.NewRequest(http.MethodPut, "/override", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			a.newOverrideMux().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("PUT /override = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminMuxHasNoOverride(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newAdminMux(&readiness{}, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/override", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET :9100/override = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// runOverrideCommand implements `control-actuator override set|clear|status`,
+// a client of the running actuator's /override endpoint. It sends
+// OVERRIDE_TOKEN, when set, as a bearer token.
+func runOverrideCommand(args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: control-actuator override set -ttl DURATION -reason TEXT [-profile PROFILE] [-by NAME] [-addr URL]")
+		fmt.Fprintln(os.Stderr, "       control-actuator override clear [-by NAME] [-addr URL]")
+		fmt.Fprintln(os.Stderr, "       control-actuator override status [-addr URL]")
+		fmt.Fprintln(os.Stderr, "\nset without -profile freezes the profile currently in force.")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	sub := args[0]
+	fs := flag.NewFlagSet("override "+sub, flag.ContinueOnError)
+	addr := fs.String("addr", envString("ACTUATOR_OVERRIDE_URL", "http://"+defaultOverrideAddr), "Actuator override endpoint base URL (ACTUATOR_OVERRIDE_URL)")
+	by := fs.String("by", os.Getenv("USER"), "Who sets or clears the override")
+	profile := fs.String("profile", "", "Profile to pin (conservative, balanced, aggressive); empty freezes the current one")
+	ttl := fs.Duration("ttl", 0, "How long the override lasts, e.g. 30m")
+	reason := fs.String("reason", "", "Why the override is needed")
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	endpoint := strings.TrimRight(*addr, "/") + "/override"
+
+	var req *http.Request
+	var err error
+	switch sub {
+	case "set":
+		if *ttl <= 0 || *reason == "" || *by == "" {
+			fmt.Fprintln(os.Stderr, "override set: -ttl, -reason and -by (or $USER) are required")
+			return 2
+		}
+		body, _ := json.Marshal(overrideRequest{Profile: *profile, TTL: ttl.String(), Reason: *reason, By: *by})
+		req, err = http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	case "clear":
+		if *by == "" {
+			fmt.Fprintln(os.Stderr, "override clear: -by (or $USER) is required")
+			return 2
+		}
+		req, err = http.NewRequest(http.MethodDelete, endpoint+"?by="+url.QueryEscape(*by), nil)
+	case "status":
+		req, err = http.NewRequest(http.MethodGet, endpoint, nil)
+	default:
+		usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "override %s: %v\n", sub, err)
+		return 2
+	}
+
+	if t := os.Getenv("OVERRIDE_TOKEN"); t != "" {
+		req.Header.Set("Authorization", "Bearer "+t)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "override %s: %v\n", sub, err)
+		return 1
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "override %s: %s: %s\n", sub, resp.Status, strings.TrimSpace(string(data)))
+		return 1
+	}
+	var status overrideStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		fmt.Fprintf(os.Stderr, "override %s: unexpected response: %v\n", sub, err)
+		return 1
+	}
+	fmt.Printf("Profile: %s (config_version %d)\n", status.Profile, status.ConfigVersion)
+	switch o := status.Override; {
+	case o == nil || o.Profile == "":
+		fmt.Println("Override: none")
+	case o.ActiveAt(time.Now()):
+		fmt.Printf("Override: active, '%s' until %s, by %s: %s\n", o.Profile, o.ExpiresAt, o.By, o.Reason)
+	default:
+		fmt.Printf("Override: inactive (last: '%s' by %s, ended %s: %s)\n", o.Profile, o.By, o.ExpiresAt, o.Reason)
+	}
+	return 0
+}
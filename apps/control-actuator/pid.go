@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// pidBias is the controller output at zero error with an empty integral,
+// i.e. the middle of the output range.
+const pidBias = 0.5
+
+// PIDConfig tunes the PID controller. Its output is a continuous optimisation
+// intensity in [0, 1] (0 = keep everything, 1 = optimise hardest) computed
+// from the optimised pipeline's series count relative to
+// TARGET_OPTIMIZED_PIPELINE_TS_COUNT.
+type PIDConfig struct {
+	// Kp, Ki and Kd act on the relative error (optimised - target) / target.
+	// Ki and Kd are per control interval, so the tuning does not change with
+	// ADAPTIVE_CONTROLLER_INTERVAL_SECONDS.
+	Kp, Ki, Kd float64
+	// DerivativeFilter is the time constant, in control intervals, of the
+	// low-pass filter on the derivative term. 0 disables filtering.
+	DerivativeFilter float64
+	// BalancedMinOutput and AggressiveMinOutput split the output range into
+	// the three profiles.
+	BalancedMinOutput   float64
+	AggressiveMinOutput float64
+	// OutputHysteresis is how far past a band edge the output has to move
+	// before the profile it is leaving is given up.
+	OutputHysteresis float64
+	// TopKMin and TopKMax bound target_k_value_for_experimental_topk, which
+	// shrinks linearly as the output grows.
+	TopKMin, TopKMax int
+}
+
+func (c *PIDConfig) validate() error {
+	if c.Kp < 0 || c.Ki < 0 || c.Kd < 0 {
+		return fmt.Errorf("PID gains must not be negative (PID_KP=%g, PID_KI=%g, PID_KD=%g)", c.Kp, c.Ki, c.Kd)
+	}
+	if c.DerivativeFilter < 0 {
+		return fmt.Errorf("PID_DERIVATIVE_FILTER_INTERVALS must not be negative")
+	}
+	if c.BalancedMinOutput <= 0 || c.AggressiveMinOutput <= c.BalancedMinOutput || c.AggressiveMinOutput >= 1 {
+		return fmt.Errorf("PID output bands must satisfy 0 < PID_BALANCED_MIN_OUTPUT (%g) < PID_AGGRESSIVE_MIN_OUTPUT (%g) < 1",
+			c.BalancedMinOutput, c.AggressiveMinOutput)
+	}
+	if c.OutputHysteresis < 0 || c.OutputHysteresis >= (c.AggressiveMinOutput-c.BalancedMinOutput)/2 {
+		return fmt.Errorf("PID_OUTPUT_HYSTERESIS must be within [0, half the balanced band), got %g", c.OutputHysteresis)
+	}
+	if c.TopKMin < 1 || c.TopKMax < c.TopKMin {
+		return fmt.Errorf("PID top-k bounds must satisfy 1 <= PID_TOPK_MIN (%d) <= PID_TOPK_MAX (%d)", c.TopKMin, c.TopKMax)
+	}
+	return nil
+}
+
+// pidMaxStepIntervals caps dt, in control intervals. After a long gap (the
+// actuator was down, or the clock jumped) the persisted integral is still the
+// best estimate of the steady-state output; a single error sample must not
+// move it by more than a few cycles' worth.
+const pidMaxStepIntervals = 5
+
+// pidStep advances the controller by one cycle. prev is the persisted state
+// (nil or unusable state starts fresh, with the integral preset so the output
+// sits in the middle of prevProfile's band, for a bumpless start).
+// interval is the nominal cycle length, used to express dt in intervals.
+func pidStep(c *PIDConfig, prev *controlfile.PIDState, prevProfile string, target, measured float64, interval time.Duration, now time.Time) controlfile.PIDState {
+	e := (measured - target) / target
+
+	var last time.Time
+	if prev != nil {
+		last, _ = time.Parse(time.RFC3339, prev.UpdatedAt)
+	}
+	dt := now.Sub(last).Seconds() / interval.Seconds()
+	fresh := prev == nil || last.Unix() <= 0 || dt <= 0
+	dt = math.Min(dt, pidMaxStepIntervals)
+	if fresh {
+		prev = &controlfile.PIDState{Integral: c.bandCentre(prevProfile) - pidBias, LastMeasurement: measured}
+		dt = 1
+	}
+
+	// Derivative on the measurement, not the error, so a changed target does
+	// not kick the output; low-pass filtered against scrape noise.
+	derivative := 0.0
+	if !fresh {
+		raw := (measured - prev.LastMeasurement) / target / dt
+		alpha := 1.0
+		if c.DerivativeFilter > 0 {
+			alpha = dt / (c.DerivativeFilter + dt)
+		}
+		derivative = prev.Derivative + alpha*(raw-prev.Derivative)
+	}
+
+	proportional := c.Kp * e
+	integral := prev.Integral + c.Ki*e*dt
+	// Anti-windup: clamp the integral to what the output range can use, and
+	// stop integrating while the output is saturated in the error's direction.
+	integral = clamp(integral, -pidBias, 1-pidBias)
+	unclamped := pidBias + proportional + integral + c.Kd*derivative
+	if (unclamped > 1 && e > 0) || (unclamped < 0 && e < 0) {
+		integral = prev.Integral
+		unclamped = pidBias + proportional + integral + c.Kd*derivative
+	}
+
+	return controlfile.PIDState{
+		Output:          round6(clamp(unclamped, 0, 1)),
+		Integral:        round6(integral),
+		Derivative:      round6(derivative),
+		LastMeasurement: measured,
+		UpdatedAt:       now.Format(time.RFC3339),
+	}
+}
+
+// profileFor maps the controller output onto a profile. The band of the
+// profile currently in force is widened by OutputHysteresis on both sides.
+func (c *PIDConfig) profileFor(output float64, prev string) string {
+	lo, hi := c.BalancedMinOutput, c.AggressiveMinOutput
+	h := c.OutputHysteresis
+	switch prev {
+	case profileConservative:
+		lo += h
+		hi += h
+	case profileAggressive:
+		lo -= h
+		hi -= h
+	case profileBalanced:
+		lo -= h
+		hi += h
+	}
+	switch {
+	case output >= hi:
+		return profileAggressive
+	case output >= lo:
+		return profileBalanced
+	}
+	return profileConservative
+}
+
+func (c *PIDConfig) bandCentre(profile string) float64 {
+	switch profile {
+	case profileConservative:
+		return c.BalancedMinOutput / 2
+	case profileAggressive:
+		return (c.AggressiveMinOutput + 1) / 2
+	}
+	return (c.BalancedMinOutput + c.AggressiveMinOutput) / 2
+}
+
+// parameters derives the continuous knobs from the controller output.
+func (c *PIDConfig) parameters(output float64) *controlfile.AdvancedParameters {
+	k := float64(c.TopKMax) - output*float64(c.TopKMax-c.TopKMin)
+	level := "low"
+	switch {
+	case output >= c.AggressiveMinOutput:
+		level = "high"
+	case output >= c.BalancedMinOutput:
+		level = "medium"
+	}
+	return &controlfile.AdvancedParameters{
+		ControlOutput:                    math.Round(output*1000) / 1000,
+		TargetKValueForExperimentalTopK:  int(math.Round(k)),
+		AttributeStrippingIntensityLevel: level,
+	}
+}
+
+// round6 keeps the persisted state readable; the precision lost is far below
+// what a KPI scrape can resolve.
+func round6(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// testPID is the config.go default tuning.
+var testPID = PIDConfig{
+	Kp: 0.2, Ki: 0.05, Kd: 0.1,
+	DerivativeFilter:    2,
+	BalancedMinOutput:   0.35,
+	AggressiveMinOutput: 0.65,
+	OutputHysteresis:    0.05,
+	TopKMin:             5,
+	TopKMax:             50,
+}
+
+// The error is 0.2 throughout, so each interval adds Ki * 0.2 = 0.01 to the
+// integral; a fresh start presets it to the balanced band centre, 0.
+func TestPIDStepIntegral(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	state := func(ago time.Duration) *controlfile.PIDState {
+		return &controlfile.PIDState{Integral: 0.2, LastMeasurement: 1200, UpdatedAt: now.Add(-ago).Format(time.RFC3339)}
+	}
+	tests := []struct {
+		name string
+		prev *controlfile.PIDState
+		want float64
+	}{
+		{"one interval", state(time.Minute), 0.21},
+		{"three intervals", state(3 * time.Minute), 0.23},
+		{"gap keeps the integral", state(time.Hour), 0.25},
+		{"no state", nil, 0.01},
+		{"epoch", &controlfile.PIDState{Integral: 0.2, UpdatedAt: controlfile.EpochTimestamp}, 0.01},
+		{"clock went back", state(-time.Minute), 0.01},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pidStep(&testPID, tt.prev, profileBalanced, 1000, 1200, time.Minute, now)
+			if math.Abs(got.Integral-tt.want) > 1e-9 {
+				t.Errorf("pidStep() integral = %g, want %g", got.Integral, tt.want)
+			}
+			if got.UpdatedAt != "2025-06-01T12:00:00Z" || got.LastMeasurement != 1200 {
+				t.Errorf("pidStep() state = %+v, want it stamped with the measurement and time", got)
+			}
+		})
+	}
+}
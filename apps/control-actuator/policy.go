@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// Control policies, selected with ADAPTIVE_CONTROLLER_MODE.
+const (
+	modeThreshold  = "threshold"
+	modePID        = "pid"
+	modeCostBudget = "cost_budget"
+	modeRules      = "rules"
+)
+
+// Snapshot is the KPI view every policy decides on.
+type Snapshot struct {
+	FullTS                     float64
+	OptimizedTS                float64
+	ExperimentalTS             float64
+	CostReductionRatio         float64
+	CardinalityExplosionAlerts float64
+	CardinalityRiskProcesses   float64
+}
+
+// PolicyInput is what a policy sees in one control cycle.
+type PolicyInput struct {
+	KPIs Snapshot
+	// PreviousProfile is the profile currently in force.
+	PreviousProfile string
+	// Previous is the control file as read at the start of the cycle, for
+	// policies that persist state in it.
+	Previous *controlfile.File
+	Now      time.Time
+}
+
+// PolicyResult is a policy's proposal. Reason ends up in trigger_reason.
+type PolicyResult struct {
+	Profile string
+	Reason  string
+	// PID and Parameters are written to the control file when set.
+	PID        *controlfile.PIDState
+	Parameters *controlfile.AdvancedParameters
+}
+
+// Policy proposes an optimisation profile from a KPI snapshot. Cardinality
+// emergencies and the stability period are applied on top of every policy by
+// decide.
+type Policy interface {
+	Name() string
+	Decide(ctx context.Context, in PolicyInput) (PolicyResult, error)
+}
+
+// newPolicy builds the policy selected in cfg. queryFn runs PromQL for
+// policies that need more than the KPI snapshot.
+func newPolicy(cfg *Config, queryFn promQueryFunc) (Policy, error) {
+	switch cfg.Mode {
+	case modeThreshold:
+		return &thresholdPolicy{th: cfg.Thresholds}, nil
+	case modePID:
+		return &pidPolicy{cfg: cfg.PID, target: cfg.TargetOptimisedTS, interval: cfg.Interval}, nil
+	case modeCostBudget:
+		return &costBudgetPolicy{cfg: cfg.CostBudget, hysteresis: cfg.Thresholds.HysteresisFactor}, nil
+	case modeRules:
+		return loadRulesPolicy(cfg.RulesFilePath, queryFn)
+	}
+	return nil, fmt.Errorf("unknown ADAPTIVE_CONTROLLER_MODE %q (supported: threshold, pid, cost_budget, rules)", cfg.Mode)
+}
+
+// thresholdPolicy compares the optimised pipeline's series count with the
+// thresholds, widened by the hysteresis factor around the current profile.
+type thresholdPolicy struct {
+	th Thresholds
+}
+
+func (p *thresholdPolicy) Name() string { return modeThreshold }
+
+func (p *thresholdPolicy) Decide(_ context.Context, in PolicyInput) (PolicyResult, error) {
+	profile, reason := proposeProfile(p.th, in.PreviousProfile, in.KPIs.OptimizedTS)
+	return PolicyResult{Profile: profile, Reason: reason}, nil
+}
+
+// pidPolicy runs the PID controller; its state lives in the control file.
+type pidPolicy struct {
+	cfg      PIDConfig
+	target   float64
+	interval time.Duration
+}
+
+func (p *pidPolicy) Name() string { return modePID }
+
+func (p *pidPolicy) Decide(_ context.Context, in PolicyInput) (PolicyResult, error) {
+	var prevState *controlfile.PIDState
+	if in.Previous != nil {
+		prevState = in.Previous.PIDState
+	}
+	ts := in.KPIs.OptimizedTS
+	state := pidStep(&p.cfg, prevState, in.PreviousProfile, p.target, ts, p.interval, in.Now)
+	profile := p.cfg.profileFor(state.Output, in.PreviousProfile)
+	recordPID(&state, (ts-p.target)/p.target)
+	return PolicyResult{
+		Profile: profile,
+		Reason: fmt.Sprintf("PID output %.3f (Optimised TS %g vs target %g, error %+.1f%%, I %.3f, D %.3f) -> %s",
+			state.Output, ts, p.target, 100*(ts-p.target)/p.target, state.Integral, state.Derivative, profile),
+		PID:        &state,
+		Parameters: p.cfg.parameters(state.Output),
+	}, nil
+}
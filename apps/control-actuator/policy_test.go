@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr string
+	}{
+		{modeThreshold, modeThreshold, ""},
+		{modePID, modePID, ""},
+		{modeCostBudget, modeCostBudget, ""},
+		{modeRules, "", "/nonexistent/policy_rules.yaml"},
+		{"fuzzy", "", `unknown ADAPTIVE_CONTROLLER_MODE "fuzzy"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := &Config{Mode: tt.mode, RulesFilePath: "/nonexistent/policy_rules.yaml", Thresholds: testThresholds}
+			p, err := newPolicy(cfg, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newPolicy() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newPolicy() error = %v", err)
+			}
+			if p.Name() != tt.want {
+				t.Errorf("newPolicy().Name() = %q, want %q", p.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestThresholdPolicyDecide(t *testing.T) {
+	p := &thresholdPolicy{th: testThresholds}
+	tests := []struct {
+		prev string
+		ts   float64
+		want string
+	}{
+		{profileConservative, 16500, profileConservative},
+		{profileBalanced, 25001, profileAggressive},
+		{profileAggressive, 22499, profileBalanced},
+	}
+	for _, tt := range tests {
+		res, err := p.Decide(context.Background(), PolicyInput{KPIs: Snapshot{OptimizedTS: tt.ts}, PreviousProfile: tt.prev})
+		if err != nil {
+			t.Fatalf("Decide() error = %v", err)
+		}
+		if res.Profile != tt.want || res.Reason == "" {
+			t.Errorf("Decide(%q, %g) = %q (%q), want %q with a reason", tt.prev, tt.ts, res.Profile, res.Reason, tt.want)
+		}
+		if res.PID != nil || res.Parameters != nil {
+			t.Errorf("threshold policy wrote PID state or parameters")
+		}
+	}
+}
+
+// stubPolicy returns a fixed result and records the input it saw.
+type stubPolicy struct {
+	res PolicyResult
+	err error
+	in  PolicyInput
+}
+
+func (p *stubPolicy) Name() string { return "stub" }
+
+func (p *stubPolicy) Decide(_ context.Context, in PolicyInput) (PolicyResult, error) {
+	p.in = in
+	return p.res, p.err
+}
+
+func TestDecidePolicyContract(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cfg := &Config{Thresholds: testThresholds, StabilityPeriod: 120 * time.Second}
+	prev := &controlfile.File{OptimizationProfile: profileBalanced, LastProfileChangeTimestamp: controlfile.EpochTimestamp}
+	kpis := KPIs{FullTS: 1000, OptimisedTS: 400, ExperimentalTS: 100, ExplosionAlerts: 0, RiskProcesses: 3}
+
+	t.Run("input", func(t *testing.T) {
+		p := &stubPolicy{res: PolicyResult{Profile: profileBalanced}}
+		if _, err := decide(context.Background(), p, cfg, prev, kpis, now); err != nil {
+			t.Fatalf("decide() error = %v", err)
+		}
+		want := Snapshot{FullTS: 1000, OptimizedTS: 400, ExperimentalTS: 100, CostReductionRatio: 0.6, CardinalityRiskProcesses: 3}
+		if p.in.KPIs != want || p.in.PreviousProfile != profileBalanced || p.in.Previous != prev || !p.in.Now.Equal(now) {
+			t.Errorf("policy input = %+v, want KPIs %+v", p.in, want)
+		}
+	})
+
+	t.Run("runs under emergency", func(t *testing.T) {
+		p := &stubPolicy{res: PolicyResult{Profile: profileConservative, PID: &controlfile.PIDState{Output: 0.1}}}
+		emergency := kpis
+		emergency.ExplosionAlerts = 1
+		d, err := decide(context.Background(), p, cfg, prev, emergency, now)
+		if err != nil {
+			t.Fatalf("decide() error = %v", err)
+		}
+		if d.Profile != profileAggressive || !d.Emergency || d.PID == nil {
+			t.Errorf("decide() = %+v, want the aggressive profile with the policy's PID state kept", d)
+		}
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		p := &stubPolicy{res: PolicyResult{Profile: "extreme"}}
+		if _, err := decide(context.Background(), p, cfg, prev, kpis, now); err == nil || !strings.Contains(err.Error(), `unknown profile "extreme"`) {
+			t.Errorf("decide() error = %v, want unknown profile", err)
+		}
+	})
+
+	t.Run("policy error", func(t *testing.T) {
+		p := &stubPolicy{err: errors.New("boom")}
+		if _, err := decide(context.Background(), p, cfg, prev, kpis, now); err == nil || !strings.Contains(err.Error(), "stub policy failed: boom") {
+			t.Errorf("decide() error = %v, want the policy's error", err)
+		}
+	})
+}
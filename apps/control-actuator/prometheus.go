@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// KPIs is one cycle's view of the pipelines, as read from Prometheus.
+type KPIs struct {
+	FullTS          float64
+	OptimisedTS     float64
+	ExperimentalTS  float64
+	ExplosionAlerts float64
+	RiskProcesses   float64
+}
+
+// kpiSource queries the KPI series through the Prometheus HTTP API.
+type kpiSource struct {
+	api        promv1.API
+	queries    KPIQueries
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+}
+
+func newKPISource(cfg *Config) (*kpiSource, error) {
+	client, err := api.NewClient(api.Config{Address: cfg.PrometheusURL})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Prometheus client for %s: %w", cfg.PrometheusURL, err)
+	}
+	return &kpiSource{
+		api:        promv1.NewAPI(client),
+		queries:    cfg.Queries,
+		timeout:    cfg.QueryTimeout,
+		retries:    cfg.QueryRetries,
+		retryDelay: cfg.QueryRetryDelay,
+	}, nil
+}
+
+// fetch reads every KPI. A KPI that cannot be read falls back to the value
+// the previous control file recorded (or 0), as the pipelines are assumed
+// unchanged rather than empty; failed is the number of such fallbacks.
+func (s *kpiSource) fetch(ctx context.Context, prev *controlfile.File) (kpis KPIs, failed int) {
+	var prevMetrics controlfile.CurrentMetrics
+	if prev != nil {
+		prevMetrics = prev.CurrentMetrics
+	}
+	read := func(name, query string, fallback int64) float64 {
+		v, err := s.queryValue(ctx, query)
+		if err != nil {
+			log.Printf("WARN (Actuator): KPI %s unavailable, assuming %d: %v", name, fallback, err)
+			queryFailuresTotal.WithLabelValues(name).Inc()
+			failed++
+			return float64(fallback)
+		}
+		// KPIs are series counts; the control file records whole numbers
+		return math.Round(v)
+	}
+	kpis.FullTS = read("full_ts", s.queries.FullTS, prevMetrics.FullTS)
+	kpis.OptimisedTS = read("optimized_ts", s.queries.OptimisedTS, prevMetrics.OptimizedTS)
+	kpis.ExperimentalTS = read("experimental_ts", s.queries.ExperimentalTS, prevMetrics.ExperimentalTS)
+	kpis.ExplosionAlerts = read("cardinality_explosion_alerts", s.queries.ExplosionAlerts, 0)
+	kpis.RiskProcesses = read("cardinality_risk_processes", s.queries.RiskProcesses, 0)
+	return kpis, failed
+}
+
+// queryValue runs an instant query, retrying until it returns a sample, and
+// returns the value of the first sample.
+func (s *kpiSource) queryValue(ctx context.Context, query string) (float64, error) {
+	var lastErr error
+	for attempt := 1; attempt <= s.retries; attempt++ {
+		v, err := s.queryOnce(ctx, query)
+		if err == nil {
+			return v, nil
+		}
+		lastErr = err
+		if attempt < s.retries {
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(s.retryDelay):
+			}
+		}
+	}
+	return 0, fmt.Errorf("query failed after %d attempts: %w", s.retries, lastErr)
+}
+
+// promQueryFunc runs an instant PromQL query and returns the sample values.
+type promQueryFunc func(ctx context.Context, query string) ([]float64, error)
+
+// queryVector runs query once and returns every sample value; an empty
+// result is not an error.
+func (s *kpiSource) queryVector(ctx context.Context, query string) ([]float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	result, _, err := s.api.Query(ctx, query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	switch r := result.(type) {
+	case model.Vector:
+		values := make([]float64, len(r))
+		for i, sample := range r {
+			values[i] = float64(sample.Value)
+		}
+		return values, nil
+	case *model.Scalar:
+		return []float64{float64(r.Value)}, nil
+	}
+	return nil, fmt.Errorf("unexpected %s result for '%s'", result.Type(), query)
+}
+
+func (s *kpiSource) queryOnce(ctx context.Context, query string) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	result, warnings, err := s.api.Query(ctx, query, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for _, w := range warnings {
+		log.Printf("WARN (Actuator): Prometheus warning for '%s': %s", query, w)
+	}
+	var v float64
+	switch r := result.(type) {
+	case model.Vector:
+		if len(r) == 0 {
+			return 0, fmt.Errorf("no data for '%s'", query)
+		}
+		v = float64(r[0].Value)
+	case *model.Scalar:
+		v = float64(r.Value)
+	default:
+		return 0, fmt.Errorf("unexpected %s result for '%s'", result.Type(), query)
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+		return 0, fmt.Errorf("invalid value %g for '%s'", v, query)
+	}
+	return v, nil
+}
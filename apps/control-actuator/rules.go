@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v3"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// RulesFile is the rule set of the rules policy. Rules are evaluated in
+// order and the first match picks the profile; Default applies when none
+// matches.
+type RulesFile struct {
+	Rules   []Rule `yaml:"rules"`
+	Default string `yaml:"default"`
+}
+
+// Rule matches on either a CEL expression over the KPI snapshot (When) or a
+// PromQL query (PromQL) that matches when it returns at least one non-zero
+// sample, so comparisons such as `sum(x) > 100` work as conditions.
+type Rule struct {
+	Name    string `yaml:"name"`
+	When    string `yaml:"when"`
+	PromQL  string `yaml:"promql"`
+	Profile string `yaml:"profile"`
+	// Reason is written to trigger_reason; defaults to the rule's condition.
+	Reason string `yaml:"reason"`
+
+	program cel.Program
+}
+
+// celSnapshotVars are the variables available to CEL conditions.
+var celSnapshotVars = []cel.EnvOption{
+	cel.Variable("full_ts", cel.DoubleType),
+	cel.Variable("optimized_ts", cel.DoubleType),
+	cel.Variable("experimental_ts", cel.DoubleType),
+	cel.Variable("cost_reduction_ratio", cel.DoubleType),
+	cel.Variable("cardinality_explosion_alerts", cel.DoubleType),
+	cel.Variable("cardinality_risk_processes", cel.DoubleType),
+	cel.Variable("previous_profile", cel.StringType),
+}
+
+type rulesPolicy struct {
+	rules   []Rule
+	def     string
+	queryFn promQueryFunc
+}
+
+// loadRulesPolicy reads and compiles the rule file at path.
+func loadRulesPolicy(path string, queryFn promQueryFunc) (*rulesPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	var rf RulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&rf); err != nil {
+		return nil, fmt.Errorf("rules file %s is malformed: %w", path, err)
+	}
+
+	// Cross-type comparisons let conditions compare the double KPIs with
+	// integer literals, e.g. optimized_ts > 25000
+	env, err := cel.NewEnv(append(celSnapshotVars, cel.CrossTypeNumericComparisons(true))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	if !controlfile.IsProfile(rf.Default) {
+		return nil, fmt.Errorf("rules file %s: default must be conservative, balanced or aggressive, got %q", path, rf.Default)
+	}
+	for i := range rf.Rules {
+		r := &rf.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if !controlfile.IsProfile(r.Profile) {
+			return nil, fmt.Errorf("rule %s: profile must be conservative, balanced or aggressive, got %q", r.Name, r.Profile)
+		}
+		switch {
+		case r.When != "" && r.PromQL != "":
+			return nil, fmt.Errorf("rule %s: set either when or promql, not both", r.Name)
+		case r.When != "":
+			ast, iss := env.Compile(r.When)
+			if iss.Err() != nil {
+				return nil, fmt.Errorf("rule %s: invalid when expression: %w", r.Name, iss.Err())
+			}
+			if ast.OutputType() != cel.BoolType {
+				return nil, fmt.Errorf("rule %s: when expression must be a bool, got %s", r.Name, ast.OutputType())
+			}
+			if r.program, err = env.Program(ast); err != nil {
+				return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+			}
+		case r.PromQL != "":
+		default:
+			return nil, fmt.Errorf("rule %s: needs a when or promql condition", r.Name)
+		}
+	}
+	log.Printf("INFO (Actuator): Loaded %d control rules from %s (default %s)", len(rf.Rules), path, rf.Default)
+	return &rulesPolicy{rules: rf.Rules, def: rf.Default, queryFn: queryFn}, nil
+}
+
+func (p *rulesPolicy) Name() string { return modeRules }
+
+func (p *rulesPolicy) Decide(ctx context.Context, in PolicyInput) (PolicyResult, error) {
+	vars := map[string]any{
+		"full_ts":                      in.KPIs.FullTS,
+		"optimized_ts":                 in.KPIs.OptimizedTS,
+		"experimental_ts":              in.KPIs.ExperimentalTS,
+		"cost_reduction_ratio":         in.KPIs.CostReductionRatio,
+		"cardinality_explosion_alerts": in.KPIs.CardinalityExplosionAlerts,
+		"cardinality_risk_processes":   in.KPIs.CardinalityRiskProcesses,
+		"previous_profile":             in.PreviousProfile,
+	}
+	for i := range p.rules {
+		r := &p.rules[i]
+		matched, err := p.matches(ctx, r, vars)
+		if err != nil {
+			// A broken rule must not block the ones after it
+			log.Printf("WARN (Actuator): Skipping rule %s: %v", r.Name, err)
+			continue
+		}
+		if !matched {
+			continue
+		}
+		reason := r.Reason
+		if reason == "" {
+			reason = "matched " + r.condition()
+		}
+		return PolicyResult{Profile: r.Profile, Reason: fmt.Sprintf("Rule '%s': %s", r.Name, reason)}, nil
+	}
+	return PolicyResult{Profile: p.def, Reason: "No rule matched; default profile"}, nil
+}
+
+func (p *rulesPolicy) matches(ctx context.Context, r *Rule, vars map[string]any) (bool, error) {
+	if r.program != nil {
+		out, _, err := r.program.Eval(vars)
+		if err != nil {
+			return false, err
+		}
+		matched, ok := out.Value().(bool)
+		if !ok {
+			return false, fmt.Errorf("when expression returned %v", out.Value())
+		}
+		return matched, nil
+	}
+	values, err := p.queryFn(ctx, r.PromQL)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range values {
+		if v != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *Rule) condition() string {
+	if r.When != "" {
+		return strings.TrimSpace(r.When)
+	}
+	return "promql " + strings.TrimSpace(r.PromQL)
+}
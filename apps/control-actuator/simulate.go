@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// KPI series names used by the simulator, as in the control file.
+const (
+	kpiFullTS          = "full_ts"
+	kpiOptimizedTS     = "optimized_ts"
+	kpiExperimentalTS  = "experimental_ts"
+	kpiExplosionAlerts = "cardinality_explosion_alerts"
+	kpiRiskProcesses   = "cardinality_risk_processes"
+)
+
+// pipelineKPIs maps the observer's phoenix_pipeline_label values onto KPIs.
+var pipelineKPIs = map[string]string{
+	pipelineFullFidelity: kpiFullTS,
+	pipelineOptimised:    kpiOptimizedTS,
+	pipelineExperimental: kpiExperimentalTS,
+}
+
+type kpiSample struct {
+	t time.Time
+	v float64
+}
+
+// kpiRecording holds recorded KPI series, each sorted by time.
+type kpiRecording map[string][]kpiSample
+
+// at returns the latest value of kpi at or before t, or 0 before the first
+// sample. Holding the last value matches the actuator, which falls back to
+// the previous control file's value when a query returns nothing.
+func (r kpiRecording) at(kpi string, t time.Time) float64 {
+	samples := r[kpi]
+	i := sort.Search(len(samples), func(i int) bool { return samples[i].t.After(t) })
+	if i == 0 {
+		return 0
+	}
+	return samples[i-1].v
+}
+
+func (r kpiRecording) span() (start, end time.Time) {
+	for _, samples := range r {
+		if len(samples) == 0 {
+			continue
+		}
+		if start.IsZero() || samples[0].t.Before(start) {
+			start = samples[0].t
+		}
+		if last := samples[len(samples)-1].t; last.After(end) {
+			end = last
+		}
+	}
+	return start, end
+}
+
+func (r kpiRecording) add(kpi string, t time.Time, v float64) {
+	r[kpi] = append(r[kpi], kpiSample{t: t, v: v})
+}
+
+func (r kpiRecording) sort() {
+	for _, samples := range r {
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].t.Before(samples[j].t) })
+	}
+}
+
+// simChange is one profile change in a simulation.
+type simChange struct {
+	Time   time.Time `json:"time"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Reason string    `json:"reason"`
+}
+
+// simReport summarises a simulation run.
+type simReport struct {
+	Policy         string      `json:"policy"`
+	Interval       string      `json:"interval"`
+	Stability      string      `json:"stability_period"`
+	Start          time.Time   `json:"start"`
+	End            time.Time   `json:"end"`
+	Cycles         int         `json:"cycles"`
+	Changes        []simChange `json:"changes"`
+	StabilityHolds int         `json:"stability_holds"`
+	// Oscillations counts changes that undo the previous change (A->B->A).
+	Oscillations  int                `json:"oscillations"`
+	TimeInProfile map[string]float64 `json:"time_in_profile_seconds"`
+	FinalProfile  string             `json:"final_profile"`
+}
+
+type inputList []string
+
+func (l *inputList) String() string     { return strings.Join(*l, ",") }
+func (l *inputList) Set(v string) error { *l = append(*l, v); return nil }
+
+// runSimulate implements `control-actuator simulate`: it replays recorded KPI
+// series through the decision logic in virtual time and reports the profile
+// changes. The exit code is 1 when a -max-* limit is exceeded and 2 on
+// usage or input errors.
+func runSimulate(args []string) int {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: control-actuator simulate -input FILE [-input FILE...] [flags]\n\n"+
+			"Replays recorded KPIs (CSV, or Prometheus range-query JSON of\n"+
+			"phoenix_pipeline_output_cardinality_estimate) through the actuator's decision\n"+
+			"logic. Settings come from the same environment variables as the service;\n"+
+			"flags override them.\n\n")
+		fs.PrintDefaults()
+	}
+	var inputs inputList
+	fs.Var(&inputs, "input", "Recorded KPI file (.csv or Prometheus range-query .json); repeatable")
+	mode := fs.String("mode", "", "Control policy (overrides ADAPTIVE_CONTROLLER_MODE)")
+	interval := fs.Duration("interval", 0, "Control interval (overrides ADAPTIVE_CONTROLLER_INTERVAL_SECONDS)")
+	stability := fs.Duration("stability", 0, "Stability period (overrides ADAPTIVE_CONTROLLER_STABILITY_SECONDS)")
+	hysteresis := fs.Float64("hysteresis", 0, "Hysteresis factor (overrides HYSTERESIS_FACTOR)")
+	consMax := fs.Float64("conservative-max", 0, "Conservative max TS (overrides THRESHOLD_OPTIMIZATION_CONSERVATIVE_MAX_TS)")
+	aggrMin := fs.Float64("aggressive-min", 0, "Aggressive min TS (overrides THRESHOLD_OPTIMIZATION_AGGRESSIVE_MIN_TS)")
+	startProfile := fs.String("start-profile", profileConservative, "Profile in force when the recording starts")
+	jsonOut := fs.Bool("json", false, "Print the report as JSON")
+	verbose := fs.Bool("v", false, "Log every simulated cycle's warnings to stderr")
+	maxChanges := fs.Int("max-changes", -1, "Exit 1 if there are more profile changes than this (-1 = no limit)")
+	maxOscillations := fs.Int("max-oscillations", -1, "Exit 1 if there are more oscillations than this (-1 = no limit)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if len(inputs) == 0 {
+		fmt.Fprintln(os.Stderr, "simulate: at least one -input is required")
+		fs.Usage()
+		return 2
+	}
+	if !controlfile.IsProfile(*startProfile) {
+		fmt.Fprintf(os.Stderr, "simulate: unknown -start-profile %q\n", *startProfile)
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "simulate: invalid configuration: %v\n", err)
+		return 2
+	}
+	// Only flags given on the command line override the environment.
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "mode":
+			cfg.Mode = strings.ToLower(*mode)
+		case "interval":
+			cfg.Interval = *interval
+		case "stability":
+			cfg.StabilityPeriod = *stability
+		case "hysteresis":
+			cfg.Thresholds.HysteresisFactor = *hysteresis
+		case "conservative-max":
+			cfg.Thresholds.ConservativeMaxTS = *consMax
+		case "aggressive-min":
+			cfg.Thresholds.AggressiveMinTS = *aggrMin
+		}
+	})
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "simulate: invalid configuration: %v\n", err)
+		return 2
+	}
+	policy, err := newPolicy(cfg, func(context.Context, string) ([]float64, error) {
+		return nil, fmt.Errorf("PromQL conditions cannot be evaluated in simulation")
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "simulate: %v\n", err)
+		return 2
+	}
+
+	rec := make(kpiRecording)
+	for _, path := range inputs {
+		if err := loadRecording(path, rec); err != nil {
+			fmt.Fprintf(os.Stderr, "simulate: %v\n", err)
+			return 2
+		}
+	}
+	rec.sort()
+	if len(rec[kpiOptimizedTS]) == 0 {
+		fmt.Fprintln(os.Stderr, "simulate: the recording has no optimised pipeline series (optimized_ts)")
+		return 2
+	}
+
+	report, err := simulate(cfg, policy, rec, *startProfile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "simulate: %v\n", err)
+		return 2
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(report)
+	} else {
+		report.print(os.Stdout)
+	}
+
+	if *maxChanges >= 0 && len(report.Changes) > *maxChanges {
+		fmt.Fprintf(os.Stderr, "simulate: %d profile changes exceed -max-changes %d\n", len(report.Changes), *maxChanges)
+		return 1
+	}
+	if *maxOscillations >= 0 && report.Oscillations > *maxOscillations {
+		fmt.Fprintf(os.Stderr, "simulate: %d oscillations exceed -max-oscillations %d\n", report.Oscillations, *maxOscillations)
+		return 1
+	}
+	return 0
+}
+
+// simulate runs one control cycle per interval over the recording's span,
+// carrying the control file state from cycle to cycle as the service does.
+func simulate(cfg *Config, policy Policy, rec kpiRecording, startProfile string) (*simReport, error) {
+	start, end := rec.span()
+	report := &simReport{
+		Policy:        policy.Name(),
+		Interval:      cfg.Interval.String(),
+		Stability:     cfg.StabilityPeriod.String(),
+		Start:         start,
+		End:           end,
+		TimeInProfile: map[string]float64{profileConservative: 0, profileBalanced: 0, profileAggressive: 0},
+	}
+	prev := controlfile.Default()
+	prev.OptimizationProfile = startProfile
+	ctx := context.Background()
+	for now := start; !now.After(end); now = now.Add(cfg.Interval) {
+		kpis := KPIs{
+			FullTS:          rec.at(kpiFullTS, now),
+			OptimisedTS:     rec.at(kpiOptimizedTS, now),
+			ExperimentalTS:  rec.at(kpiExperimentalTS, now),
+			ExplosionAlerts: rec.at(kpiExplosionAlerts, now),
+			RiskProcesses:   rec.at(kpiRiskProcesses, now),
+		}
+		d, err := decide(ctx, policy, cfg, prev, kpis, now)
+		if err != nil {
+			return nil, fmt.Errorf("cycle at %s: %w", now.Format(time.RFC3339), err)
+		}
+		report.Cycles++
+		if d.Held {
+			report.StabilityHolds++
+		}
+		if d.Changed {
+			change := simChange{Time: now, From: prev.OptimizationProfile, To: d.Profile, Reason: d.Reason}
+			if n := len(report.Changes); n > 0 && report.Changes[n-1].From == change.To {
+				report.Oscillations++
+			}
+			report.Changes = append(report.Changes, change)
+		}
+		report.TimeInProfile[d.Profile] += cfg.Interval.Seconds()
+		prev = nextControlFile(cfg, prev, d, kpis, now)
+	}
+	report.FinalProfile = prev.OptimizationProfile
+	return report, nil
+}
+
+func (r *simReport) print(w io.Writer) {
+	fmt.Fprintf(w, "Simulated %d cycles from %s to %s (policy %s, interval %s, stability period %s)\n",
+		r.Cycles, r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339), r.Policy, r.Interval, r.Stability)
+	fmt.Fprintf(w, "\nProfile changes: %d (oscillations: %d, stability holds: %d)\n", len(r.Changes), r.Oscillations, r.StabilityHolds)
+	for _, c := range r.Changes {
+		fmt.Fprintf(w, "  %s  %-12s -> %-12s %s\n", c.Time.Format(time.RFC3339), c.From, c.To, c.Reason)
+	}
+	total := 0.0
+	for _, s := range r.TimeInProfile {
+		total += s
+	}
+	fmt.Fprintln(w, "\nTime in profile:")
+	for _, p := range []string{profileConservative, profileBalanced, profileAggressive} {
+		s := r.TimeInProfile[p]
+		pct := 0.0
+		if total > 0 {
+			pct = 100 * s / total
+		}
+		fmt.Fprintf(w, "  %-12s %10s  %5.1f%%\n", p, time.Duration(s*float64(time.Second)), pct)
+	}
+	fmt.Fprintf(w, "\nFinal profile: %s\n", r.FinalProfile)
+}
+
+// loadRecording adds the KPI series in path to rec. JSON files are read as
+// Prometheus range-query responses, anything else as CSV.
+func loadRecording(path string, rec kpiRecording) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read recording: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = parsePromRangeJSON(data, rec)
+	} else {
+		err = parseKPICSV(data, rec)
+	}
+	if err != nil {
+		return fmt.Errorf("recording %s: %w", path, err)
+	}
+	return nil
+}
+
+// parsePromRangeJSON reads the response of /api/v1/query_range (or just its
+// data object). Cardinality estimate series are told apart by their
+// phoenix_pipeline_label.
+func parsePromRangeJSON(data []byte, rec kpiRecording) error {
+	type matrix struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][2]any          `json:"values"`
+		} `json:"result"`
+	}
+	var resp struct {
+		Status string  `json:"status"`
+		Data   *matrix `json:"data"`
+		matrix
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	m := resp.matrix
+	if resp.Data != nil {
+		m = *resp.Data
+	}
+	if m.ResultType != "matrix" {
+		return fmt.Errorf("expected a range query (matrix) result, got %q", m.ResultType)
+	}
+	for _, series := range m.Result {
+		label := series.Metric["phoenix_pipeline_label"]
+		kpi, ok := pipelineKPIs[label]
+		if !ok {
+			return fmt.Errorf("series %v has no known phoenix_pipeline_label (full_fidelity, optimised, experimental)", series.Metric)
+		}
+		for _, pair := range series.Values {
+			ts, ok := pair[0].(float64)
+			if !ok {
+				return fmt.Errorf("invalid timestamp %v", pair[0])
+			}
+			s, ok := pair[1].(string)
+			if !ok {
+				return fmt.Errorf("invalid sample value %v", pair[1])
+			}
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("invalid sample value %q: %w", s, err)
+			}
+			rec.add(kpi, unixTime(ts), v)
+		}
+	}
+	return nil
+}
+
+// parseKPICSV reads a CSV with a header row: a timestamp column (unix
+// seconds or RFC3339) and any of the KPI columns full_ts, optimized_ts,
+// experimental_ts, cardinality_explosion_alerts and
+// cardinality_risk_processes. Empty cells are skipped.
+func parseKPICSV(data []byte, rec kpiRecording) error {
+	r := csv.NewReader(strings.NewReader(string(data)))
+	r.Comment = '#'
+	rows, err := r.ReadAll()
+	if err != nil {
+		return fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(rows) < 2 {
+		return fmt.Errorf("CSV needs a header row and at least one sample")
+	}
+	tsCol := -1
+	cols := make(map[int]string)
+	for i, name := range rows[0] {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "timestamp", "time":
+			tsCol = i
+		case "optimised_ts":
+			cols[i] = kpiOptimizedTS
+		case kpiFullTS, kpiOptimizedTS, kpiExperimentalTS, kpiExplosionAlerts, kpiRiskProcesses:
+			cols[i] = name
+		default:
+			return fmt.Errorf("unknown CSV column %q", name)
+		}
+	}
+	if tsCol < 0 {
+		return fmt.Errorf("CSV has no timestamp column")
+	}
+	for line, row := range rows[1:] {
+		t, err := parseSampleTime(strings.TrimSpace(row[tsCol]))
+		if err != nil {
+			return fmt.Errorf("row %d: %w", line+2, err)
+		}
+		for i, kpi := range cols {
+			cell := strings.TrimSpace(row[i])
+			if cell == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				return fmt.Errorf("row %d: invalid %s value %q", line+2, kpi, cell)
+			}
+			rec.add(kpi, t, v)
+		}
+	}
+	return nil
+}
+
+func parseSampleTime(s string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return unixTime(secs), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q (want unix seconds or RFC3339)", s)
+	}
+	return t.UTC(), nil
+}
+
+func unixTime(secs float64) time.Time {
+	return time.UnixMilli(int64(secs * 1000)).UTC()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSimulateFlapping replays testdata/flapping.csv; the expectations are
+// worked out cycle by cycle in the comments.
+func TestSimulateFlapping(t *testing.T) {
+	rec := make(kpiRecording)
+	if err := loadRecording("testdata/flapping.csv", rec); err != nil {
+		t.Fatal(err)
+	}
+	rec.sort()
+	cfg := &Config{
+		Mode:            modeThreshold,
+		Interval:        time.Minute,
+		StabilityPeriod: 5 * time.Minute,
+		Thresholds:      testThresholds,
+	}
+	report, err := simulate(cfg, &thresholdPolicy{th: cfg.Thresholds}, rec, profileConservative)
+	if err != nil {
+		t.Fatalf("simulate() error = %v", err)
+	}
+
+	start := time.Unix(1760000000, 0).UTC()
+	at := func(minute int) time.Time { return start.Add(time.Duration(minute) * time.Minute) }
+	want := []simChange{
+		// 20000 leaves conservative only up to balanced
+		{Time: at(5), From: profileConservative, To: profileBalanced},
+		// 30000 from minute 6, held until the stability period is over
+		{Time: at(10), From: profileBalanced, To: profileAggressive},
+		// 23000 is within the hysteresis band; 20000 is not
+		{Time: at(15), From: profileAggressive, To: profileBalanced},
+		// The explosion alert at 24000, otherwise balanced
+		{Time: at(20), From: profileBalanced, To: profileAggressive},
+		// 12000 from minute 21, held until minute 25
+		{Time: at(25), From: profileAggressive, To: profileConservative},
+	}
+	if len(report.Changes) != len(want) {
+		t.Fatalf("got %d profile changes, want %d: %+v", len(report.Changes), len(want), report.Changes)
+	}
+	for i, c := range report.Changes {
+		if !c.Time.Equal(want[i].Time) || c.From != want[i].From || c.To != want[i].To {
+			t.Errorf("change %d = %s %s -> %s, want %s %s -> %s", i,
+				c.Time.Format(time.RFC3339), c.From, c.To, want[i].Time.Format(time.RFC3339), want[i].From, want[i].To)
+		}
+	}
+
+	if report.Cycles != 35 {
+		t.Errorf("Cycles = %d, want 35", report.Cycles)
+	}
+	// balanced -> aggressive -> balanced, then aggressive -> balanced -> aggressive
+	if report.Oscillations != 2 {
+		t.Errorf("Oscillations = %d, want 2", report.Oscillations)
+	}
+	if report.StabilityHolds != 8 {
+		t.Errorf("StabilityHolds = %d, want 8", report.StabilityHolds)
+	}
+	wantTime := map[string]float64{
+		profileConservative: 15 * 60, // minutes 0-4 and 25-34
+		profileBalanced:     10 * 60, // minutes 5-9 and 15-19
+		profileAggressive:   10 * 60, // minutes 10-14 and 20-24
+	}
+	for profile, seconds := range wantTime {
+		if got := report.TimeInProfile[profile]; got != seconds {
+			t.Errorf("TimeInProfile[%s] = %gs, want %gs", profile, got, seconds)
+		}
+	}
+	if report.FinalProfile != profileConservative {
+		t.Errorf("FinalProfile = %q, want conservative", report.FinalProfile)
+	}
+}
+
+// TestRunSimulateLimits runs the subcommand on the recording shipped in
+// configs/control, as in the README.
+func TestRunSimulateLimits(t *testing.T) {
+	const input = "../../configs/control/recordings/ramp_up_down.csv"
+	t.Setenv("ADAPTIVE_CONTROLLER_MODE", modeThreshold)
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"within limits", []string{"-max-oscillations", "1"}, 0},
+		{"too many changes", []string{"-max-changes", "0"}, 1},
+		{"unknown start profile", []string{"-start-profile", "extreme"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-input", input, "-interval", "60s", "-stability", "5m", "-hysteresis", "0.1", "-json"}, tt.args...)
+			if got := runSimulate(args); got != tt.want {
+				t.Errorf("runSimulate(%v) = %d, want %d", args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Command componentcheck verifies that a collector binary contains every
+// component the given collector configs use. It compares the component
+// types in the configs' receivers, processors, exporters, extensions and
+// connectors sections with the output of the binary's components command.
+//
+//	componentcheck [-bin PATH] CONFIG...
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kinds are the config sections that name components.
+var kinds = []string{"receivers", "processors", "exporters", "extensions", "connectors"}
+
+func main() {
+	bin := flag.String("bin", "phoenix-otelcol", "Collector binary to check")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: componentcheck [-bin PATH] CONFIG...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	built, err := builtComponents(*bin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *bin, err)
+		os.Exit(2)
+	}
+
+	rc := 0
+	for _, path := range flag.Args() {
+		used, err := configComponents(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			os.Exit(2)
+		}
+		var missing []string
+		count := 0
+		for _, kind := range kinds {
+			for _, typ := range used[kind] {
+				count++
+				if !built[kind][typ] {
+					missing = append(missing, kind+"/"+typ)
+				}
+			}
+		}
+		if len(missing) > 0 {
+			fmt.Printf("%s: MISSING from %s\n", path, *bin)
+			for _, m := range missing {
+				fmt.Printf("  - %s\n", m)
+			}
+			rc = 1
+			continue
+		}
+		fmt.Printf("%s: OK (%d component types)\n", path, count)
+	}
+	os.Exit(rc)
+}
+
+// builtComponents runs "bin components" and returns the component types it
+// lists, by kind.
+func builtComponents(bin string) (map[string]map[string]bool, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(bin, "components")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("components command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	type component struct {
+		Name string `yaml:"name"`
+	}
+	var listing struct {
+		Receivers  []component `yaml:"receivers"`
+		Processors []component `yaml:"processors"`
+		Exporters  []component `yaml:"exporters"`
+		Extensions []component `yaml:"extensions"`
+		Connectors []component `yaml:"connectors"`
+	}
+	if err := yaml.Unmarshal(out, &listing); err != nil {
+		return nil, fmt.Errorf("unexpected components output: %w", err)
+	}
+	built := map[string]map[string]bool{}
+	for kind, cs := range map[string][]component{
+		"receivers":  listing.Receivers,
+		"processors": listing.Processors,
+		"exporters":  listing.Exporters,
+		"extensions": listing.Extensions,
+		"connectors": listing.Connectors,
+	} {
+		built[kind] = map[string]bool{}
+		for _, c := range cs {
+			built[kind][c.Name] = true
+		}
+	}
+	return built, nil
+}
+
+// configComponents returns the sorted component types a collector config
+// defines, by kind. The type is the part of a component ID before "/".
+func configComponents(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg map[string]map[string]any
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("not a collector config: %w", err)
+	}
+	used := map[string][]string{}
+	for _, kind := range kinds {
+		seen := map[string]bool{}
+		for id := range cfg[kind] {
+			typ, _, _ := strings.Cut(id, "/")
+			if !seen[typ] {
+				seen[typ] = true
+				used[kind] = append(used[kind], typ)
+			}
+		}
+		sort.Strings(used[kind])
+	}
+	return used, nil
+}
@@ -0,0 +1,11 @@
+// Code generated by "go.opentelemetry.io/collector/cmd/builder". DO NOT EDIT.
+
+//go:build !windows
+
+package main
+
+import "go.opentelemetry.io/collector/otelcol"
+
+func run(params otelcol.CollectorSettings) error {
+	return runInteractive(params)
+}
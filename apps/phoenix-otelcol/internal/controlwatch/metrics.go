@@ -0,0 +1,117 @@
+package controlwatch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// The applied-control metrics describe the control file the collector is
+// running with, so the observer can confirm the actuator's writes took
+// effect. They are served on status_endpoint rather than through the
+// collector's own telemetry, whose names carry an otelcol_ prefix.
+var (
+	registry = prometheus.NewRegistry()
+
+	appliedConfigVersion = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "phoenix_main_applied_control_config_version",
+		Help: "config_version of the control file the collector is applying.",
+	})
+	appliedProfile = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "phoenix_main_applied_control_profile",
+		Help: "1 for the optimisation profile the collector is applying, 0 for the others.",
+	}, []string{"profile"})
+	appliedTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "phoenix_main_applied_control_timestamp_seconds",
+		Help: "Unix time the current control file was applied.",
+	})
+	reloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "phoenix_main_applied_control_reloads_total",
+		Help: "Control file reloads, by result (success or failure).",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(appliedConfigVersion, appliedProfile, appliedTimestamp, reloadsTotal)
+	reloadsTotal.WithLabelValues("success")
+	reloadsTotal.WithLabelValues("failure")
+}
+
+func recordApplied(f *controlfile.File) {
+	appliedConfigVersion.Set(float64(f.ConfigVersion))
+	for _, p := range []string{controlfile.ProfileConservative, controlfile.ProfileBalanced, controlfile.ProfileAggressive} {
+		v := 0.0
+		if p == f.OptimizationProfile {
+			v = 1
+		}
+		appliedProfile.WithLabelValues(p).Set(v)
+	}
+	appliedTimestamp.Set(float64(time.Now().Unix()))
+}
+
+// StatusServer serves the applied-control metrics on one endpoint for every
+// component configured with it.
+type StatusServer struct {
+	endpoint string
+	server   *http.Server
+	refs     int // guarded by serversMu
+}
+
+var (
+	serversMu sync.Mutex
+	servers   = map[string]*StatusServer{}
+)
+
+// AcquireStatusServer returns the StatusServer listening on endpoint,
+// starting one if needed. Every AcquireStatusServer must be paired with a
+// Release.
+func AcquireStatusServer(endpoint string, logger *zap.Logger) (*StatusServer, error) {
+	serversMu.Lock()
+	defer serversMu.Unlock()
+	if s, ok := servers[endpoint]; ok {
+		s.refs++
+		return s, nil
+	}
+	ln, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	s := &StatusServer{
+		endpoint: endpoint,
+		server:   &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		refs:     1,
+	}
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Control status endpoint stopped", zap.String("endpoint", endpoint), zap.Error(err))
+		}
+	}()
+	servers[endpoint] = s
+	return s, nil
+}
+
+// Release drops a reference and shuts the server down with the last one.
+func (s *StatusServer) Release(ctx context.Context) error {
+	serversMu.Lock()
+	s.refs--
+	last := s.refs == 0
+	if last {
+		delete(servers, s.endpoint)
+	}
+	serversMu.Unlock()
+	if last {
+		return s.server.Shutdown(ctx)
+	}
+	return nil
+}
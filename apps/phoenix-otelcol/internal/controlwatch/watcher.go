@@ -0,0 +1,168 @@
+// Package controlwatch reloads the control file written by the
+// control-actuator for the Phoenix collector components and publishes the
+// phoenix_main_applied_control_* metrics describing the file in force.
+package controlwatch
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// Watcher polls one control file and holds the last valid version of it.
+// Every component configured with the same file shares one Watcher, so all
+// pipelines switch profile on the same reload.
+type Watcher struct {
+	path     string
+	interval time.Duration
+	logger   *zap.Logger
+
+	file atomic.Pointer[controlfile.File]
+	last []byte // contents of the last file read, valid or not
+
+	refs int // guarded by watchersMu
+	stop chan struct{}
+	done chan struct{}
+}
+
+var (
+	watchersMu sync.Mutex
+	watchers   = map[string]*Watcher{}
+)
+
+// Acquire returns the running Watcher for path, starting one if needed.
+// The first reload happens before it returns, so Current never returns nil.
+// Every Acquire must be paired with a Release.
+func Acquire(path string, interval time.Duration, logger *zap.Logger) *Watcher {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	if w, ok := watchers[path]; ok {
+		w.refs++
+		return w
+	}
+	w := &Watcher{
+		path:     path,
+		interval: interval,
+		logger:   logger.With(zap.String("control_file", path)),
+		refs:     1,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	w.reload()
+	go w.run()
+	watchers[path] = w
+	return w
+}
+
+// Release drops a reference and stops the Watcher with the last one.
+func (w *Watcher) Release() {
+	watchersMu.Lock()
+	w.refs--
+	last := w.refs == 0
+	if last {
+		delete(watchers, w.path)
+	}
+	watchersMu.Unlock()
+	if last {
+		close(w.stop)
+		<-w.done
+	}
+}
+
+// Current returns the control file in force. It must not be modified.
+func (w *Watcher) Current() *controlfile.File {
+	return w.file.Load()
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+// reload reads the control file and applies it if it changed and is valid.
+// A missing file applies the default (conservative) file until one appears;
+// an unreadable or invalid file keeps the last valid one in force.
+func (w *Watcher) reload() {
+	data, err := os.ReadFile(w.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if w.file.Load() == nil {
+			w.logger.Warn("Control file not found, applying the default profile until it appears",
+				zap.String("profile", controlfile.ProfileConservative))
+			w.apply(controlfile.Default())
+		}
+		return
+	}
+	if err != nil {
+		w.reject("Failed to read control file", err)
+		if w.file.Load() == nil {
+			w.apply(controlfile.Default())
+		}
+		return
+	}
+	if w.file.Load() != nil && bytes.Equal(data, w.last) {
+		return
+	}
+	w.last = data
+
+	f, version, err := controlfile.Parse(data)
+	if err == nil {
+		err = controlfile.Validate(f, nil)
+	}
+	if err != nil {
+		w.reject("Control file is invalid, keeping the last valid one", err)
+		if w.file.Load() == nil {
+			w.apply(controlfile.Default())
+		}
+		return
+	}
+	if version < controlfile.SchemaVersion {
+		w.logger.Info("Control file uses an older schema, upgraded on read", zap.Int("schema_version", version))
+	}
+	if prev := w.file.Load(); prev != nil && f.ConfigVersion < prev.ConfigVersion {
+		w.logger.Warn("Control file config_version went backwards",
+			zap.Int("previous", prev.ConfigVersion), zap.Int("config_version", f.ConfigVersion))
+	}
+	w.apply(f)
+	reloadsTotal.WithLabelValues("success").Inc()
+}
+
+func (w *Watcher) apply(f *controlfile.File) {
+	prev := w.file.Swap(f)
+	recordApplied(f)
+	fields := []zap.Field{
+		zap.String("profile", f.OptimizationProfile),
+		zap.Int("config_version", f.ConfigVersion),
+		zap.String("correlation_id", f.CorrelationID),
+	}
+	if prev == nil || prev.OptimizationProfile != f.OptimizationProfile {
+		w.logger.Info("Applied optimisation profile", fields...)
+	} else {
+		w.logger.Debug("Applied control file", fields...)
+	}
+}
+
+func (w *Watcher) reject(msg string, err error) {
+	reloadsTotal.WithLabelValues("failure").Inc()
+	fields := []zap.Field{zap.Error(err)}
+	if f := w.file.Load(); f != nil {
+		fields = append(fields, zap.Int("kept_config_version", f.ConfigVersion))
+	}
+	w.logger.Error(msg, fields...)
+}
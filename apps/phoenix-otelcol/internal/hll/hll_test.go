@@ -0,0 +1,69 @@
+package hll
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+func insertRange(h *Sketch, from, to int) {
+	for i := from; i < to; i++ {
+		h.Insert(xxhash.Sum64String(fmt.Sprintf("series-%d", i)))
+	}
+}
+
+// The estimate stays within four standard errors at the precisions the
+// collector configures, on both sides of the linear-counting threshold.
+func TestEstimateError(t *testing.T) {
+	tests := []struct {
+		p uint8
+		n int
+	}{
+		{10, 0}, {10, 10}, {10, 200}, {10, 800}, {10, 2000}, {10, 2600}, {10, 3100}, {10, 50000},
+		{14, 100}, {14, 5000}, {14, 11000}, {14, 20000}, {14, 41000}, {14, 49000}, {14, 200000},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("p%d/n%d", tt.p, tt.n), func(t *testing.T) {
+			h := New(tt.p)
+			insertRange(h, 0, tt.n)
+			// Inserting again changes nothing
+			insertRange(h, 0, tt.n/2)
+			got := h.Estimate()
+			stdErr := 1.04 / math.Sqrt(float64(int(1)<<tt.p))
+			if tolerance := max(4*stdErr*float64(tt.n), 1); math.Abs(got-float64(tt.n)) > tolerance {
+				t.Errorf("Estimate() = %.1f, want %d ± %.1f", got, tt.n, tolerance)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b, union := New(12), New(12), New(12)
+	insertRange(a, 0, 6000)
+	insertRange(b, 4000, 10000)
+	insertRange(union, 0, 10000)
+
+	a.Merge(b)
+	if !slices.Equal(a.regs, union.regs) {
+		t.Fatal("merged registers differ from the sketch of the union")
+	}
+	if got := a.Estimate(); math.Abs(got-10000) > 4*1.04/64*10000 {
+		t.Errorf("merged Estimate() = %.0f, want about 10000", got)
+	}
+	// Merging is idempotent and leaves the other sketch alone
+	a.Merge(b)
+	if !slices.Equal(a.regs, union.regs) {
+		t.Error("merging twice changed the sketch")
+	}
+	if got := b.Estimate(); math.Abs(got-6000) > 4*1.04/64*6000 {
+		t.Errorf("merged-from Estimate() = %.0f, want about 6000", got)
+	}
+
+	a.Reset()
+	if got := a.Estimate(); got != 0 {
+		t.Errorf("Estimate() after Reset = %g, want 0", got)
+	}
+}
@@ -0,0 +1,379 @@
+// Package rollup folds the resources of processes a pipeline does not keep
+// into one process.executable.name="_other" resource per host, so host
+// totals survive while the per-process series go away. The rollup resource
+// carries the number of processes folded in as phoenix.rollup.count.
+package rollup
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"phoenix-vnext/apps/phoenix-otelcol/internal/metricutil"
+)
+
+const (
+	// ProcessName is the process.executable.name of rollup resources.
+	ProcessName = "_other"
+	// CountAttribute is the resource attribute of a rollup holding the
+	// number of processes folded into it.
+	CountAttribute = "phoenix.rollup.count"
+
+	processExecutableName = "process.executable.name"
+	scopeName             = "phoenix-vnext/apps/phoenix-otelcol/internal/rollup"
+	// Suffixes of the extra metrics aggregating a gauge
+	maxSuffix   = ".max"
+	countSuffix = ".count"
+)
+
+// State holds the rollup of every host across batches. Processes export in
+// separate batches, so a rollup is built from the last points of every
+// process folded into it rather than from the processes of one batch.
+// State is not safe for concurrent use.
+type State struct {
+	keep        []string
+	expireAfter time.Duration
+	hosts       map[string]*host
+	// touched are the hosts added to since the last MoveTo, in order
+	touched   []string
+	expiredAt time.Time
+}
+
+// NewState returns an empty State. Rollup resources take the keep
+// attributes of the first resource folded into them; keep should only name
+// host-level attributes, so each rollup keeps one identity. Processes not
+// added for expireAfter leave their rollup.
+func NewState(keep []string, expireAfter time.Duration) *State {
+	return &State{keep: keep, expireAfter: expireAfter, hosts: map[string]*host{}}
+}
+
+// Add folds rm, the resource of process key seen at now, into the rollup of
+// hostName.
+func (s *State) Add(hostName, key string, rm pmetric.ResourceMetrics, now time.Time) {
+	h, ok := s.hosts[hostName]
+	if !ok {
+		h = newHost(rm.Resource().Attributes(), s.keep)
+		s.hosts[hostName] = h
+	}
+	if !h.touched {
+		h.touched = true
+		s.touched = append(s.touched, hostName)
+	}
+	h.processes[key] = now
+
+	sms := rm.ScopeMetrics()
+	for i := 0; i < sms.Len(); i++ {
+		ms := sms.At(i).Metrics()
+		for j := 0; j < ms.Len(); j++ {
+			m := ms.At(j)
+			var dps pmetric.NumberDataPointSlice
+			switch m.Type() {
+			case pmetric.MetricTypeSum:
+				dps = m.Sum().DataPoints()
+			case pmetric.MetricTypeGauge:
+				dps = m.Gauge().DataPoints()
+			default:
+				continue
+			}
+			for k := 0; k < dps.Len(); k++ {
+				h.record(key, m, dps.At(k))
+			}
+		}
+	}
+}
+
+// Remove takes process key out of the rollup of hostName, for a process the
+// pipeline keeps again.
+func (s *State) Remove(hostName, key string) {
+	if h, ok := s.hosts[hostName]; ok {
+		if _, ok := h.processes[key]; ok {
+			h.remove(key)
+		}
+	}
+}
+
+// MoveTo appends the rollups of the hosts added to since the last call to
+// dest, in the order the hosts were first added. now drives expiry.
+func (s *State) MoveTo(dest pmetric.ResourceMetricsSlice, now time.Time) {
+	cutoff := now.Add(-s.expireAfter)
+	if now.Sub(s.expiredAt) >= s.expireAfter {
+		s.expire(cutoff)
+		s.expiredAt = now
+	}
+	for _, name := range s.touched {
+		h, ok := s.hosts[name]
+		if !ok {
+			continue
+		}
+		h.touched = false
+		h.expire(cutoff)
+		h.moveTo(dest)
+	}
+	s.touched = s.touched[:0]
+}
+
+// expire drops processes not added since before cutoff, and hosts left
+// without any.
+func (s *State) expire(cutoff time.Time) {
+	for name, h := range s.hosts {
+		h.expire(cutoff)
+		if len(h.processes) == 0 {
+			delete(s.hosts, name)
+		}
+	}
+}
+
+// host is the rollup of one host's processes. Sums and gauges are kept;
+// other metric types are dropped.
+type host struct {
+	attrs     pcommon.Map
+	processes map[string]time.Time // last seen, by process key
+	series    map[string]*series
+	touched   bool
+}
+
+// series is one data point of a rollup and the processes feeding it.
+//
+// Delta sums add up the points of the batch. Gauges and other non-monotonic
+// sums (such as process.memory.usage) are the sum of the last value of each
+// process, and also get <name>.max and <name>.count gauges. Monotonic
+// cumulative sums also count the last values of the processes that left the
+// rollup, so they never drop.
+type series struct {
+	metricID string
+	// metric carries the name, unit, description and type of the series
+	metric pmetric.Metric
+	attrs  pcommon.Map
+	start  pcommon.Timestamp
+	inputs map[string]*input
+	// retired is what processes that left a monotonic sum had counted
+	retired float64
+	// batch are the points of a delta sum since the last MoveTo
+	batch *input
+}
+
+// input is the last point of one process.
+type input struct {
+	value     float64
+	start, ts pcommon.Timestamp
+}
+
+func newHost(attrs pcommon.Map, keep []string) *host {
+	h := &host{attrs: pcommon.NewMap(), processes: map[string]time.Time{}, series: map[string]*series{}}
+	for _, k := range keep {
+		if v, ok := attrs.Get(k); ok {
+			v.CopyTo(h.attrs.PutEmpty(k))
+		}
+	}
+	return h
+}
+
+func (h *host) record(process string, m pmetric.Metric, dp pmetric.NumberDataPoint) {
+	metricID := metricKey(m)
+	id := metricID + "\x01" + metricutil.AttributesKey(dp.Attributes())
+	s, ok := h.series[id]
+	if !ok {
+		s = newSeries(metricID, m, dp)
+		h.series[id] = s
+	}
+	v := metricutil.NumberValue(dp)
+
+	if isDelta(s.metric) {
+		if s.batch == nil {
+			s.batch = &input{start: dp.StartTimestamp()}
+		}
+		s.batch.value += v
+		s.batch.ts = max(s.batch.ts, dp.Timestamp())
+		if dp.StartTimestamp() != 0 && (s.batch.start == 0 || dp.StartTimestamp() < s.batch.start) {
+			s.batch.start = dp.StartTimestamp()
+		}
+		return
+	}
+
+	in, ok := s.inputs[process]
+	if !ok {
+		in = &input{}
+		s.inputs[process] = in
+	} else if !gaugeLike(s.metric) && (v < in.value || dp.StartTimestamp() != in.start) {
+		// The process's counter restarted; keep what it had counted
+		s.retired += in.value
+	}
+	in.value, in.start, in.ts = v, dp.StartTimestamp(), dp.Timestamp()
+}
+
+func newSeries(metricID string, m pmetric.Metric, dp pmetric.NumberDataPoint) *series {
+	s := &series{
+		metricID: metricID,
+		metric:   pmetric.NewMetric(),
+		attrs:    pcommon.NewMap(),
+		start:    dp.StartTimestamp(),
+		inputs:   map[string]*input{},
+	}
+	s.metric.SetName(m.Name())
+	s.metric.SetDescription(m.Description())
+	s.metric.SetUnit(m.Unit())
+	if m.Type() == pmetric.MetricTypeSum {
+		sum := s.metric.SetEmptySum()
+		sum.SetAggregationTemporality(m.Sum().AggregationTemporality())
+		sum.SetIsMonotonic(m.Sum().IsMonotonic())
+	} else {
+		s.metric.SetEmptyGauge()
+	}
+	if s.start == 0 && !gaugeLike(s.metric) {
+		s.start = dp.Timestamp()
+	}
+	dp.Attributes().CopyTo(s.attrs)
+	return s
+}
+
+// remove takes process out of every series of h.
+func (h *host) remove(process string) {
+	delete(h.processes, process)
+	for _, s := range h.series {
+		if in, ok := s.inputs[process]; ok {
+			if !gaugeLike(s.metric) {
+				s.retired += in.value
+			}
+			delete(s.inputs, process)
+		}
+	}
+}
+
+// expire removes the processes not added since before cutoff.
+func (h *host) expire(cutoff time.Time) {
+	for process, seen := range h.processes {
+		if seen.Before(cutoff) {
+			h.remove(process)
+		}
+	}
+}
+
+// moveTo appends the rollup resource of h to dest. Series left without
+// processes are forgotten, so a monotonic sum starting over gets a new start
+// timestamp.
+func (h *host) moveTo(dest pmetric.ResourceMetricsSlice) {
+	rm := dest.AppendEmpty()
+	h.attrs.CopyTo(rm.Resource().Attributes())
+	rm.Resource().Attributes().PutStr(processExecutableName, ProcessName)
+	rm.Resource().Attributes().PutInt(CountAttribute, int64(len(h.processes)))
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	b := &builder{metrics: sm.Metrics(), byID: map[string]pmetric.Metric{}}
+
+	ids := make([]string, 0, len(h.series))
+	for id := range h.series {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		s := h.series[id]
+		switch {
+		case isDelta(s.metric):
+			delete(h.series, id)
+			if s.batch != nil {
+				dp := b.point(s.metricID, s.metric, s.attrs, s.batch.start, s.batch.ts)
+				dp.SetDoubleValue(s.batch.value)
+			}
+		case len(s.inputs) == 0:
+			delete(h.series, id)
+		case gaugeLike(s.metric):
+			var (
+				sum         float64
+				largest     = math.Inf(-1)
+				start, last pcommon.Timestamp
+			)
+			for _, in := range s.inputs {
+				sum += in.value
+				largest = max(largest, in.value)
+				last = max(last, in.ts)
+				if in.start != 0 && (start == 0 || in.start < start) {
+					start = in.start
+				}
+			}
+			b.point(s.metricID, s.metric, s.attrs, start, last).SetDoubleValue(sum)
+			b.gauge(s.metricID+maxSuffix, s.metric.Name()+maxSuffix, s.metric.Unit(),
+				"Largest value among the processes of the rollup.", s.attrs, last).SetDoubleValue(largest)
+			b.gauge(s.metricID+countSuffix, s.metric.Name()+countSuffix, "{processes}",
+				"Processes of the rollup reporting the metric.", s.attrs, last).SetIntValue(int64(len(s.inputs)))
+		default:
+			total := s.retired
+			var last pcommon.Timestamp
+			for _, in := range s.inputs {
+				total += in.value
+				last = max(last, in.ts)
+			}
+			b.point(s.metricID, s.metric, s.attrs, s.start, last).SetDoubleValue(total)
+		}
+	}
+}
+
+// builder appends the points of a rollup to its metrics.
+type builder struct {
+	metrics pmetric.MetricSlice
+	byID    map[string]pmetric.Metric
+}
+
+// point appends a data point with attrs to metric id, a copy of the empty
+// metric like.
+func (b *builder) point(id string, like pmetric.Metric, attrs pcommon.Map, start, ts pcommon.Timestamp) pmetric.NumberDataPoint {
+	m, ok := b.byID[id]
+	if !ok {
+		m = b.metrics.AppendEmpty()
+		like.CopyTo(m)
+		b.byID[id] = m
+	}
+	var dp pmetric.NumberDataPoint
+	if m.Type() == pmetric.MetricTypeSum {
+		dp = m.Sum().DataPoints().AppendEmpty()
+	} else {
+		dp = m.Gauge().DataPoints().AppendEmpty()
+	}
+	attrs.CopyTo(dp.Attributes())
+	dp.SetStartTimestamp(start)
+	dp.SetTimestamp(ts)
+	return dp
+}
+
+// gauge appends a data point with attrs to the gauge id, creating it if
+// needed.
+func (b *builder) gauge(id, name, unit, description string, attrs pcommon.Map, ts pcommon.Timestamp) pmetric.NumberDataPoint {
+	m, ok := b.byID[id]
+	if !ok {
+		m = b.metrics.AppendEmpty()
+		m.SetName(name)
+		m.SetDescription(description)
+		m.SetUnit(unit)
+		m.SetEmptyGauge()
+		b.byID[id] = m
+	}
+	dp := m.Gauge().DataPoints().AppendEmpty()
+	attrs.CopyTo(dp.Attributes())
+	dp.SetTimestamp(ts)
+	return dp
+}
+
+// metricKey identifies a metric by everything its rollup series must agree
+// on.
+func metricKey(m pmetric.Metric) string {
+	key := m.Name() + "\x01" + m.Type().String()
+	if m.Type() == pmetric.MetricTypeSum {
+		key += "\x01" + m.Sum().AggregationTemporality().String() + "\x01" + strconv.FormatBool(m.Sum().IsMonotonic())
+	}
+	return key
+}
+
+// gaugeLike reports whether m is a gauge or a non-monotonic sum, whose
+// values are levels rather than counts.
+func gaugeLike(m pmetric.Metric) bool {
+	return m.Type() == pmetric.MetricTypeGauge || (m.Type() == pmetric.MetricTypeSum && !m.Sum().IsMonotonic())
+}
+
+// isDelta reports whether m is a delta sum, whose points are added up
+// within a batch.
+func isDelta(m pmetric.Metric) bool {
+	return m.Type() == pmetric.MetricTypeSum && m.Sum().AggregationTemporality() == pmetric.AggregationTemporalityDelta
+}
@@ -0,0 +1,221 @@
+package rollup
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+var t0 = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// sample is one process's points in a batch.
+type sample struct {
+	cpu      float64 // cumulative process.cpu.time
+	start    int     // start of the cpu.time counter, in seconds after t0
+	memory   float64 // gauge process.memory.usage
+	requests float64 // delta process.requests
+}
+
+func resource(host string, pid int, s sample) pmetric.ResourceMetrics {
+	rm := pmetric.NewResourceMetrics()
+	attrs := rm.Resource().Attributes()
+	attrs.PutStr("host.name", host)
+	attrs.PutInt("process.pid", int64(pid))
+	attrs.PutStr(processExecutableName, fmt.Sprintf("worker_%d", pid))
+	ms := rm.ScopeMetrics().AppendEmpty().Metrics()
+	ts := pcommon.NewTimestampFromTime(t0)
+
+	cpu := ms.AppendEmpty()
+	cpu.SetName("process.cpu.time")
+	cpu.SetUnit("s")
+	sum := cpu.SetEmptySum()
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	sum.SetIsMonotonic(true)
+	dp := sum.DataPoints().AppendEmpty()
+	dp.Attributes().PutStr("state", "user")
+	dp.SetStartTimestamp(pcommon.NewTimestampFromTime(t0.Add(time.Duration(s.start) * time.Second)))
+	dp.SetTimestamp(ts)
+	dp.SetDoubleValue(s.cpu)
+
+	memory := ms.AppendEmpty()
+	memory.SetName("process.memory.usage")
+	dp = memory.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(ts)
+	dp.SetDoubleValue(s.memory)
+
+	requests := ms.AppendEmpty()
+	requests.SetName("process.requests")
+	sum = requests.SetEmptySum()
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	sum.SetIsMonotonic(true)
+	dp = sum.DataPoints().AppendEmpty()
+	dp.SetTimestamp(ts)
+	dp.SetIntValue(int64(s.requests))
+	return rm
+}
+
+func add(s *State, host string, pid int, smp sample, now time.Time) {
+	s.Add(host, fmt.Sprint(pid), resource(host, pid, smp), now)
+}
+
+// emitted is one rollup resource as sent down the pipeline.
+type emitted struct {
+	attrs  map[string]any
+	values map[string]float64 // by metric name
+}
+
+func moveTo(s *State, now time.Time) []emitted {
+	rms := pmetric.NewResourceMetricsSlice()
+	s.MoveTo(rms, now)
+	out := make([]emitted, 0, rms.Len())
+	for i := 0; i < rms.Len(); i++ {
+		e := emitted{attrs: rms.At(i).Resource().Attributes().AsRaw(), values: map[string]float64{}}
+		ms := rms.At(i).ScopeMetrics().At(0).Metrics()
+		for j := 0; j < ms.Len(); j++ {
+			m := ms.At(j)
+			var dps pmetric.NumberDataPointSlice
+			if m.Type() == pmetric.MetricTypeSum {
+				dps = m.Sum().DataPoints()
+			} else {
+				dps = m.Gauge().DataPoints()
+			}
+			if dps.Len() != 1 {
+				panic(fmt.Sprintf("%s has %d points", m.Name(), dps.Len()))
+			}
+			dp := dps.At(0)
+			if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+				e.values[m.Name()] = float64(dp.IntValue())
+			} else {
+				e.values[m.Name()] = dp.DoubleValue()
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func checkRollup(t *testing.T, step string, got emitted, count int64, want map[string]float64) {
+	t.Helper()
+	if got.attrs[CountAttribute] != count {
+		t.Errorf("%s: %s = %v, want %d", step, CountAttribute, got.attrs[CountAttribute], count)
+	}
+	if len(got.values) != len(want) {
+		t.Errorf("%s: got metrics %v, want %v", step, got.values, want)
+	}
+	for name, v := range want {
+		if got.values[name] != v {
+			t.Errorf("%s: %s = %g, want %g", step, name, got.values[name], v)
+		}
+	}
+}
+
+func TestRollupAcrossBatches(t *testing.T) {
+	s := NewState([]string{"host.name"}, time.Minute)
+
+	add(s, "host-1", 1, sample{cpu: 10, memory: 100, requests: 1}, t0)
+	add(s, "host-1", 2, sample{cpu: 20, memory: 300, requests: 2}, t0)
+	out := moveTo(s, t0)
+	if len(out) != 1 {
+		t.Fatalf("%d rollups, want 1", len(out))
+	}
+	checkRollup(t, "first batch", out[0], 2, map[string]float64{
+		"process.cpu.time":           30,
+		"process.memory.usage":       400,
+		"process.memory.usage.max":   300,
+		"process.memory.usage.count": 2,
+		"process.requests":           3,
+	})
+
+	// Process 2 exports in another batch: its last points still count, but
+	// a delta only counts in the batch it came in
+	add(s, "host-1", 1, sample{cpu: 12, memory: 50, requests: 5}, t0.Add(10*time.Second))
+	checkRollup(t, "second batch", moveTo(s, t0.Add(10*time.Second))[0], 2, map[string]float64{
+		"process.cpu.time":           32,
+		"process.memory.usage":       350,
+		"process.memory.usage.max":   300,
+		"process.memory.usage.count": 2,
+		"process.requests":           5,
+	})
+
+	// The pipeline keeps process 2 again: its CPU time stays in the total
+	s.Remove("host-1", "2")
+	add(s, "host-1", 1, sample{cpu: 13, memory: 60}, t0.Add(20*time.Second))
+	checkRollup(t, "process left", moveTo(s, t0.Add(20*time.Second))[0], 1, map[string]float64{
+		"process.cpu.time":           33,
+		"process.memory.usage":       60,
+		"process.memory.usage.max":   60,
+		"process.memory.usage.count": 1,
+		"process.requests":           0,
+	})
+
+	// Process 1 restarts: its counter starts over, the total does not drop
+	add(s, "host-1", 1, sample{cpu: 1, start: 25, memory: 10}, t0.Add(30*time.Second))
+	checkRollup(t, "restart", moveTo(s, t0.Add(30*time.Second))[0], 1, map[string]float64{
+		"process.cpu.time":           34,
+		"process.memory.usage":       10,
+		"process.memory.usage.max":   10,
+		"process.memory.usage.count": 1,
+		"process.requests":           0,
+	})
+}
+
+func TestRollupExpiry(t *testing.T) {
+	s := NewState([]string{"host.name"}, time.Minute)
+	add(s, "host-1", 1, sample{cpu: 10, memory: 100}, t0)
+	moveTo(s, t0)
+
+	// Process 1 has not reported for over a minute
+	add(s, "host-1", 2, sample{cpu: 20, memory: 200}, t0.Add(50*time.Second))
+	checkRollup(t, "expired process", moveTo(s, t0.Add(70*time.Second))[0], 1, map[string]float64{
+		"process.cpu.time":           30, // what process 1 counted stays
+		"process.memory.usage":       200,
+		"process.memory.usage.max":   200,
+		"process.memory.usage.count": 1,
+		"process.requests":           0,
+	})
+
+	// Nothing is sent for hosts without new data, and once all their
+	// processes expire they are forgotten
+	if out := moveTo(s, t0.Add(3*time.Minute)); len(out) != 0 {
+		t.Errorf("%d rollups sent without new data", len(out))
+	}
+	if len(s.hosts) != 0 {
+		t.Errorf("%d hosts kept after every process expired", len(s.hosts))
+	}
+	add(s, "host-1", 3, sample{cpu: 5, memory: 50}, t0.Add(4*time.Minute))
+	checkRollup(t, "host back", moveTo(s, t0.Add(4*time.Minute))[0], 1, map[string]float64{
+		"process.cpu.time":           5,
+		"process.memory.usage":       50,
+		"process.memory.usage.max":   50,
+		"process.memory.usage.count": 1,
+		"process.requests":           0,
+	})
+}
+
+func TestRollupPerHost(t *testing.T) {
+	s := NewState([]string{"host.name", "k8s.cluster.name"}, time.Minute)
+	for pid := 1; pid <= 6; pid++ {
+		host := fmt.Sprintf("host-%d", 2-pid%2)
+		add(s, host, pid, sample{cpu: float64(pid), memory: 10}, t0)
+	}
+	out := moveTo(s, t0)
+	if len(out) != 2 {
+		t.Fatalf("%d rollups, want one per host", len(out))
+	}
+	for i, host := range []string{"host-1", "host-2"} {
+		want := map[string]any{"host.name": host, processExecutableName: ProcessName, CountAttribute: int64(3)}
+		if fmt.Sprint(out[i].attrs) != fmt.Sprint(want) {
+			t.Errorf("rollup %d resource = %v, want %v", i, out[i].attrs, want)
+		}
+	}
+	// host-1 has the odd PIDs
+	if got := out[0].values["process.cpu.time"]; got != 9 {
+		t.Errorf("host-1 process.cpu.time = %g, want 9", got)
+	}
+	if got := out[1].values["process.cpu.time"]; got != 12 {
+		t.Errorf("host-2 process.cpu.time = %g, want 12", got)
+	}
+}
@@ -0,0 +1,57 @@
+package phoenixcardinalityprocessor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"phoenix-vnext/apps/phoenix-otelcol/internal/hll"
+)
+
+// Config configures a phoenixcardinality processor.
+type Config struct {
+	// PipelineLabel is the phoenix_pipeline_label of the emitted series
+	// (full_fidelity, optimised or experimental).
+	PipelineLabel string `mapstructure:"pipeline_label"`
+	// Window is the sliding window distinct series are counted over.
+	Window time.Duration `mapstructure:"window"`
+	// WindowBuckets is the number of sub-sketches the window is split into;
+	// the window slides by Window/WindowBuckets.
+	WindowBuckets int `mapstructure:"window_buckets"`
+	// Precision is the precision of the pipeline-wide sketch (standard error
+	// about 1.04/sqrt(2^precision)).
+	Precision int `mapstructure:"precision"`
+	// MetricPrecision is the precision of the per-metric sketches.
+	MetricPrecision int `mapstructure:"metric_precision"`
+	// MaxMetrics bounds the number of metrics broken down; series of further
+	// metrics are counted under metric_name="_other".
+	MaxMetrics int `mapstructure:"max_metrics"`
+	// EmitInterval is how often the estimates are sent down the pipeline.
+	EmitInterval time.Duration `mapstructure:"emit_interval"`
+}
+
+// Validate checks the configuration.
+func (cfg *Config) Validate() error {
+	var errs []error
+	if cfg.PipelineLabel == "" {
+		errs = append(errs, errors.New("pipeline_label must be set"))
+	}
+	if cfg.Window <= 0 {
+		errs = append(errs, errors.New("window must be positive"))
+	}
+	if cfg.WindowBuckets < 1 {
+		errs = append(errs, errors.New("window_buckets must be at least 1"))
+	}
+	for name, p := range map[string]int{"precision": cfg.Precision, "metric_precision": cfg.MetricPrecision} {
+		if p < hll.MinPrecision || p > hll.MaxPrecision {
+			errs = append(errs, fmt.Errorf("%s must be between %d and %d", name, hll.MinPrecision, hll.MaxPrecision))
+		}
+	}
+	if cfg.MaxMetrics < 0 {
+		errs = append(errs, errors.New("max_metrics must not be negative"))
+	}
+	if cfg.EmitInterval <= 0 {
+		errs = append(errs, errors.New("emit_interval must be positive"))
+	}
+	return errors.Join(errs...)
+}
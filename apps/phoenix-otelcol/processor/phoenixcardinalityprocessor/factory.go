@@ -0,0 +1,49 @@
+// Package phoenixcardinalityprocessor estimates the number of distinct
+// series leaving a pipeline. Placed at the end of a pipeline, it hashes
+// every data point's identity (metric name, resource and data point
+// attributes) into HyperLogLog sketches over a sliding window and emits
+// phoenix.pipeline.output.cardinality_estimate, overall and per metric,
+// with memory bounded by the sketch precisions and max_metrics.
+package phoenixcardinalityprocessor
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	typeStr   = "phoenixcardinality"
+	stability = component.StabilityLevelDevelopment
+)
+
+// NewFactory returns the phoenixcardinality processor factory.
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		processor.WithMetrics(createMetricsProcessor, stability))
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Window:          5 * time.Minute,
+		WindowBuckets:   5,
+		Precision:       14,
+		MetricPrecision: 10,
+		MaxMetrics:      200,
+		EmitInterval:    15 * time.Second,
+	}
+}
+
+func createMetricsProcessor(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Metrics) (processor.Metrics, error) {
+	p := newCardinalityProcessor(cfg.(*Config), set.Logger, next)
+	return processorhelper.NewMetricsProcessor(ctx, set, cfg, next, p.processMetrics,
+		processorhelper.WithStart(p.start),
+		processorhelper.WithShutdown(p.shutdown),
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: false}))
+}
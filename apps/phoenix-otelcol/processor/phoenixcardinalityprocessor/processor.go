@@ -0,0 +1,224 @@
+package phoenixcardinalityprocessor
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"phoenix-vnext/apps/phoenix-otelcol/internal/hll"
+	"phoenix-vnext/apps/phoenix-otelcol/internal/metricutil"
+)
+
+const (
+	estimateMetric         = "phoenix.pipeline.output.cardinality_estimate"
+	estimateByMetricMetric = "phoenix.pipeline.output.cardinality_estimate_by_metric"
+	pipelineLabelAttribute = "phoenix_pipeline_label"
+	metricNameAttribute    = "metric_name"
+	// otherMetric collects the series of metrics beyond max_metrics.
+	otherMetric = "_other"
+	scopeName   = "phoenix-vnext/apps/phoenix-otelcol/processor/phoenixcardinalityprocessor"
+)
+
+type cardinalityProcessor struct {
+	cfg    *Config
+	logger *zap.Logger
+	next   consumer.Metrics
+
+	mu        sync.Mutex
+	buckets   []*bucket
+	current   int
+	rotatedAt time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// bucket holds the sketches of one slice of the window.
+type bucket struct {
+	total     *hll.Sketch
+	perMetric map[string]*hll.Sketch
+}
+
+func newCardinalityProcessor(cfg *Config, logger *zap.Logger, next consumer.Metrics) *cardinalityProcessor {
+	p := &cardinalityProcessor{
+		cfg:       cfg,
+		logger:    logger,
+		next:      next,
+		buckets:   make([]*bucket, cfg.WindowBuckets),
+		rotatedAt: time.Now(),
+	}
+	for i := range p.buckets {
+		p.buckets[i] = &bucket{total: hll.New(uint8(cfg.Precision)), perMetric: map[string]*hll.Sketch{}}
+	}
+	return p
+}
+
+func (p *cardinalityProcessor) start(_ context.Context, _ component.Host) error {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run()
+	return nil
+}
+
+func (p *cardinalityProcessor) shutdown(ctx context.Context) error {
+	if p.stop == nil {
+		return nil
+	}
+	close(p.stop)
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	p.stop = nil
+	return nil
+}
+
+func (p *cardinalityProcessor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.cfg.EmitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.emit(now)
+		}
+	}
+}
+
+// processMetrics counts the series of md and passes it on unchanged.
+func (p *cardinalityProcessor) processMetrics(_ context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rotate(now)
+	b := p.buckets[p.current]
+
+	var id strings.Builder
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		resource := metricutil.AttributesKey(rm.Resource().Attributes())
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			ms := sms.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				m := ms.At(k)
+				if m.Name() == estimateMetric || m.Name() == estimateByMetricMetric {
+					continue
+				}
+				perMetric := p.metricSketch(b, m.Name())
+				metricutil.ForEachDataPointAttributes(m, func(attrs pcommon.Map) {
+					id.Reset()
+					id.WriteString(resource)
+					id.WriteByte('\x01')
+					id.WriteString(m.Name())
+					id.WriteByte('\x01')
+					id.WriteString(metricutil.AttributesKey(attrs))
+					hash := xxhash.Sum64String(id.String())
+					b.total.Insert(hash)
+					perMetric.Insert(hash)
+				})
+			}
+		}
+	}
+	return md, nil
+}
+
+// metricSketch returns the sketch of metric name in b, or the _other sketch
+// once the bucket breaks down max_metrics metrics.
+func (p *cardinalityProcessor) metricSketch(b *bucket, name string) *hll.Sketch {
+	if h, ok := b.perMetric[name]; ok {
+		return h
+	}
+	if len(b.perMetric) >= p.cfg.MaxMetrics {
+		name = otherMetric
+		if h, ok := b.perMetric[name]; ok {
+			return h
+		}
+	}
+	h := hll.New(uint8(p.cfg.MetricPrecision))
+	b.perMetric[name] = h
+	return h
+}
+
+// rotate advances the window to now, clearing the buckets that fell out of
+// it.
+func (p *cardinalityProcessor) rotate(now time.Time) {
+	step := p.cfg.Window / time.Duration(p.cfg.WindowBuckets)
+	for n := 0; now.Sub(p.rotatedAt) >= step; n++ {
+		p.rotatedAt = p.rotatedAt.Add(step)
+		if n >= len(p.buckets) {
+			// Everything is stale; skip ahead
+			p.rotatedAt = now
+			continue
+		}
+		p.current = (p.current + 1) % len(p.buckets)
+		b := p.buckets[p.current]
+		b.total.Reset()
+		clear(b.perMetric)
+	}
+}
+
+// emit sends the estimates over the window ending at now down the pipeline.
+func (p *cardinalityProcessor) emit(now time.Time) {
+	p.mu.Lock()
+	p.rotate(now)
+	total := hll.New(uint8(p.cfg.Precision))
+	perMetric := map[string]*hll.Sketch{}
+	for _, b := range p.buckets {
+		total.Merge(b.total)
+		for name, h := range b.perMetric {
+			acc, ok := perMetric[name]
+			if !ok {
+				acc = hll.New(uint8(p.cfg.MetricPrecision))
+				perMetric[name] = acc
+			}
+			acc.Merge(h)
+		}
+	}
+	p.mu.Unlock()
+
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+	ts := pcommon.NewTimestampFromTime(now)
+
+	m := sm.Metrics().AppendEmpty()
+	m.SetName(estimateMetric)
+	m.SetDescription("Estimated distinct series leaving the pipeline over the window.")
+	m.SetUnit("{series}")
+	dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(ts)
+	dp.SetDoubleValue(total.Estimate())
+	dp.Attributes().PutStr(pipelineLabelAttribute, p.cfg.PipelineLabel)
+
+	if len(perMetric) > 0 {
+		m = sm.Metrics().AppendEmpty()
+		m.SetName(estimateByMetricMetric)
+		m.SetDescription("Estimated distinct series leaving the pipeline over the window, per metric.")
+		m.SetUnit("{series}")
+		dps := m.SetEmptyGauge().DataPoints()
+		for name, h := range perMetric {
+			dp := dps.AppendEmpty()
+			dp.SetTimestamp(ts)
+			dp.SetDoubleValue(h.Estimate())
+			dp.Attributes().PutStr(pipelineLabelAttribute, p.cfg.PipelineLabel)
+			dp.Attributes().PutStr(metricNameAttribute, name)
+		}
+	}
+
+	if err := p.next.ConsumeMetrics(context.Background(), md); err != nil {
+		p.logger.Warn("Failed to emit cardinality estimates", zap.Error(err))
+	}
+}
@@ -0,0 +1,126 @@
+package phoenixcardinalityprocessor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+func newTestProcessor(maxMetrics int) (*cardinalityProcessor, *consumertest.MetricsSink) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.PipelineLabel = "optimised"
+	cfg.MaxMetrics = maxMetrics
+	sink := new(consumertest.MetricsSink)
+	return newCardinalityProcessor(cfg, zap.NewNop(), sink), sink
+}
+
+// series returns n series of each metric, named by metrics.
+func series(n int, metrics ...string) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("host.name", "host-1")
+	ms := rm.ScopeMetrics().AppendEmpty().Metrics()
+	for _, name := range metrics {
+		m := ms.AppendEmpty()
+		m.SetName(name)
+		dps := m.SetEmptyGauge().DataPoints()
+		for i := 0; i < n; i++ {
+			dp := dps.AppendEmpty()
+			dp.Attributes().PutInt("process.pid", int64(i))
+			dp.SetIntValue(1)
+		}
+	}
+	return md
+}
+
+func process(t *testing.T, p *cardinalityProcessor, md pmetric.Metrics) {
+	t.Helper()
+	if _, err := p.processMetrics(context.Background(), md); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// estimates emits at now and returns the pipeline estimate and the
+// per-metric ones, rounded.
+func estimates(t *testing.T, p *cardinalityProcessor, sink *consumertest.MetricsSink, now time.Time) (float64, map[string]float64) {
+	t.Helper()
+	sink.Reset()
+	p.emit(now)
+	ms := sink.AllMetrics()[0].ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	total := math.Round(ms.At(0).Gauge().DataPoints().At(0).DoubleValue())
+	perMetric := map[string]float64{}
+	if ms.Len() > 1 {
+		dps := ms.At(1).Gauge().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			name, _ := dps.At(i).Attributes().Get(metricNameAttribute)
+			perMetric[name.Str()] = math.Round(dps.At(i).DoubleValue())
+		}
+	}
+	return total, perMetric
+}
+
+// The window is five one-minute buckets; series count until the bucket
+// they arrived in is reused.
+func TestWindowRotation(t *testing.T) {
+	p, sink := newTestProcessor(200)
+	start := p.rotatedAt
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	process(t, p, series(100, "process.cpu.time"))
+
+	tests := []struct {
+		name        string
+		at          time.Time
+		want        float64
+		wantCurrent int
+	}{
+		{"same bucket", at(30 * time.Second), 100, 0},
+		{"two buckets later", at(150 * time.Second), 100, 2},
+		{"bucket reused", at(330 * time.Second), 0, 0},
+	}
+	for _, tt := range tests {
+		total, _ := estimates(t, p, sink, tt.at)
+		if total != tt.want || p.current != tt.wantCurrent {
+			t.Errorf("%s: estimate %g in bucket %d, want %g in bucket %d", tt.name, total, p.current, tt.want, tt.wantCurrent)
+		}
+	}
+
+	// After a long gap every bucket is cleared once and the window restarts
+	// at the gap's end rather than stepping through every missed minute
+	process(t, p, series(50, "process.cpu.time"))
+	if total, _ := estimates(t, p, sink, at(6*time.Minute)); total != 50 {
+		t.Fatalf("estimate before the gap = %g, want 50", total)
+	}
+	gapEnd := at(time.Hour + 10*time.Second)
+	if total, perMetric := estimates(t, p, sink, gapEnd); total != 0 || len(perMetric) != 0 {
+		t.Errorf("estimate after the gap = %g %v, want 0", total, perMetric)
+	}
+	if !p.rotatedAt.Equal(gapEnd) {
+		t.Errorf("window restarted at %s, want %s", p.rotatedAt, gapEnd)
+	}
+	current := p.current
+	estimates(t, p, sink, gapEnd.Add(59*time.Second))
+	if p.current != current {
+		t.Error("rotated within a minute of the skip-ahead")
+	}
+}
+
+func TestMaxMetrics(t *testing.T) {
+	p, sink := newTestProcessor(2)
+	process(t, p, series(10, "a", "b", "c", "d"))
+	// A metric counted before the limit keeps its own estimate
+	process(t, p, series(20, "b"))
+	total, perMetric := estimates(t, p, sink, p.rotatedAt)
+	if total != 50 {
+		t.Errorf("pipeline estimate = %g, want 50", total)
+	}
+	want := map[string]float64{"a": 10, "b": 20, otherMetric: 20}
+	if fmt.Sprint(perMetric) != fmt.Sprint(want) {
+		t.Errorf("per-metric estimates = %v, want %v", perMetric, want)
+	}
+}
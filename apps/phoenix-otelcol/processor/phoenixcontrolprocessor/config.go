@@ -0,0 +1,124 @@
+package phoenixcontrolprocessor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// Pipeline names accepted by the pipeline setting, matching the
+// pipelines.<name>_enabled flags of the control file.
+const (
+	pipelineFullFidelity = "full_fidelity"
+	pipelineOptimised    = "optimised"
+	pipelineExperimental = "experimental"
+)
+
+// defaultRollupExpireAfter applies when rollup.expire_after is not set.
+const defaultRollupExpireAfter = time.Minute
+
+// Config configures a phoenixcontrol processor.
+type Config struct {
+	// ControlFile is the optimization_mode.yaml written by the actuator.
+	ControlFile string `mapstructure:"control_file"`
+	// PollInterval is how often the control file is checked for changes.
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// Pipeline, when set, drops everything while the control file disables
+	// that pipeline (full_fidelity, optimised or experimental).
+	Pipeline string `mapstructure:"pipeline"`
+	// StatusEndpoint, when set, serves the phoenix_main_applied_control_*
+	// metrics in Prometheus format. Processors naming the same endpoint
+	// share one listener.
+	StatusEndpoint string `mapstructure:"status_endpoint"`
+	// Profiles are the rules applied under each optimisation profile. A
+	// profile without rules passes data through unchanged.
+	Profiles map[string]ProfileRules `mapstructure:"profiles"`
+	// Rollup, when set, folds the processes the profile filters out into one
+	// process.executable.name="_other" resource per host instead of dropping
+	// them, so host totals stay correct.
+	Rollup *RollupConfig `mapstructure:"rollup"`
+}
+
+// RollupConfig configures the rollup of filtered-out processes.
+type RollupConfig struct {
+	// HostAttribute is the resource attribute processes are grouped by.
+	HostAttribute string `mapstructure:"host_attribute"`
+	// Attributes are the resource attributes, besides HostAttribute, copied
+	// onto each host's rollup resource. They should be host-level so the
+	// rollup keeps one identity.
+	Attributes []string `mapstructure:"attributes"`
+	// ExpireAfter is how long a process stays in its host's rollup after
+	// its last batch. Zero means one minute.
+	ExpireAfter time.Duration `mapstructure:"expire_after"`
+}
+
+// ProfileRules are the filter and attribute-stripping rules of one profile.
+type ProfileRules struct {
+	// IncludeProcesses keeps only resources whose process.executable.name
+	// matches this regexp.
+	IncludeProcesses string `mapstructure:"include_processes"`
+	// ExcludeProcesses drops resources whose process.executable.name
+	// matches this regexp.
+	ExcludeProcesses string `mapstructure:"exclude_processes"`
+	// DropAttributes are deleted from resources and data points.
+	DropAttributes []string `mapstructure:"drop_attributes"`
+}
+
+// Validate checks the configuration.
+func (cfg *Config) Validate() error {
+	var errs []error
+	if cfg.ControlFile == "" {
+		errs = append(errs, errors.New("control_file must be set"))
+	}
+	if cfg.PollInterval <= 0 {
+		errs = append(errs, errors.New("poll_interval must be positive"))
+	}
+	switch cfg.Pipeline {
+	case "", pipelineFullFidelity, pipelineOptimised, pipelineExperimental:
+	default:
+		errs = append(errs, fmt.Errorf("pipeline %q is not full_fidelity, optimised or experimental", cfg.Pipeline))
+	}
+	if cfg.Rollup != nil {
+		if cfg.Rollup.HostAttribute == "" {
+			errs = append(errs, errors.New("rollup.host_attribute must be set"))
+		}
+		if cfg.Rollup.ExpireAfter < 0 {
+			errs = append(errs, errors.New("rollup.expire_after must not be negative"))
+		}
+	}
+	for profile, rules := range cfg.Profiles {
+		if !controlfile.IsProfile(profile) {
+			errs = append(errs, fmt.Errorf("profiles: %q is not conservative, balanced or aggressive", profile))
+		}
+		if _, err := rules.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("profiles.%s: %w", profile, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compiledRules are ProfileRules with their regexps compiled.
+type compiledRules struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+	drop    []string
+}
+
+func (r ProfileRules) compile() (*compiledRules, error) {
+	c := &compiledRules{drop: r.DropAttributes}
+	var err error
+	if r.IncludeProcesses != "" {
+		if c.include, err = regexp.Compile(r.IncludeProcesses); err != nil {
+			return nil, fmt.Errorf("invalid include_processes: %w", err)
+		}
+	}
+	if r.ExcludeProcesses != "" {
+		if c.exclude, err = regexp.Compile(r.ExcludeProcesses); err != nil {
+			return nil, fmt.Errorf("invalid exclude_processes: %w", err)
+		}
+	}
+	return c, nil
+}
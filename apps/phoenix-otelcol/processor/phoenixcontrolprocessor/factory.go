@@ -0,0 +1,48 @@
+// Package phoenixcontrolprocessor applies the optimisation profile from the
+// control file written by the control-actuator. It reloads the file while
+// the collector runs and applies the filter and attribute-stripping rules
+// configured for the profile in force, so a profile change takes effect
+// without a collector restart. Processes the filters reject can be folded
+// into per-host rollups instead of being dropped.
+package phoenixcontrolprocessor
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	typeStr   = "phoenixcontrol"
+	stability = component.StabilityLevelDevelopment
+)
+
+// NewFactory returns the phoenixcontrol processor factory.
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		processor.WithMetrics(createMetricsProcessor, stability))
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		ControlFile:  "/etc/otelcol/control/optimization_mode.yaml",
+		PollInterval: 5 * time.Second,
+	}
+}
+
+func createMetricsProcessor(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Metrics) (processor.Metrics, error) {
+	p, err := newControlProcessor(cfg.(*Config), set.Logger)
+	if err != nil {
+		return nil, err
+	}
+	return processorhelper.NewMetricsProcessor(ctx, set, cfg, next, p.processMetrics,
+		processorhelper.WithStart(p.start),
+		processorhelper.WithShutdown(p.shutdown),
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}))
+}
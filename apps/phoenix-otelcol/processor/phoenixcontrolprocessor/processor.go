@@ -0,0 +1,178 @@
+package phoenixcontrolprocessor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.uber.org/zap"
+
+	"phoenix-vnext/apps/phoenix-otelcol/internal/controlwatch"
+	"phoenix-vnext/apps/phoenix-otelcol/internal/metricutil"
+	"phoenix-vnext/apps/phoenix-otelcol/internal/rollup"
+	"phoenix-vnext/pkg/controlfile"
+)
+
+// processExecutableName is the resource attribute the process filters match.
+const processExecutableName = "process.executable.name"
+
+type controlProcessor struct {
+	cfg    *Config
+	logger *zap.Logger
+	rules  map[string]*compiledRules
+
+	watcher *controlwatch.Watcher
+	status  *controlwatch.StatusServer
+
+	mu      sync.Mutex
+	rollups *rollup.State // nil without rollup
+}
+
+func newControlProcessor(cfg *Config, logger *zap.Logger) (*controlProcessor, error) {
+	p := &controlProcessor{cfg: cfg, logger: logger, rules: map[string]*compiledRules{}}
+	if cfg.Rollup != nil {
+		expireAfter := cfg.Rollup.ExpireAfter
+		if expireAfter == 0 {
+			expireAfter = defaultRollupExpireAfter
+		}
+		p.rollups = rollup.NewState(append([]string{cfg.Rollup.HostAttribute}, cfg.Rollup.Attributes...), expireAfter)
+	}
+	for profile, r := range cfg.Profiles {
+		c, err := r.compile()
+		if err != nil {
+			return nil, err
+		}
+		p.rules[profile] = c
+	}
+	return p, nil
+}
+
+func (p *controlProcessor) start(_ context.Context, _ component.Host) error {
+	if p.cfg.StatusEndpoint != "" {
+		s, err := controlwatch.AcquireStatusServer(p.cfg.StatusEndpoint, p.logger)
+		if err != nil {
+			return err
+		}
+		p.status = s
+	}
+	p.watcher = controlwatch.Acquire(p.cfg.ControlFile, p.cfg.PollInterval, p.logger)
+	return nil
+}
+
+func (p *controlProcessor) shutdown(ctx context.Context) error {
+	if p.watcher != nil {
+		p.watcher.Release()
+		p.watcher = nil
+	}
+	if p.status != nil {
+		err := p.status.Release(ctx)
+		p.status = nil
+		return err
+	}
+	return nil
+}
+
+func (p *controlProcessor) processMetrics(_ context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
+	f := p.watcher.Current()
+	if !p.pipelineEnabled(f) {
+		return md, processorhelper.ErrSkipProcessingData
+	}
+	rules := p.rules[f.OptimizationProfile]
+	if rules == nil {
+		return md, nil
+	}
+
+	rms := md.ResourceMetrics()
+	if rules.include != nil || rules.exclude != nil {
+		if p.rollups != nil {
+			p.filterAndRollUp(rms, rules)
+		} else {
+			rms.RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+				return !rules.keep(rm.Resource())
+			})
+		}
+	}
+	if len(rules.drop) > 0 {
+		for i := 0; i < rms.Len(); i++ {
+			rm := rms.At(i)
+			deleteKeys(rm.Resource().Attributes(), rules.drop)
+			sms := rm.ScopeMetrics()
+			for j := 0; j < sms.Len(); j++ {
+				ms := sms.At(j).Metrics()
+				for k := 0; k < ms.Len(); k++ {
+					metricutil.ForEachDataPointAttributes(ms.At(k), func(attrs pcommon.Map) {
+						deleteKeys(attrs, rules.drop)
+					})
+				}
+			}
+		}
+	}
+	if rms.Len() == 0 {
+		return md, processorhelper.ErrSkipProcessingData
+	}
+	return md, nil
+}
+
+// filterAndRollUp folds the resources rules reject into the rollups of
+// their hosts and appends the rollups to rms.
+func (p *controlProcessor) filterAndRollUp(rms pmetric.ResourceMetricsSlice, rules *compiledRules) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rms.RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		attrs := rm.Resource().Attributes()
+		if _, ok := attrs.Get(processExecutableName); !ok {
+			return false
+		}
+		var host string
+		if v, ok := attrs.Get(p.cfg.Rollup.HostAttribute); ok {
+			host = v.AsString()
+		}
+		key := metricutil.AttributesKey(attrs)
+		if rules.keep(rm.Resource()) {
+			p.rollups.Remove(host, key)
+			return false
+		}
+		p.rollups.Add(host, key, rm, now)
+		return true
+	})
+	p.rollups.MoveTo(rms, now)
+}
+
+// pipelineEnabled reports whether the control file enables this
+// processor's pipeline. Processors without a pipeline are always enabled.
+func (p *controlProcessor) pipelineEnabled(f *controlfile.File) bool {
+	switch p.cfg.Pipeline {
+	case pipelineFullFidelity:
+		return f.Pipelines.FullFidelityEnabled
+	case pipelineOptimised:
+		return f.Pipelines.OptimizedEnabled
+	case pipelineExperimental:
+		return f.Pipelines.ExperimentalEnabled
+	}
+	return true
+}
+
+// keep reports whether a resource passes the process filters. Resources
+// without process.executable.name are not process metrics and always pass.
+func (r *compiledRules) keep(res pcommon.Resource) bool {
+	v, ok := res.Attributes().Get(processExecutableName)
+	if !ok {
+		return true
+	}
+	name := v.AsString()
+	if r.include != nil && !r.include.MatchString(name) {
+		return false
+	}
+	return r.exclude == nil || !r.exclude.MatchString(name)
+}
+
+func deleteKeys(attrs pcommon.Map, keys []string) {
+	for _, k := range keys {
+		attrs.Remove(k)
+	}
+}
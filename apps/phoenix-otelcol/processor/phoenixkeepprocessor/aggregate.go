@@ -0,0 +1,126 @@
+package phoenixkeepprocessor
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// aggregates holds the last value of every input series that feeds a kept
+// series. Processes export in separate batches, so series collapsing onto
+// the same identity are summed over the last value of each input rather
+// than within a batch.
+type aggregates struct {
+	series map[string]*aggregate
+}
+
+// aggregate is one kept series and the input series feeding it.
+type aggregate struct {
+	inputs map[string]*input
+}
+
+// input is the last point of one input series.
+type input struct {
+	intValue    int64
+	doubleValue float64
+	isInt       bool
+	start       pcommon.Timestamp
+	seen        time.Time
+}
+
+func newAggregates() *aggregates {
+	return &aggregates{series: map[string]*aggregate{}}
+}
+
+// note records that input series source, whose points carry no value kept
+// here, feeds kept series id.
+func (a *aggregates) note(id, source string, now time.Time) {
+	a.inputsOf(id)[source] = &input{seen: now}
+}
+
+// record stores dp as the last point of input series source, which feeds
+// kept series id.
+func (a *aggregates) record(id, source string, dp pmetric.NumberDataPoint, now time.Time) {
+	in := &input{start: dp.StartTimestamp(), seen: now}
+	if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		in.intValue, in.isInt = dp.IntValue(), true
+	} else {
+		in.doubleValue = dp.DoubleValue()
+	}
+	a.inputsOf(id)[source] = in
+}
+
+func (a *aggregates) inputsOf(id string) map[string]*input {
+	agg, ok := a.series[id]
+	if !ok {
+		agg = &aggregate{inputs: map[string]*input{}}
+		a.series[id] = agg
+	}
+	return agg.inputs
+}
+
+// apply sets dp to the sum of the inputs of kept series id, with their
+// earliest start timestamp.
+func (a *aggregates) apply(id string, dp pmetric.NumberDataPoint) {
+	agg, ok := a.series[id]
+	if !ok || len(agg.inputs) < 2 {
+		return
+	}
+	var (
+		intSum    int64
+		doubleSum float64
+		allInt    = true
+		start     pcommon.Timestamp
+	)
+	for _, in := range agg.inputs {
+		if in.isInt {
+			intSum += in.intValue
+			doubleSum += float64(in.intValue)
+		} else {
+			allInt = false
+			doubleSum += in.doubleValue
+		}
+		if in.start != 0 && (start == 0 || in.start < start) {
+			start = in.start
+		}
+	}
+	if allInt {
+		dp.SetIntValue(intSum)
+	} else {
+		dp.SetDoubleValue(doubleSum)
+	}
+	dp.SetStartTimestamp(start)
+}
+
+// expire forgets input series not seen since before cutoff and returns the
+// number of input series merged into another kept series.
+func (a *aggregates) expire(cutoff time.Time) int {
+	merged := 0
+	for id, agg := range a.series {
+		for source, in := range agg.inputs {
+			if in.seen.Before(cutoff) {
+				delete(agg.inputs, source)
+			}
+		}
+		if len(agg.inputs) == 0 {
+			delete(a.series, id)
+			continue
+		}
+		merged += len(agg.inputs) - 1
+	}
+	return merged
+}
+
+// stateful reports whether the points of m are levels or running totals,
+// whose inputs are summed over their last values; delta sums are added up
+// within the batch instead.
+func stateful(m pmetric.Metric) bool {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		return true
+	case pmetric.MetricTypeSum:
+		return m.Sum().AggregationTemporality() != pmetric.AggregationTemporalityDelta
+	}
+	return false
+}
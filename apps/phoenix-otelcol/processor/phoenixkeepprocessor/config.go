@@ -0,0 +1,44 @@
+package phoenixkeepprocessor
+
+import (
+	"errors"
+	"time"
+)
+
+// Config configures a phoenixkeep processor.
+type Config struct {
+	// ControlFile is the optimization_mode.yaml written by the actuator.
+	ControlFile string `mapstructure:"control_file"`
+	// PollInterval is how often the control file is checked for changes.
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// PipelineLabel is the phoenix_pipeline_label of the merged-series
+	// count (full_fidelity, optimised or experimental).
+	PipelineLabel string `mapstructure:"pipeline_label"`
+	// ExpireAfter is how long an input series is remembered after its last
+	// point. It should match the exporter's metric_expiration.
+	ExpireAfter time.Duration `mapstructure:"expire_after"`
+	// EmitInterval is how often the merged-series count is sent down the
+	// pipeline.
+	EmitInterval time.Duration `mapstructure:"emit_interval"`
+}
+
+// Validate checks the configuration.
+func (cfg *Config) Validate() error {
+	var errs []error
+	if cfg.ControlFile == "" {
+		errs = append(errs, errors.New("control_file must be set"))
+	}
+	if cfg.PollInterval <= 0 {
+		errs = append(errs, errors.New("poll_interval must be positive"))
+	}
+	if cfg.PipelineLabel == "" {
+		errs = append(errs, errors.New("pipeline_label must be set"))
+	}
+	if cfg.ExpireAfter <= 0 {
+		errs = append(errs, errors.New("expire_after must be positive"))
+	}
+	if cfg.EmitInterval <= 0 {
+		errs = append(errs, errors.New("emit_interval must be positive"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,48 @@
+// Package phoenixkeepprocessor strips every attribute not on the allow-list
+// of the optimisation profile in force. The per-profile lists, for resource
+// and data point attributes, come from attribute_keep_lists in the control
+// file, which is reloaded while the collector runs. Series left with the
+// same identity after stripping are merged, summing the last value of each
+// input series across batches, and the number of input series merged is
+// emitted as phoenix.pipeline.keep.merged_series.
+package phoenixkeepprocessor
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	typeStr   = "phoenixkeep"
+	stability = component.StabilityLevelDevelopment
+)
+
+// NewFactory returns the phoenixkeep processor factory.
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		processor.WithMetrics(createMetricsProcessor, stability))
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		ControlFile:  "/etc/otelcol/control/optimization_mode.yaml",
+		PollInterval: 5 * time.Second,
+		ExpireAfter:  5 * time.Minute,
+		EmitInterval: 15 * time.Second,
+	}
+}
+
+func createMetricsProcessor(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Metrics) (processor.Metrics, error) {
+	p := newKeepProcessor(cfg.(*Config), set.Logger, next)
+	return processorhelper.NewMetricsProcessor(ctx, set, cfg, next, p.processMetrics,
+		processorhelper.WithStart(p.start),
+		processorhelper.WithShutdown(p.shutdown),
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}))
+}
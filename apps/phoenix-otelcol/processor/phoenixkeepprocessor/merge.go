@@ -0,0 +1,205 @@
+package phoenixkeepprocessor
+
+import (
+	"slices"
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"phoenix-vnext/apps/phoenix-otelcol/internal/metricutil"
+)
+
+// mergeResources merges the resources of rms with the same attributes, then
+// their scopes, metrics and data points with the same identity.
+//
+// Points of gauges and cumulative sums already carry the aggregate of their
+// inputs, so the first is kept. Delta sums are added and histograms are
+// merged when their bucket bounds match; otherwise, and for exponential
+// histograms and summaries, the first point is kept.
+func mergeResources(rms pmetric.ResourceMetricsSlice) {
+	byKey := map[string]pmetric.ResourceMetrics{}
+	rms.RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		key := metricutil.AttributesKey(rm.Resource().Attributes())
+		if dst, ok := byKey[key]; ok {
+			rm.ScopeMetrics().MoveAndAppendTo(dst.ScopeMetrics())
+			return true
+		}
+		byKey[key] = rm
+		return false
+	})
+
+	for i := 0; i < rms.Len(); i++ {
+		sms := rms.At(i).ScopeMetrics()
+		mergeScopes(sms)
+		for j := 0; j < sms.Len(); j++ {
+			ms := sms.At(j).Metrics()
+			mergeMetrics(ms)
+			for k := 0; k < ms.Len(); k++ {
+				mergePoints(ms.At(k))
+			}
+		}
+	}
+}
+
+func mergeScopes(sms pmetric.ScopeMetricsSlice) {
+	byKey := map[string]pmetric.ScopeMetrics{}
+	sms.RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+		scope := sm.Scope()
+		key := scope.Name() + "\x01" + scope.Version() + "\x01" + metricutil.AttributesKey(scope.Attributes())
+		if dst, ok := byKey[key]; ok {
+			sm.Metrics().MoveAndAppendTo(dst.Metrics())
+			return true
+		}
+		byKey[key] = sm
+		return false
+	})
+}
+
+func mergeMetrics(ms pmetric.MetricSlice) {
+	byKey := map[string]pmetric.Metric{}
+	ms.RemoveIf(func(m pmetric.Metric) bool {
+		key := metricKey(m)
+		dst, ok := byKey[key]
+		if !ok {
+			byKey[key] = m
+			return false
+		}
+		switch m.Type() {
+		case pmetric.MetricTypeGauge:
+			m.Gauge().DataPoints().MoveAndAppendTo(dst.Gauge().DataPoints())
+		case pmetric.MetricTypeSum:
+			m.Sum().DataPoints().MoveAndAppendTo(dst.Sum().DataPoints())
+		case pmetric.MetricTypeHistogram:
+			m.Histogram().DataPoints().MoveAndAppendTo(dst.Histogram().DataPoints())
+		case pmetric.MetricTypeExponentialHistogram:
+			m.ExponentialHistogram().DataPoints().MoveAndAppendTo(dst.ExponentialHistogram().DataPoints())
+		case pmetric.MetricTypeSummary:
+			m.Summary().DataPoints().MoveAndAppendTo(dst.Summary().DataPoints())
+		}
+		return true
+	})
+}
+
+// metricKey identifies a metric by everything that must agree for its data
+// points to be merged.
+func metricKey(m pmetric.Metric) string {
+	key := m.Name() + "\x01" + m.Unit() + "\x01" + m.Type().String()
+	switch m.Type() {
+	case pmetric.MetricTypeSum:
+		key += "\x01" + m.Sum().AggregationTemporality().String() + "\x01" + strconv.FormatBool(m.Sum().IsMonotonic())
+	case pmetric.MetricTypeHistogram:
+		key += "\x01" + m.Histogram().AggregationTemporality().String()
+	case pmetric.MetricTypeExponentialHistogram:
+		key += "\x01" + m.ExponentialHistogram().AggregationTemporality().String()
+	}
+	return key
+}
+
+// mergePoints merges the data points of m with the same attributes.
+func mergePoints(m pmetric.Metric) {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		mergeNumberPoints(m.Gauge().DataPoints(), false)
+	case pmetric.MetricTypeSum:
+		mergeNumberPoints(m.Sum().DataPoints(), !stateful(m))
+	case pmetric.MetricTypeHistogram:
+		byKey := map[string]pmetric.HistogramDataPoint{}
+		m.Histogram().DataPoints().RemoveIf(func(dp pmetric.HistogramDataPoint) bool {
+			key := metricutil.AttributesKey(dp.Attributes())
+			dst, ok := byKey[key]
+			if !ok {
+				byKey[key] = dp
+				return false
+			}
+			addHistogram(dst, dp)
+			return true
+		})
+	case pmetric.MetricTypeExponentialHistogram:
+		seen := map[string]bool{}
+		m.ExponentialHistogram().DataPoints().RemoveIf(func(dp pmetric.ExponentialHistogramDataPoint) bool {
+			return seenBefore(seen, dp.Attributes())
+		})
+	case pmetric.MetricTypeSummary:
+		seen := map[string]bool{}
+		m.Summary().DataPoints().RemoveIf(func(dp pmetric.SummaryDataPoint) bool {
+			return seenBefore(seen, dp.Attributes())
+		})
+	}
+}
+
+// mergeNumberPoints merges points with the same attributes, adding their
+// values if add is set and keeping the first otherwise.
+func mergeNumberPoints(dps pmetric.NumberDataPointSlice, add bool) {
+	byKey := map[string]pmetric.NumberDataPoint{}
+	dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+		key := metricutil.AttributesKey(dp.Attributes())
+		dst, ok := byKey[key]
+		if !ok {
+			byKey[key] = dp
+			return false
+		}
+		if add {
+			if dst.ValueType() == pmetric.NumberDataPointValueTypeInt && dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+				dst.SetIntValue(dst.IntValue() + dp.IntValue())
+			} else {
+				dst.SetDoubleValue(metricutil.NumberValue(dst) + metricutil.NumberValue(dp))
+			}
+		}
+		mergeTimestamps(dst, dp)
+		return true
+	})
+}
+
+// addHistogram adds src to dst when their bucket bounds match; otherwise
+// dst is left as it is.
+func addHistogram(dst, src pmetric.HistogramDataPoint) {
+	if !slices.Equal(dst.ExplicitBounds().AsRaw(), src.ExplicitBounds().AsRaw()) ||
+		dst.BucketCounts().Len() != src.BucketCounts().Len() {
+		return
+	}
+	dst.SetCount(dst.Count() + src.Count())
+	if dst.HasSum() && src.HasSum() {
+		dst.SetSum(dst.Sum() + src.Sum())
+	}
+	if dst.HasMin() && src.HasMin() {
+		dst.SetMin(min(dst.Min(), src.Min()))
+	}
+	if dst.HasMax() && src.HasMax() {
+		dst.SetMax(max(dst.Max(), src.Max()))
+	}
+	counts := dst.BucketCounts()
+	for i := 0; i < counts.Len(); i++ {
+		counts.SetAt(i, counts.At(i)+src.BucketCounts().At(i))
+	}
+	mergeTimestamps(dst, src)
+}
+
+// timestamped is a data point with start and end timestamps.
+type timestamped interface {
+	StartTimestamp() pcommon.Timestamp
+	SetStartTimestamp(pcommon.Timestamp)
+	Timestamp() pcommon.Timestamp
+	SetTimestamp(pcommon.Timestamp)
+}
+
+// mergeTimestamps gives dst the latest timestamp and earliest start
+// timestamp of dst and src.
+func mergeTimestamps(dst, src timestamped) {
+	if src.Timestamp() > dst.Timestamp() {
+		dst.SetTimestamp(src.Timestamp())
+	}
+	if src.StartTimestamp() != 0 && (dst.StartTimestamp() == 0 || src.StartTimestamp() < dst.StartTimestamp()) {
+		dst.SetStartTimestamp(src.StartTimestamp())
+	}
+}
+
+// seenBefore reports whether a point with attrs was already seen.
+func seenBefore(seen map[string]bool, attrs pcommon.Map) bool {
+	key := metricutil.AttributesKey(attrs)
+	if seen[key] {
+		return true
+	}
+	seen[key] = true
+	return false
+}
@@ -0,0 +1,107 @@
+package phoenixkeepprocessor
+
+import (
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// histograms returns two resources with the same attributes, each with one
+// latency histogram point.
+func histograms(bounds1, bounds2 []float64, counts1, counts2 []uint64) pmetric.ResourceMetricsSlice {
+	rms := pmetric.NewResourceMetricsSlice()
+	for _, h := range []struct {
+		bounds []float64
+		counts []uint64
+		min    float64
+		max    float64
+	}{{bounds1, counts1, 2, 40}, {bounds2, counts2, 1, 30}} {
+		rm := rms.AppendEmpty()
+		rm.Resource().Attributes().PutStr("host.name", "host-1")
+		m := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+		m.SetName("http.server.duration")
+		hist := m.SetEmptyHistogram()
+		hist.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		dp := hist.DataPoints().AppendEmpty()
+		dp.ExplicitBounds().FromRaw(h.bounds)
+		dp.BucketCounts().FromRaw(h.counts)
+		var count uint64
+		for _, c := range h.counts {
+			count += c
+		}
+		dp.SetCount(count)
+		dp.SetSum(float64(count) * 10)
+		dp.SetMin(h.min)
+		dp.SetMax(h.max)
+	}
+	return rms
+}
+
+func TestMergeHistograms(t *testing.T) {
+	tests := []struct {
+		name       string
+		bounds1    []float64
+		bounds2    []float64
+		counts2    []uint64
+		wantCount  uint64
+		wantCounts []uint64
+		wantMin    float64
+		wantMax    float64
+	}{
+		{
+			name:    "matching bounds",
+			bounds1: []float64{10, 100}, bounds2: []float64{10, 100}, counts2: []uint64{4, 5, 6},
+			wantCount: 21, wantCounts: []uint64{5, 7, 9}, wantMin: 1, wantMax: 40,
+		},
+		{
+			// The first point is kept as it is
+			name:    "mismatched bounds",
+			bounds1: []float64{10, 100}, bounds2: []float64{5, 50}, counts2: []uint64{4, 5, 6},
+			wantCount: 6, wantCounts: []uint64{1, 2, 3}, wantMin: 2, wantMax: 40,
+		},
+		{
+			name:    "mismatched bucket count",
+			bounds1: []float64{10, 100}, bounds2: []float64{10, 100}, counts2: []uint64{4, 5},
+			wantCount: 6, wantCounts: []uint64{1, 2, 3}, wantMin: 2, wantMax: 40,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rms := histograms(tt.bounds1, tt.bounds2, []uint64{1, 2, 3}, tt.counts2)
+			mergeResources(rms)
+			if rms.Len() != 1 || rms.At(0).ScopeMetrics().Len() != 1 || rms.At(0).ScopeMetrics().At(0).Metrics().Len() != 1 {
+				t.Fatal("resources, scopes or metrics with the same identity were not merged")
+			}
+			dps := rms.At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints()
+			if dps.Len() != 1 {
+				t.Fatalf("%d points, want 1", dps.Len())
+			}
+			dp := dps.At(0)
+			if dp.Count() != tt.wantCount || !slices.Equal(dp.BucketCounts().AsRaw(), tt.wantCounts) {
+				t.Errorf("count %d buckets %v, want %d %v", dp.Count(), dp.BucketCounts().AsRaw(), tt.wantCount, tt.wantCounts)
+			}
+			if dp.Sum() != float64(tt.wantCount)*10 || dp.Min() != tt.wantMin || dp.Max() != tt.wantMax {
+				t.Errorf("sum %g min %g max %g, want %g %g %g", dp.Sum(), dp.Min(), dp.Max(), float64(tt.wantCount)*10, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+// Points of different metrics identities are kept apart.
+func TestMergeKeepsDistinctMetrics(t *testing.T) {
+	rms := pmetric.NewResourceMetricsSlice()
+	for _, temporality := range []pmetric.AggregationTemporality{pmetric.AggregationTemporalityDelta, pmetric.AggregationTemporalityCumulative} {
+		rm := rms.AppendEmpty()
+		rm.Resource().Attributes().PutStr("host.name", "host-1")
+		m := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+		m.SetName("process.requests")
+		sum := m.SetEmptySum()
+		sum.SetAggregationTemporality(temporality)
+		sum.DataPoints().AppendEmpty().SetIntValue(1)
+	}
+	mergeResources(rms)
+	if n := rms.At(0).ScopeMetrics().At(0).Metrics().Len(); rms.Len() != 1 || n != 2 {
+		t.Errorf("%d resources with %d metrics, want 1 with the delta and cumulative sums apart", rms.Len(), n)
+	}
+}
@@ -0,0 +1,220 @@
+package phoenixkeepprocessor
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"phoenix-vnext/apps/phoenix-otelcol/internal/controlwatch"
+	"phoenix-vnext/apps/phoenix-otelcol/internal/metricutil"
+	"phoenix-vnext/pkg/controlfile"
+)
+
+const (
+	mergedMetric           = "phoenix.pipeline.keep.merged_series"
+	pipelineLabelAttribute = "phoenix_pipeline_label"
+	scopeName              = "phoenix-vnext/apps/phoenix-otelcol/processor/phoenixkeepprocessor"
+)
+
+type keepProcessor struct {
+	cfg    *Config
+	logger *zap.Logger
+	next   consumer.Metrics
+
+	watcher *controlwatch.Watcher
+
+	mu         sync.Mutex
+	profile    string               // profile and keep list aggregates were
+	list       controlfile.KeepList // built under
+	aggregates *aggregates
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newKeepProcessor(cfg *Config, logger *zap.Logger, next consumer.Metrics) *keepProcessor {
+	return &keepProcessor{cfg: cfg, logger: logger, next: next, aggregates: newAggregates()}
+}
+
+func (p *keepProcessor) start(_ context.Context, _ component.Host) error {
+	p.watcher = controlwatch.Acquire(p.cfg.ControlFile, p.cfg.PollInterval, p.logger)
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run()
+	return nil
+}
+
+func (p *keepProcessor) shutdown(ctx context.Context) error {
+	if p.stop != nil {
+		close(p.stop)
+		select {
+		case <-p.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		p.stop = nil
+	}
+	if p.watcher != nil {
+		p.watcher.Release()
+		p.watcher = nil
+	}
+	return nil
+}
+
+func (p *keepProcessor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.cfg.EmitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.emit(now)
+		}
+	}
+}
+
+// processMetrics applies the keep list of the profile in force to md and
+// merges the series that collapse onto the same identity. Profiles without
+// a keep list pass data through unchanged.
+func (p *keepProcessor) processMetrics(_ context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
+	f := p.watcher.Current()
+	list := f.AttributeKeepLists[f.OptimizationProfile]
+	if len(list.Resource) == 0 && len(list.DataPoint) == 0 {
+		return md, nil
+	}
+	resource, dataPoint := keySet(list.Resource), keySet(list.DataPoint)
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if f.OptimizationProfile != p.profile || !sameKeepList(list, p.list) {
+		// Identities change with the keep list; start over. Every cycle of
+		// the actuator rewrites the file, which alone must not reset the
+		// cumulative sums built so far.
+		p.profile, p.list, p.aggregates = f.OptimizationProfile, list, newAggregates()
+	}
+
+	// Record every input series under the kept series it feeds, then give
+	// the points of gauges and cumulative sums the aggregate of their inputs
+	type pending struct {
+		id string
+		dp pmetric.NumberDataPoint
+	}
+	var points []pending
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		attrs := rm.Resource().Attributes()
+		sourceResource := metricutil.AttributesKey(attrs)
+		if resource != nil {
+			keepKeys(attrs, resource)
+		}
+		keptResource := metricutil.AttributesKey(attrs)
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			scope := sms.At(j).Scope()
+			scopeKey := scope.Name() + "\x01" + scope.Version()
+			ms := sms.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				m := ms.At(k)
+				metric := scopeKey + "\x01" + metricKey(m)
+				isStateful := stateful(m)
+				forEachPoint(m, func(attrs pcommon.Map, dp *pmetric.NumberDataPoint) {
+					source := sourceResource + "\x01" + metric + "\x01" + metricutil.AttributesKey(attrs)
+					if dataPoint != nil {
+						keepKeys(attrs, dataPoint)
+					}
+					id := keptResource + "\x01" + metric + "\x01" + metricutil.AttributesKey(attrs)
+					if dp != nil && isStateful {
+						p.aggregates.record(id, source, *dp, now)
+						points = append(points, pending{id: id, dp: *dp})
+					} else {
+						p.aggregates.note(id, source, now)
+					}
+				})
+			}
+		}
+	}
+	for _, pt := range points {
+		p.aggregates.apply(pt.id, pt.dp)
+	}
+	mergeResources(rms)
+	return md, nil
+}
+
+// emit expires input series no longer reported and sends the number of
+// input series merged into others down the pipeline.
+func (p *keepProcessor) emit(now time.Time) {
+	p.mu.Lock()
+	merged := p.aggregates.expire(now.Add(-p.cfg.ExpireAfter))
+	p.mu.Unlock()
+
+	md := pmetric.NewMetrics()
+	sm := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName(scopeName)
+
+	m := sm.Metrics().AppendEmpty()
+	m.SetName(mergedMetric)
+	m.SetDescription("Input series merged into another series after stripping the attributes not on the keep list.")
+	m.SetUnit("{series}")
+	dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(now))
+	dp.SetIntValue(int64(merged))
+	dp.Attributes().PutStr(pipelineLabelAttribute, p.cfg.PipelineLabel)
+
+	if err := p.next.ConsumeMetrics(context.Background(), md); err != nil {
+		p.logger.Warn("Failed to emit merged series count", zap.Error(err))
+	}
+}
+
+func sameKeepList(a, b controlfile.KeepList) bool {
+	return slices.Equal(a.Resource, b.Resource) && slices.Equal(a.DataPoint, b.DataPoint)
+}
+
+// keySet returns keys as a set, or nil when there are none.
+func keySet(keys []string) map[string]bool {
+	if len(keys) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	return set
+}
+
+func keepKeys(attrs pcommon.Map, keep map[string]bool) {
+	attrs.RemoveIf(func(k string, _ pcommon.Value) bool {
+		return !keep[k]
+	})
+}
+
+// forEachPoint calls fn with the attributes of every data point of m, and
+// the point itself for gauges and sums.
+func forEachPoint(m pmetric.Metric, fn func(attrs pcommon.Map, dp *pmetric.NumberDataPoint)) {
+	var dps pmetric.NumberDataPointSlice
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		dps = m.Gauge().DataPoints()
+	case pmetric.MetricTypeSum:
+		dps = m.Sum().DataPoints()
+	default:
+		metricutil.ForEachDataPointAttributes(m, func(attrs pcommon.Map) {
+			fn(attrs, nil)
+		})
+		return
+	}
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		fn(dp.Attributes(), &dp)
+	}
+}
@@ -0,0 +1,270 @@
+package phoenixkeepprocessor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// writeControlFile writes a balanced control file with the given keep list
+// for the balanced profile.
+func writeControlFile(t *testing.T, path string, version int, resource string) {
+	t.Helper()
+	data := fmt.Sprintf(`schema_version: 2
+optimization_profile: balanced
+config_version: %d
+attribute_keep_lists:
+  balanced: {resource: [%s], data_point: [state]}
+`, version, resource)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForVersion waits until the watcher has applied config_version.
+func waitForVersion(t *testing.T, p *keepProcessor, version int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for p.watcher.Current().ConfigVersion != version {
+		if time.Now().After(deadline) {
+			t.Fatalf("control file version %d was not applied", version)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// proc is one process's points in a batch.
+type proc struct {
+	pid      int64
+	cpu      float64 // cumulative process.cpu.time
+	memory   int64   // gauge process.memory.usage
+	requests int64   // delta process.requests
+}
+
+func batch(procs ...proc) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	for _, pr := range procs {
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().PutStr("host.name", "host-1")
+		rm.Resource().Attributes().PutInt("process.pid", pr.pid)
+		ms := rm.ScopeMetrics().AppendEmpty().Metrics()
+
+		cpu := ms.AppendEmpty()
+		cpu.SetName("process.cpu.time")
+		sum := cpu.SetEmptySum()
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		sum.SetIsMonotonic(true)
+		dp := sum.DataPoints().AppendEmpty()
+		dp.SetDoubleValue(pr.cpu)
+		dp.Attributes().PutStr("state", "user")
+		dp.Attributes().PutInt("thread", pr.pid)
+
+		memory := ms.AppendEmpty()
+		memory.SetName("process.memory.usage")
+		memory.SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(pr.memory)
+
+		requests := ms.AppendEmpty()
+		requests.SetName("process.requests")
+		sum = requests.SetEmptySum()
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		sum.DataPoints().AppendEmpty().SetIntValue(pr.requests)
+	}
+	return md
+}
+
+// values returns the value of every point in md by resource attributes,
+// metric name and point attributes.
+func values(md pmetric.Metrics) map[string]float64 {
+	got := map[string]float64{}
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		resource := fmt.Sprint(rms.At(i).Resource().Attributes().AsRaw())
+		sms := rms.At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			ms := sms.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				m := ms.At(k)
+				var dps pmetric.NumberDataPointSlice
+				if m.Type() == pmetric.MetricTypeGauge {
+					dps = m.Gauge().DataPoints()
+				} else {
+					dps = m.Sum().DataPoints()
+				}
+				for l := 0; l < dps.Len(); l++ {
+					dp := dps.At(l)
+					key := resource + " " + m.Name() + " " + fmt.Sprint(dp.Attributes().AsRaw())
+					if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+						got[key] = float64(dp.IntValue())
+					} else {
+						got[key] = dp.DoubleValue()
+					}
+				}
+			}
+		}
+	}
+	return got
+}
+
+func checkValues(t *testing.T, md pmetric.Metrics, want map[string]float64) {
+	t.Helper()
+	got := values(md)
+	if len(got) != len(want) {
+		t.Errorf("got %d points, want %d: %v", len(got), len(want), got)
+	}
+	for key, v := range want {
+		if got[key] != v {
+			t.Errorf("%s = %g, want %g", key, got[key], v)
+		}
+	}
+}
+
+func lastMerged(t *testing.T, sink *consumertest.MetricsSink) int64 {
+	t.Helper()
+	all := sink.AllMetrics()
+	if len(all) == 0 {
+		t.Fatal("no merged series count emitted")
+	}
+	m := all[len(all)-1].ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	if m.Name() != mergedMetric {
+		t.Fatalf("emitted %s, want %s", m.Name(), mergedMetric)
+	}
+	return m.Gauge().DataPoints().At(0).IntValue()
+}
+
+// The actuator rewrites the control file every cycle; only a change of the
+// keep list in force may restart the cross-batch sums.
+func TestKeepAcrossControlFileRewrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "optimization_mode.yaml")
+	writeControlFile(t, path, 1, "host.name")
+	sink := new(consumertest.MetricsSink)
+	cfg := &Config{ControlFile: path, PollInterval: 5 * time.Millisecond, PipelineLabel: "optimised", ExpireAfter: time.Minute, EmitInterval: time.Hour}
+	p := newKeepProcessor(cfg, zap.NewNop(), sink)
+	if err := p.start(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.shutdown(context.Background()) }()
+	process := func(md pmetric.Metrics) pmetric.Metrics {
+		t.Helper()
+		out, err := p.processMetrics(context.Background(), md)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	const host = "map[host.name:host-1]"
+	out := process(batch(proc{pid: 1, cpu: 10, memory: 100, requests: 1}, proc{pid: 2, cpu: 20, memory: 200, requests: 2}))
+	checkValues(t, out, map[string]float64{
+		host + " process.cpu.time map[state:user]": 30,
+		host + " process.memory.usage map[]":       300,
+		host + " process.requests map[]":           3,
+	})
+	p.emit(time.Now())
+	// Each of the three kept series merges two processes
+	if got := lastMerged(t, sink); got != 3 {
+		t.Errorf("merged_series = %d, want 3", got)
+	}
+
+	// A new cycle of the actuator: same profile and keep list
+	writeControlFile(t, path, 2, "host.name")
+	waitForVersion(t, p, 2)
+	out = process(batch(proc{pid: 1, cpu: 15, memory: 150, requests: 4}))
+	checkValues(t, out, map[string]float64{
+		// Process 2's last values still count; its delta does not carry over
+		host + " process.cpu.time map[state:user]": 35,
+		host + " process.memory.usage map[]":       350,
+		host + " process.requests map[]":           4,
+	})
+	p.emit(time.Now())
+	if got := lastMerged(t, sink); got != 3 {
+		t.Errorf("merged_series after rewrite = %d, want 3", got)
+	}
+
+	// Keeping process.pid changes every identity, so the sums start over
+	writeControlFile(t, path, 3, "host.name, process.pid")
+	waitForVersion(t, p, 3)
+	out = process(batch(proc{pid: 1, cpu: 16, memory: 160, requests: 5}))
+	const pid1 = "map[host.name:host-1 process.pid:1]"
+	checkValues(t, out, map[string]float64{
+		pid1 + " process.cpu.time map[state:user]": 16,
+		pid1 + " process.memory.usage map[]":       160,
+		pid1 + " process.requests map[]":           5,
+	})
+	p.emit(time.Now())
+	if got := lastMerged(t, sink); got != 0 {
+		t.Errorf("merged_series after keep list change = %d, want 0", got)
+	}
+}
+
+func TestKeepWithoutKeepList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "optimization_mode.yaml")
+	if err := os.WriteFile(path, []byte("schema_version: 2\noptimization_profile: aggressive\nconfig_version: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{ControlFile: path, PollInterval: time.Hour, PipelineLabel: "optimised", ExpireAfter: time.Minute, EmitInterval: time.Hour}
+	p := newKeepProcessor(cfg, zap.NewNop(), new(consumertest.MetricsSink))
+	if err := p.start(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.shutdown(context.Background()) }()
+
+	out, err := p.processMetrics(context.Background(), batch(proc{pid: 1, cpu: 10}, proc{pid: 2, cpu: 20}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := out.ResourceMetrics().Len(); n != 2 {
+		t.Errorf("%d resources, want both processes passed through", n)
+	}
+	if _, ok := out.ResourceMetrics().At(0).Resource().Attributes().Get("process.pid"); !ok {
+		t.Error("process.pid stripped without a keep list")
+	}
+}
+
+func TestAggregatesExpire(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	point := func(v int64) pmetric.NumberDataPoint {
+		dp := pmetric.NewNumberDataPoint()
+		dp.SetIntValue(v)
+		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(start.Add(time.Duration(v) * time.Second)))
+		return dp
+	}
+	a := newAggregates()
+	a.record("memory", "pid 1", point(100), start)
+	a.record("memory", "pid 2", point(200), start.Add(time.Minute))
+	a.record("memory", "pid 3", point(300), start.Add(2*time.Minute))
+	a.note("histogram", "pid 1", start)
+	a.note("histogram", "pid 2", start.Add(2*time.Minute))
+	a.note("alone", "pid 4", start.Add(2*time.Minute))
+
+	dp := pmetric.NewNumberDataPoint()
+	a.apply("memory", dp)
+	if dp.IntValue() != 600 || dp.StartTimestamp().AsTime() != start.Add(100*time.Second) {
+		t.Errorf("apply() = %d from %s, want 600 from the earliest start", dp.IntValue(), dp.StartTimestamp().AsTime())
+	}
+
+	tests := []struct {
+		cutoff time.Time
+		want   int
+	}{
+		{start, 3},                      // memory 3 inputs, histogram 2
+		{start.Add(time.Second), 1},     // pid 1 gone from both
+		{start.Add(2 * time.Minute), 0}, // memory has pid 3 only
+		{start.Add(3 * time.Minute), 0}, // nothing left
+	}
+	for _, tt := range tests {
+		if got := a.expire(tt.cutoff); got != tt.want {
+			t.Errorf("expire(%s) = %d, want %d", tt.cutoff.Format(time.TimeOnly), got, tt.want)
+		}
+	}
+	if len(a.series) != 0 {
+		t.Errorf("%d series left after everything expired", len(a.series))
+	}
+}
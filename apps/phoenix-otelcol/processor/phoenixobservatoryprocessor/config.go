@@ -0,0 +1,74 @@
+package phoenixobservatoryprocessor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"phoenix-vnext/apps/phoenix-otelcol/internal/hll"
+)
+
+// Config configures a phoenixobservatory processor.
+type Config struct {
+	// Endpoint is where the phoenix_cardinality_observatory_* metrics are
+	// served for Prometheus to scrape.
+	Endpoint string `mapstructure:"endpoint"`
+	// Precision is the precision of the sketch of distinct values kept for
+	// each (metric, attribute key, process) tracked.
+	Precision int `mapstructure:"precision"`
+	// MaxTracked bounds the number of (metric, attribute key, process)
+	// combinations tracked; further ones are ignored until others expire.
+	MaxTracked int `mapstructure:"max_tracked"`
+	// ExpireAfter is how long a combination that stopped reporting stays
+	// tracked.
+	ExpireAfter time.Duration `mapstructure:"expire_after"`
+	// EvaluationInterval is how often growth rates are computed and alerts
+	// evaluated.
+	EvaluationInterval time.Duration `mapstructure:"evaluation_interval"`
+	// AlertGrowthRate is the rate of new distinct values per second from
+	// which an attribute can raise a cardinality_explosion alert.
+	AlertGrowthRate float64 `mapstructure:"alert_growth_rate"`
+	// AlertGrowthFactor is how many times its usual growth rate an attribute
+	// must also be growing at to raise an alert, so attributes that always
+	// churn (such as pids of short-lived processes) need a sharper rise.
+	AlertGrowthFactor float64 `mapstructure:"alert_growth_factor"`
+	// BaselineWindow is the time constant of the moving average of the
+	// growth rate alerts are compared against.
+	BaselineWindow time.Duration `mapstructure:"baseline_window"`
+	// ReportTop is the number of combinations, by distinct values, whose
+	// counts and growth rates are served; alerts are always served.
+	ReportTop int `mapstructure:"report_top"`
+}
+
+// Validate checks the configuration.
+func (cfg *Config) Validate() error {
+	var errs []error
+	if cfg.Endpoint == "" {
+		errs = append(errs, errors.New("endpoint must be set"))
+	}
+	if cfg.Precision < hll.MinPrecision || cfg.Precision > hll.MaxPrecision {
+		errs = append(errs, fmt.Errorf("precision must be between %d and %d", hll.MinPrecision, hll.MaxPrecision))
+	}
+	if cfg.MaxTracked < 1 {
+		errs = append(errs, errors.New("max_tracked must be at least 1"))
+	}
+	if cfg.ExpireAfter <= 0 {
+		errs = append(errs, errors.New("expire_after must be positive"))
+	}
+	if cfg.EvaluationInterval <= 0 {
+		errs = append(errs, errors.New("evaluation_interval must be positive"))
+	}
+	if cfg.AlertGrowthRate <= 0 {
+		errs = append(errs, errors.New("alert_growth_rate must be positive"))
+	}
+	if cfg.AlertGrowthFactor < 1 {
+		errs = append(errs, errors.New("alert_growth_factor must be at least 1"))
+	}
+	if cfg.BaselineWindow < cfg.EvaluationInterval {
+		errs = append(errs, errors.New("baseline_window must not be shorter than evaluation_interval"))
+	}
+	if cfg.ReportTop < 0 {
+		errs = append(errs, errors.New("report_top must not be negative"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+// Package phoenixobservatoryprocessor is the cardinality observatory. Placed
+// in the intake pipeline, it passes data through unchanged while counting,
+// for every metric, attribute key and process.executable.name, the distinct
+// values the attribute takes. When the rate of new values jumps well above
+// its usual rate it raises a cardinality_explosion alert naming the
+// attribute and process. Counts, growth rates and alerts are served as
+// phoenix_cardinality_observatory_* on the processor's endpoint, which the
+// observer scrapes for the control-loop actuator.
+package phoenixobservatoryprocessor
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	typeStr   = "phoenixobservatory"
+	stability = component.StabilityLevelDevelopment
+)
+
+// NewFactory returns the phoenixobservatory processor factory.
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		processor.WithMetrics(createMetricsProcessor, stability))
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Endpoint:           "0.0.0.0:8891",
+		Precision:          8,
+		MaxTracked:         5000,
+		ExpireAfter:        10 * time.Minute,
+		EvaluationInterval: 15 * time.Second,
+		AlertGrowthRate:    5,
+		AlertGrowthFactor:  3,
+		BaselineWindow:     10 * time.Minute,
+		ReportTop:          50,
+	}
+}
+
+func createMetricsProcessor(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Metrics) (processor.Metrics, error) {
+	p := newObservatoryProcessor(cfg.(*Config), set.Logger)
+	return processorhelper.NewMetricsProcessor(ctx, set, cfg, next, p.processMetrics,
+		processorhelper.WithStart(p.start),
+		processorhelper.WithShutdown(p.shutdown),
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: false}))
+}
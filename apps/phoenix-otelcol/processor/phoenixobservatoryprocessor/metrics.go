@@ -0,0 +1,112 @@
+package phoenixobservatoryprocessor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// alertType is the alert_type of the alerts the observatory raises.
+const alertType = "cardinality_explosion"
+
+var (
+	keyLabels = []string{"metric_name", "attribute_key", "process_executable_name"}
+
+	distinctValuesDesc = prometheus.NewDesc("phoenix_cardinality_observatory_distinct_values",
+		"Distinct values seen for the attribute of the metric and process since it was first seen (top report_top).",
+		keyLabels, nil)
+	growthRateDesc = prometheus.NewDesc("phoenix_cardinality_observatory_growth_rate",
+		"New distinct values per second of the attribute over the last evaluation interval (top report_top).",
+		keyLabels, nil)
+	alertDesc = prometheus.NewDesc("phoenix_cardinality_observatory_alert",
+		"New distinct values per second of an attribute raising an alert; present only while the alert is active.",
+		append([]string{"alert_type"}, keyLabels...), nil)
+	alertCountDesc = prometheus.NewDesc("phoenix_cardinality_observatory_explosion_alert_count",
+		"Active cardinality_explosion alerts.",
+		nil, nil)
+	riskProcessesDesc = prometheus.NewDesc("phoenix_cardinality_observatory_risk_processes",
+		"Distinct process.executable.name values with an active alert.",
+		[]string{"alert_type"}, nil)
+	trackedDesc = prometheus.NewDesc("phoenix_cardinality_observatory_tracked_keys",
+		"(metric, attribute, process) combinations tracked.",
+		nil, nil)
+	untrackedDesc = prometheus.NewDesc("phoenix_cardinality_observatory_untracked_keys",
+		"Combinations ignored over the last evaluation interval because max_tracked was reached.",
+		nil, nil)
+)
+
+// snapshot is the outcome of one evaluation.
+type snapshot struct {
+	reports       []report
+	alerts        []report
+	riskProcesses int
+	tracked       int
+	untracked     int
+}
+
+type report struct {
+	trackKey
+	distinct float64
+	rate     float64
+}
+
+// collector serves the latest snapshot of an observatory.
+type collector struct {
+	p *observatoryProcessor
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{distinctValuesDesc, growthRateDesc, alertDesc, alertCountDesc, riskProcessesDesc, trackedDesc, untrackedDesc} {
+		ch <- d
+	}
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.p.current()
+	for _, r := range s.reports {
+		ch <- prometheus.MustNewConstMetric(distinctValuesDesc, prometheus.GaugeValue, r.distinct, r.metric, r.attribute, r.process)
+		ch <- prometheus.MustNewConstMetric(growthRateDesc, prometheus.GaugeValue, r.rate, r.metric, r.attribute, r.process)
+	}
+	for _, r := range s.alerts {
+		ch <- prometheus.MustNewConstMetric(alertDesc, prometheus.GaugeValue, r.rate, alertType, r.metric, r.attribute, r.process)
+	}
+	ch <- prometheus.MustNewConstMetric(alertCountDesc, prometheus.GaugeValue, float64(len(s.alerts)))
+	ch <- prometheus.MustNewConstMetric(riskProcessesDesc, prometheus.GaugeValue, float64(s.riskProcesses), alertType)
+	ch <- prometheus.MustNewConstMetric(trackedDesc, prometheus.GaugeValue, float64(s.tracked))
+	ch <- prometheus.MustNewConstMetric(untrackedDesc, prometheus.GaugeValue, float64(s.untracked))
+}
+
+// server serves an observatory's metrics on its endpoint.
+type server struct {
+	http *http.Server
+}
+
+func startServer(endpoint string, p *observatoryProcessor, logger *zap.Logger) (*server, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collector{p: p}); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	s := &server{http: &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}}
+	go func() {
+		if err := s.http.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Cardinality observatory endpoint stopped", zap.String("endpoint", endpoint), zap.Error(err))
+		}
+	}()
+	return s, nil
+}
+
+func (s *server) shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
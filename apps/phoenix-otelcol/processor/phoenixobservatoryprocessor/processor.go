@@ -0,0 +1,229 @@
+package phoenixobservatoryprocessor
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"phoenix-vnext/apps/phoenix-otelcol/internal/hll"
+	"phoenix-vnext/apps/phoenix-otelcol/internal/metricutil"
+)
+
+// processExecutableName is the resource attribute attributes are blamed on.
+const processExecutableName = "process.executable.name"
+
+// trackKey identifies one attribute of one metric of one process.
+type trackKey struct {
+	metric, attribute, process string
+}
+
+// tracked is the state of one trackKey.
+type tracked struct {
+	values *hll.Sketch
+	seen   time.Time
+
+	// Set by evaluate
+	evaluated bool
+	distinct  float64
+	rate      float64
+	baseline  float64
+	alerting  bool
+}
+
+type observatoryProcessor struct {
+	cfg    *Config
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	tracked   map[trackKey]*tracked
+	untracked int // combinations ignored since the last evaluation
+	snapshot  *snapshot
+
+	server *server
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func newObservatoryProcessor(cfg *Config, logger *zap.Logger) *observatoryProcessor {
+	return &observatoryProcessor{
+		cfg:      cfg,
+		logger:   logger,
+		tracked:  map[trackKey]*tracked{},
+		snapshot: &snapshot{},
+	}
+}
+
+func (p *observatoryProcessor) start(_ context.Context, _ component.Host) error {
+	s, err := startServer(p.cfg.Endpoint, p, p.logger)
+	if err != nil {
+		return err
+	}
+	p.server = s
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run()
+	return nil
+}
+
+func (p *observatoryProcessor) shutdown(ctx context.Context) error {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+		p.stop = nil
+	}
+	if p.server != nil {
+		err := p.server.shutdown(ctx)
+		p.server = nil
+		return err
+	}
+	return nil
+}
+
+func (p *observatoryProcessor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.cfg.EvaluationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.evaluate(now)
+		}
+	}
+}
+
+// processMetrics records the attribute values of md and passes it on
+// unchanged.
+func (p *observatoryProcessor) processMetrics(_ context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		resource := rm.Resource().Attributes()
+		var process string
+		if v, ok := resource.Get(processExecutableName); ok {
+			process = v.AsString()
+		}
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			ms := sms.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				m := ms.At(k)
+				// Resource attributes are the same for every point of m
+				p.record(m.Name(), process, resource, now)
+				metricutil.ForEachDataPointAttributes(m, func(attrs pcommon.Map) {
+					p.record(m.Name(), process, attrs, now)
+				})
+			}
+		}
+	}
+	return md, nil
+}
+
+func (p *observatoryProcessor) record(metric, process string, attrs pcommon.Map, now time.Time) {
+	attrs.Range(func(k string, v pcommon.Value) bool {
+		key := trackKey{metric: metric, attribute: k, process: process}
+		t, ok := p.tracked[key]
+		if !ok {
+			if len(p.tracked) >= p.cfg.MaxTracked {
+				p.untracked++
+				return true
+			}
+			t = &tracked{values: hll.New(uint8(p.cfg.Precision))}
+			p.tracked[key] = t
+		}
+		t.values.Insert(xxhash.Sum64String(v.AsString()))
+		t.seen = now
+		return true
+	})
+}
+
+// evaluate updates growth rates, baselines and alerts, expires combinations
+// no longer reported and publishes a new snapshot.
+func (p *observatoryProcessor) evaluate(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	interval := p.cfg.EvaluationInterval.Seconds()
+	alpha := 1 - math.Exp(-interval/p.cfg.BaselineWindow.Seconds())
+	s := &snapshot{tracked: len(p.tracked), untracked: p.untracked}
+	p.untracked = 0
+	processes := map[string]bool{}
+	for key, t := range p.tracked {
+		if now.Sub(t.seen) > p.cfg.ExpireAfter {
+			if t.alerting {
+				p.logger.Info("Cardinality explosion cleared", keyFields(key)...)
+			}
+			delete(p.tracked, key)
+			continue
+		}
+
+		distinct := t.values.Estimate()
+		if !t.evaluated {
+			// Every value is new the first time round; start the baseline
+			// from the next interval
+			t.evaluated, t.distinct = true, distinct
+			continue
+		}
+		t.rate = max(distinct-t.distinct, 0) / interval
+		t.distinct = distinct
+
+		alerting := t.rate >= p.cfg.AlertGrowthRate && t.rate >= p.cfg.AlertGrowthFactor*t.baseline
+		switch {
+		case alerting && !t.alerting:
+			p.logger.Warn("Cardinality explosion detected", append(keyFields(key),
+				zap.Float64("new_values_per_second", t.rate),
+				zap.Float64("baseline_per_second", t.baseline),
+				zap.Float64("distinct_values", distinct))...)
+		case !alerting && t.alerting:
+			p.logger.Info("Cardinality explosion cleared", keyFields(key)...)
+		}
+		t.alerting = alerting
+		// The baseline learns only from normal intervals, so a sustained
+		// explosion keeps alerting
+		if !alerting {
+			t.baseline += alpha * (t.rate - t.baseline)
+		}
+
+		r := report{trackKey: key, distinct: distinct, rate: t.rate}
+		s.reports = append(s.reports, r)
+		if alerting {
+			s.alerts = append(s.alerts, r)
+			processes[key.process] = true
+		}
+	}
+	s.riskProcesses = len(processes)
+
+	sort.Slice(s.reports, func(i, j int) bool { return s.reports[i].distinct > s.reports[j].distinct })
+	if len(s.reports) > p.cfg.ReportTop {
+		s.reports = s.reports[:p.cfg.ReportTop]
+	}
+	p.snapshot = s
+}
+
+// current returns the latest snapshot.
+func (p *observatoryProcessor) current() *snapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.snapshot
+}
+
+func keyFields(key trackKey) []zap.Field {
+	return []zap.Field{
+		zap.String("metric", key.metric),
+		zap.String("attribute", key.attribute),
+		zap.String("process", key.process),
+	}
+}
@@ -0,0 +1,197 @@
+package phoenixobservatoryprocessor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.uber.org/zap"
+)
+
+var t0 = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// testConfig evaluates every 10s, alerting from 1 new value per second at
+// three times the baseline, whose moving average moves about 10% of the way
+// each interval.
+func testConfig() *Config {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Precision = 14
+	cfg.EvaluationInterval = 10 * time.Second
+	cfg.AlertGrowthRate = 1
+	cfg.AlertGrowthFactor = 3
+	cfg.BaselineWindow = 100 * time.Second
+	cfg.ExpireAfter = time.Minute
+	return cfg
+}
+
+// feeder records new distinct values of attributes of a metric.
+type feeder struct {
+	p    *observatoryProcessor
+	next int
+}
+
+func (f *feeder) feed(process, attribute string, n int, now time.Time) {
+	for i := 0; i < n; i++ {
+		attrs := pcommon.NewMap()
+		attrs.PutStr(attribute, fmt.Sprint(f.next))
+		f.next++
+		f.p.record("http.server.requests", process, attrs, now)
+	}
+}
+
+// Each step adds that many new values of one attribute over a 10s interval;
+// the first evaluation only starts the count.
+func TestGrowthAlerts(t *testing.T) {
+	repeat := func(n, times int) []int {
+		steps := make([]int, times)
+		for i := range steps {
+			steps[i] = n
+		}
+		return steps
+	}
+	tests := []struct {
+		name  string
+		steps []int
+		want  string // per evaluation after the first: A alerting, . not
+	}{
+		{"explosion and clear", []int{5, 5, 5, 40, 40, 0, 5}, "..AA.."},
+		{"below the absolute rate", []int{5, 9, 9, 9}, "..."},
+		{
+			// A baseline of about 0.76/s after 30 intervals: 1.5/s is under three
+			// times that, 3/s is not
+			name:  "churning attribute needs a sharper rise",
+			steps: append(repeat(8, 31), 15, 30, 30, 8),
+			want:  strings.Repeat(".", 31) + "AA.",
+		},
+		{
+			// The baseline does not learn from alerting intervals, so a
+			// sustained explosion keeps alerting
+			name:  "sustained explosion",
+			steps: append([]int{5}, repeat(20, 40)...),
+			want:  strings.Repeat("A", 40),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newObservatoryProcessor(testConfig(), zap.NewNop())
+			f := &feeder{p: p}
+			var got strings.Builder
+			for i, n := range tt.steps {
+				now := t0.Add(time.Duration(i) * 10 * time.Second)
+				f.feed("api", "request.id", n, now)
+				p.evaluate(now)
+				if i == 0 {
+					continue
+				}
+				s := p.current()
+				switch {
+				case len(s.alerts) == 1 && s.riskProcesses == 1:
+					got.WriteByte('A')
+				case len(s.alerts) == 0 && s.riskProcesses == 0:
+					got.WriteByte('.')
+				default:
+					t.Fatalf("step %d: %d alerts, %d risk processes", i, len(s.alerts), s.riskProcesses)
+				}
+			}
+			if got.String() != tt.want {
+				t.Errorf("alerts %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRiskProcesses(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReportTop = 3
+	p := newObservatoryProcessor(cfg, zap.NewNop())
+	f := &feeder{p: p}
+	keys := []struct {
+		process, attribute string
+		growth             int
+	}{
+		{"api", "request.id", 50},
+		{"api", "session.id", 40},
+		{"db", "query.hash", 30},
+		{"web", "route", 5},
+	}
+	for _, k := range keys {
+		f.feed(k.process, k.attribute, 1, t0)
+	}
+	p.evaluate(t0)
+	now := t0.Add(10 * time.Second)
+	for _, k := range keys {
+		f.feed(k.process, k.attribute, k.growth, now)
+	}
+	p.evaluate(now)
+
+	s := p.current()
+	if len(s.alerts) != 3 || s.riskProcesses != 2 {
+		t.Errorf("%d alerts over %d processes, want 3 over api and db", len(s.alerts), s.riskProcesses)
+	}
+	if len(s.reports) != 3 || s.reports[0].attribute != "request.id" || s.reports[2].attribute != "query.hash" {
+		t.Errorf("reports = %+v, want the top three by distinct values", s.reports)
+	}
+	if s.tracked != 4 {
+		t.Errorf("tracked = %d, want 4", s.tracked)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	p := newObservatoryProcessor(testConfig(), zap.NewNop())
+	f := &feeder{p: p}
+	f.feed("api", "request.id", 1, t0)
+	p.evaluate(t0)
+	f.feed("api", "request.id", 50, t0.Add(10*time.Second))
+	f.feed("db", "query.hash", 1, t0.Add(10*time.Second))
+	p.evaluate(t0.Add(10 * time.Second))
+	if s := p.current(); s.riskProcesses != 1 {
+		t.Fatalf("risk processes = %d, want 1", s.riskProcesses)
+	}
+
+	// Neither is reported again; a minute after its last value api's alert
+	// clears with it
+	f.feed("db", "query.hash", 1, t0.Add(30*time.Second))
+	p.evaluate(t0.Add(80 * time.Second))
+	s := p.current()
+	if len(s.alerts) != 0 || s.riskProcesses != 0 || s.tracked != 2 {
+		t.Errorf("%d alerts, %d risk processes, %d tracked at evaluation; want the alert cleared", len(s.alerts), s.riskProcesses, s.tracked)
+	}
+	if len(p.tracked) != 1 {
+		t.Errorf("%d combinations kept, want db's only", len(p.tracked))
+	}
+	p.evaluate(t0.Add(100 * time.Second))
+	if len(p.tracked) != 0 {
+		t.Errorf("%d combinations kept after all expired", len(p.tracked))
+	}
+}
+
+func TestMaxTracked(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxTracked = 2
+	p := newObservatoryProcessor(cfg, zap.NewNop())
+	attrs := pcommon.NewMap()
+	attrs.PutStr("a", "1")
+	attrs.PutStr("b", "1")
+	attrs.PutStr("c", "1")
+	p.record("m", "api", attrs, t0)
+	p.record("m", "api", attrs, t0)
+	p.evaluate(t0)
+	if s := p.current(); s.tracked != 2 || s.untracked != 2 {
+		t.Errorf("tracked %d, untracked %d; want 2 and c ignored twice", s.tracked, s.untracked)
+	}
+	p.evaluate(t0.Add(10 * time.Second))
+	if s := p.current(); s.untracked != 0 {
+		t.Errorf("untracked = %d after an interval without data, want 0", s.untracked)
+	}
+
+	// Once the others expire, c is tracked
+	p.evaluate(t0.Add(2 * time.Minute))
+	c := pcommon.NewMap()
+	c.PutStr("c", "1")
+	p.record("m", "api", c, t0.Add(2*time.Minute))
+	if _, ok := p.tracked[trackKey{metric: "m", attribute: "c", process: "api"}]; !ok || len(p.tracked) != 1 {
+		t.Errorf("tracked %v, want c alone", p.tracked)
+	}
+}
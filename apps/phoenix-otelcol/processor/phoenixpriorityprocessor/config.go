@@ -0,0 +1,32 @@
+package phoenixpriorityprocessor
+
+import (
+	"fmt"
+
+	"phoenix-vnext/pkg/priority"
+)
+
+// Config configures a phoenixpriority processor.
+type Config struct {
+	// RulesFile is a priority rule file; empty uses the rules built into
+	// pkg/priority.
+	RulesFile string `mapstructure:"rules_file"`
+	// Strip removes the rules' strip_attributes from resources and data
+	// points of processes whose policy is strip.
+	Strip bool `mapstructure:"strip"`
+}
+
+// Validate checks the configuration.
+func (cfg *Config) Validate() error {
+	if _, err := cfg.rules(); err != nil {
+		return fmt.Errorf("rules_file: %w", err)
+	}
+	return nil
+}
+
+func (cfg *Config) rules() (*priority.Rules, error) {
+	if cfg.RulesFile == "" {
+		return priority.Default(), nil
+	}
+	return priority.Read(cfg.RulesFile)
+}
@@ -0,0 +1,41 @@
+// Package phoenixpriorityprocessor classifies processes with the shared
+// rules of pkg/priority, the same rules the synthetic generator labels its
+// ground truth with. It sets phoenix.priority on every resource carrying
+// process.executable.name and can remove the rules' strip_attributes from
+// processes whose policy is strip.
+package phoenixpriorityprocessor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	typeStr   = "phoenixpriority"
+	stability = component.StabilityLevelDevelopment
+)
+
+// NewFactory returns the phoenixpriority processor factory.
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		processor.WithMetrics(createMetricsProcessor, stability))
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{}
+}
+
+func createMetricsProcessor(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Metrics) (processor.Metrics, error) {
+	p, err := newPriorityProcessor(cfg.(*Config), set.Logger)
+	if err != nil {
+		return nil, err
+	}
+	return processorhelper.NewMetricsProcessor(ctx, set, cfg, next, p.processMetrics,
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}))
+}
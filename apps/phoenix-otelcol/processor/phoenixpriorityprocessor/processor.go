@@ -0,0 +1,106 @@
+package phoenixpriorityprocessor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"phoenix-vnext/apps/phoenix-otelcol/internal/metricutil"
+	"phoenix-vnext/pkg/priority"
+)
+
+const (
+	processExecutableName = "process.executable.name"
+	processOwner          = "process.owner"
+	processCommandLine    = "process.command_line"
+	k8sNamespaceName      = "k8s.namespace.name"
+	priorityAttribute     = "phoenix.priority"
+)
+
+type priorityProcessor struct {
+	cfg    *Config
+	logger *zap.Logger
+	rules  *priority.Rules
+}
+
+func newPriorityProcessor(cfg *Config, logger *zap.Logger) (*priorityProcessor, error) {
+	rules, err := cfg.rules()
+	if err != nil {
+		return nil, err
+	}
+	source := cfg.RulesFile
+	if source == "" {
+		source = "built-in"
+	}
+	logger.Info("Loaded priority rules", zap.String("rules", source), zap.Bool("strip", cfg.Strip))
+	return &priorityProcessor{cfg: cfg, logger: logger, rules: rules}, nil
+}
+
+// processMetrics sets phoenix.priority on every process resource of md and,
+// with strip on, removes the strip_attributes of processes whose policy is
+// strip.
+func (p *priorityProcessor) processMetrics(_ context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		resource := rm.Resource().Attributes()
+		execName, ok := resource.Get(processExecutableName)
+		if !ok {
+			continue
+		}
+		class := p.rules.Classify(priority.Process{
+			ExecName:    execName.AsString(),
+			Owner:       stringAttribute(resource, processOwner),
+			Namespace:   stringAttribute(resource, k8sNamespaceName),
+			CommandLine: stringAttribute(resource, processCommandLine),
+			Attributes:  p.matchedAttributes(resource),
+		})
+		resource.PutStr(priorityAttribute, class.Priority)
+
+		if !p.cfg.Strip || class.Policy != priority.PolicyStrip {
+			continue
+		}
+		strip := p.rules.StripAttributes()
+		deleteKeys(resource, strip)
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			ms := sms.At(j).Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				metricutil.ForEachDataPointAttributes(ms.At(k), func(attrs pcommon.Map) {
+					deleteKeys(attrs, strip)
+				})
+			}
+		}
+	}
+	return md, nil
+}
+
+// matchedAttributes returns the resource attributes the rules match on.
+func (p *priorityProcessor) matchedAttributes(resource pcommon.Map) map[string]string {
+	keys := p.rules.AttributeKeys()
+	if len(keys) == 0 {
+		return nil
+	}
+	attrs := make(map[string]string, len(keys))
+	for _, k := range keys {
+		if v, ok := resource.Get(k); ok {
+			attrs[k] = v.AsString()
+		}
+	}
+	return attrs
+}
+
+func stringAttribute(attrs pcommon.Map, key string) string {
+	if v, ok := attrs.Get(key); ok {
+		return v.AsString()
+	}
+	return ""
+}
+
+func deleteKeys(attrs pcommon.Map, keys []string) {
+	for _, k := range keys {
+		attrs.Remove(k)
+	}
+}
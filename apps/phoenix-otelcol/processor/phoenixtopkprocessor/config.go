@@ -0,0 +1,72 @@
+package phoenixtopkprocessor
+
+import (
+	"errors"
+	"time"
+)
+
+// Config configures a phoenixtopk processor.
+type Config struct {
+	// K is the number of processes kept per host when the control file does
+	// not set target_k_value_for_experimental_topk.
+	K int `mapstructure:"k"`
+	// ControlFile, when set, is read for
+	// advanced_phoenix_parameters.target_k_value_for_experimental_topk,
+	// which overrides K.
+	ControlFile string `mapstructure:"control_file"`
+	// PollInterval is how often the control file is checked for changes.
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// RankMetric is the metric processes are ranked by: the rate of a sum
+	// (process.cpu.time by default) or the value of a gauge.
+	RankMetric string `mapstructure:"rank_metric"`
+	// HostAttribute is the resource attribute processes are grouped by.
+	HostAttribute string `mapstructure:"host_attribute"`
+	// Capacity is the number of processes the SpaceSaving summary of each
+	// host monitors. Zero means four times K; it is never below K.
+	Capacity int `mapstructure:"capacity"`
+	// HalfLife is how quickly past usage stops counting towards a process's
+	// rank.
+	HalfLife time.Duration `mapstructure:"half_life"`
+	// RollupAttributes are the resource attributes, besides HostAttribute,
+	// copied onto each host's rollup resource. They should be host-level so
+	// the rollup keeps one identity.
+	RollupAttributes []string `mapstructure:"rollup_attributes"`
+	// RollupExpireAfter is how long a process stays in its host's rollup
+	// after its last batch.
+	RollupExpireAfter time.Duration `mapstructure:"rollup_expire_after"`
+}
+
+// Validate checks the configuration.
+func (cfg *Config) Validate() error {
+	var errs []error
+	if cfg.K < 1 {
+		errs = append(errs, errors.New("k must be at least 1"))
+	}
+	if cfg.ControlFile != "" && cfg.PollInterval <= 0 {
+		errs = append(errs, errors.New("poll_interval must be positive"))
+	}
+	if cfg.RankMetric == "" {
+		errs = append(errs, errors.New("rank_metric must be set"))
+	}
+	if cfg.HostAttribute == "" {
+		errs = append(errs, errors.New("host_attribute must be set"))
+	}
+	if cfg.Capacity < 0 {
+		errs = append(errs, errors.New("capacity must not be negative"))
+	}
+	if cfg.HalfLife <= 0 {
+		errs = append(errs, errors.New("half_life must be positive"))
+	}
+	if cfg.RollupExpireAfter <= 0 {
+		errs = append(errs, errors.New("rollup_expire_after must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// capacityFor returns the summary capacity used with k.
+func (cfg *Config) capacityFor(k int) int {
+	if cfg.Capacity == 0 {
+		return 4 * k
+	}
+	return max(cfg.Capacity, k)
+}
@@ -0,0 +1,50 @@
+// Package phoenixtopkprocessor keeps the K heaviest processes of each host
+// and folds the others into one rollup resource per host. Processes are
+// ranked with a weighted SpaceSaving summary of a rank metric (the
+// process.cpu.time rate by default), so memory stays bounded however many
+// processes a host runs. K can be set at runtime through
+// target_k_value_for_experimental_topk in the control file.
+package phoenixtopkprocessor
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	typeStr   = "phoenixtopk"
+	stability = component.StabilityLevelDevelopment
+)
+
+// NewFactory returns the phoenixtopk processor factory.
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		component.MustNewType(typeStr),
+		createDefaultConfig,
+		processor.WithMetrics(createMetricsProcessor, stability))
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		K:                 20,
+		PollInterval:      5 * time.Second,
+		RankMetric:        "process.cpu.time",
+		HostAttribute:     "host.name",
+		HalfLife:          5 * time.Minute,
+		RollupAttributes:  []string{"os.type", "benchmark.id", "deployment.environment"},
+		RollupExpireAfter: time.Minute,
+	}
+}
+
+func createMetricsProcessor(ctx context.Context, set processor.Settings, cfg component.Config, next consumer.Metrics) (processor.Metrics, error) {
+	p := newTopKProcessor(cfg.(*Config), set.Logger)
+	return processorhelper.NewMetricsProcessor(ctx, set, cfg, next, p.processMetrics,
+		processorhelper.WithStart(p.start),
+		processorhelper.WithShutdown(p.shutdown),
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}))
+}
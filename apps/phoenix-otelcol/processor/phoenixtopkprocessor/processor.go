@@ -0,0 +1,260 @@
+package phoenixtopkprocessor
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"phoenix-vnext/apps/phoenix-otelcol/internal/controlwatch"
+	"phoenix-vnext/apps/phoenix-otelcol/internal/metricutil"
+	"phoenix-vnext/apps/phoenix-otelcol/internal/rollup"
+)
+
+// processExecutableName marks process resources; others pass through.
+const processExecutableName = "process.executable.name"
+
+// expireInterval is how often state of processes and hosts no longer
+// reporting is dropped.
+const expireInterval = time.Minute
+
+type topKProcessor struct {
+	cfg     *Config
+	logger  *zap.Logger
+	watcher *controlwatch.Watcher
+
+	mu        sync.Mutex
+	hosts     map[string]*hostState
+	rollups   *rollup.State
+	k         int
+	expiredAt time.Time
+}
+
+// hostState is the ranking of one host's processes.
+type hostState struct {
+	summary   *spaceSaving
+	decayedAt time.Time
+	processes map[string]*processState
+}
+
+// processState remembers a process's last rank metric sample, to turn
+// cumulative sums into rates.
+type processState struct {
+	value float64
+	ts    pcommon.Timestamp
+	seen  time.Time
+}
+
+func newTopKProcessor(cfg *Config, logger *zap.Logger) *topKProcessor {
+	return &topKProcessor{
+		cfg:     cfg,
+		logger:  logger,
+		hosts:   map[string]*hostState{},
+		rollups: rollup.NewState(append([]string{cfg.HostAttribute}, cfg.RollupAttributes...), cfg.RollupExpireAfter),
+		k:       cfg.K,
+	}
+}
+
+func (p *topKProcessor) start(_ context.Context, _ component.Host) error {
+	if p.cfg.ControlFile != "" {
+		p.watcher = controlwatch.Acquire(p.cfg.ControlFile, p.cfg.PollInterval, p.logger)
+	}
+	return nil
+}
+
+func (p *topKProcessor) shutdown(context.Context) error {
+	if p.watcher != nil {
+		p.watcher.Release()
+		p.watcher = nil
+	}
+	return nil
+}
+
+// currentK returns K from the control file if it sets one, else from the
+// configuration.
+func (p *topKProcessor) currentK() int {
+	if p.watcher != nil {
+		if a := p.watcher.Current().AdvancedParameters; a != nil && a.TargetKValueForExperimentalTopK >= 1 {
+			return a.TargetKValueForExperimentalTopK
+		}
+	}
+	return p.cfg.K
+}
+
+func (p *topKProcessor) processMetrics(_ context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k := p.currentK(); k != p.k {
+		p.logger.Info("TopK changed", zap.Int("previous", p.k), zap.Int("k", k))
+		p.k = k
+		for _, h := range p.hosts {
+			h.summary.resize(p.cfg.capacityFor(k))
+		}
+	}
+
+	// Feed every process into its host's summary before ranking, so a batch
+	// is ranked on its own samples too
+	type entry struct{ host, key string }
+	rms := md.ResourceMetrics()
+	entries := make([]entry, rms.Len())
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		attrs := rm.Resource().Attributes()
+		if _, ok := attrs.Get(processExecutableName); !ok {
+			continue
+		}
+		var host string
+		if v, ok := attrs.Get(p.cfg.HostAttribute); ok {
+			host = v.AsString()
+		}
+		key := metricutil.AttributesKey(attrs)
+		h := p.host(host, now)
+		h.summary.add(key, p.weight(h, key, rm, now))
+		entries[i] = entry{host: host, key: key}
+	}
+
+	tops := map[string]map[string]bool{}
+	i := 0
+	rms.RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		e := entries[i]
+		i++
+		if e.key == "" {
+			return false
+		}
+		top, ok := tops[e.host]
+		if !ok {
+			top = p.hosts[e.host].summary.top(p.k)
+			tops[e.host] = top
+		}
+		if top[e.key] {
+			p.rollups.Remove(e.host, e.key)
+			return false
+		}
+		p.rollups.Add(e.host, e.key, rm, now)
+		return true
+	})
+	p.rollups.MoveTo(rms, now)
+
+	if now.Sub(p.expiredAt) >= expireInterval {
+		p.expire(now)
+		p.expiredAt = now
+	}
+	return md, nil
+}
+
+// host returns the state of host, decayed to now.
+func (p *topKProcessor) host(name string, now time.Time) *hostState {
+	h, ok := p.hosts[name]
+	if !ok {
+		h = &hostState{
+			summary:   newSpaceSaving(p.cfg.capacityFor(p.k)),
+			decayedAt: now,
+			processes: map[string]*processState{},
+		}
+		p.hosts[name] = h
+		return h
+	}
+	if elapsed := now.Sub(h.decayedAt); elapsed > 0 {
+		h.summary.decay(math.Exp2(-elapsed.Seconds() / p.cfg.HalfLife.Seconds()))
+		h.decayedAt = now
+	}
+	return h
+}
+
+// weight returns the rank metric of a process: the per-second rate of a sum
+// or the value of a gauge. Processes without the metric weigh zero.
+func (p *topKProcessor) weight(h *hostState, key string, rm pmetric.ResourceMetrics, now time.Time) float64 {
+	var (
+		total       float64
+		start, ts   pcommon.Timestamp
+		found, sum  bool
+		temporality pmetric.AggregationTemporality
+	)
+	sms := rm.ScopeMetrics()
+	for i := 0; i < sms.Len(); i++ {
+		ms := sms.At(i).Metrics()
+		for j := 0; j < ms.Len(); j++ {
+			m := ms.At(j)
+			if m.Name() != p.cfg.RankMetric {
+				continue
+			}
+			var dps pmetric.NumberDataPointSlice
+			switch m.Type() {
+			case pmetric.MetricTypeSum:
+				dps, sum, temporality = m.Sum().DataPoints(), true, m.Sum().AggregationTemporality()
+			case pmetric.MetricTypeGauge:
+				dps = m.Gauge().DataPoints()
+			default:
+				continue
+			}
+			// Processes report one point per state (user, system, ...)
+			for k := 0; k < dps.Len(); k++ {
+				dp := dps.At(k)
+				total += metricutil.NumberValue(dp)
+				ts = max(ts, dp.Timestamp())
+				if start == 0 || (dp.StartTimestamp() != 0 && dp.StartTimestamp() < start) {
+					start = dp.StartTimestamp()
+				}
+				found = true
+			}
+		}
+	}
+	if !found {
+		return 0
+	}
+	if !sum {
+		return total
+	}
+	if temporality == pmetric.AggregationTemporalityDelta {
+		return rate(total, start, ts)
+	}
+
+	ps, ok := h.processes[key]
+	if !ok {
+		ps = &processState{}
+		h.processes[key] = ps
+	}
+	var w float64
+	switch {
+	case ok && ts > ps.ts:
+		delta := total - ps.value
+		if delta < 0 {
+			delta = total // counter reset
+		}
+		w = rate(delta, ps.ts, ts)
+	case !ok:
+		// First sample: average rate since the counter started
+		w = rate(total, start, ts)
+	}
+	ps.value, ps.ts, ps.seen = total, ts, now
+	return w
+}
+
+func rate(v float64, from, to pcommon.Timestamp) float64 {
+	if from == 0 || to <= from {
+		return 0
+	}
+	return v / to.AsTime().Sub(from.AsTime()).Seconds()
+}
+
+// expire drops processes that stopped reporting and hosts left without any.
+func (p *topKProcessor) expire(now time.Time) {
+	stale := 2 * p.cfg.HalfLife
+	for name, h := range p.hosts {
+		for key, ps := range h.processes {
+			if now.Sub(ps.seen) > stale {
+				delete(h.processes, key)
+			}
+		}
+		if len(h.processes) == 0 && now.Sub(h.decayedAt) > stale {
+			delete(p.hosts, name)
+		}
+	}
+}
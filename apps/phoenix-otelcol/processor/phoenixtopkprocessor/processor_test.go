@@ -0,0 +1,207 @@
+package phoenixtopkprocessor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"phoenix-vnext/apps/phoenix-otelcol/internal/rollup"
+)
+
+func testConfig() *Config {
+	cfg := createDefaultConfig().(*Config)
+	cfg.K = 3
+	cfg.PollInterval = time.Hour
+	return cfg
+}
+
+// cpuResource is a process resource with a process.cpu.time sum of value
+// over [start, ts].
+func cpuResource(host string, pid int, temporality pmetric.AggregationTemporality, value float64, start, ts time.Time) pmetric.ResourceMetrics {
+	rm := pmetric.NewResourceMetrics()
+	attrs := rm.Resource().Attributes()
+	attrs.PutStr("host.name", host)
+	attrs.PutInt("process.pid", int64(pid))
+	attrs.PutStr(processExecutableName, fmt.Sprintf("worker_%d", pid))
+	m := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("process.cpu.time")
+	sum := m.SetEmptySum()
+	sum.SetAggregationTemporality(temporality)
+	sum.SetIsMonotonic(true)
+	// Split over two states, which the rank adds up
+	for _, state := range []string{"user", "system"} {
+		dp := sum.DataPoints().AppendEmpty()
+		dp.Attributes().PutStr("state", state)
+		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+		dp.SetDoubleValue(value / 2)
+	}
+	return rm
+}
+
+func TestWeightRate(t *testing.T) {
+	t0 := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	p := newTopKProcessor(testConfig(), zap.NewNop())
+	h := p.host("host-1", t0)
+	key := "worker_1"
+
+	tests := []struct {
+		name  string
+		value float64
+		start time.Time
+		ts    time.Time
+		want  float64
+	}{
+		{"first sample averages since the start", 10, t0, at(10), 1},
+		{"cumulative delta", 30, t0, at(20), 2},
+		{"same timestamp", 40, t0, at(20), 0},
+		{"counter reset", 5, at(25), at(30), 0.5},
+		{"after the reset", 25, at(25), at(40), 2},
+	}
+	for _, tt := range tests {
+		rm := cpuResource("host-1", 1, pmetric.AggregationTemporalityCumulative, tt.value, tt.start, tt.ts)
+		if got := p.weight(h, key, rm, tt.ts); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: weight = %g, want %g", tt.name, got, tt.want)
+		}
+	}
+
+	delta := cpuResource("host-1", 2, pmetric.AggregationTemporalityDelta, 6, at(0), at(3))
+	if got := p.weight(h, "worker_2", delta, at(3)); got != 2 {
+		t.Errorf("delta weight = %g, want 2", got)
+	}
+	if _, ok := h.processes["worker_2"]; ok {
+		t.Error("delta sums kept cumulative state")
+	}
+	if got := p.weight(h, "worker_3", pmetric.NewResourceMetrics(), at(3)); got != 0 {
+		t.Errorf("weight without the rank metric = %g, want 0", got)
+	}
+}
+
+// batch returns one host's processes 1..n; process i uses i CPU seconds per
+// second.
+func batch(n int, now time.Time) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	for pid := 1; pid <= n; pid++ {
+		cpuResource("host-1", pid, pmetric.AggregationTemporalityCumulative, float64(10*pid), now.Add(-10*time.Second), now).
+			CopyTo(md.ResourceMetrics().AppendEmpty())
+	}
+	// Resources that are not processes pass through
+	md.ResourceMetrics().AppendEmpty().Resource().Attributes().PutStr("host.name", "host-1")
+	return md
+}
+
+// kept returns the process.executable.name of the resources in md, and the
+// rollup resource if there is one.
+func kept(md pmetric.Metrics) (names []string, rollupRM pmetric.ResourceMetrics, ok bool) {
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		v, has := rms.At(i).Resource().Attributes().Get(processExecutableName)
+		switch {
+		case !has:
+			names = append(names, "-")
+		case v.Str() == rollup.ProcessName:
+			rollupRM, ok = rms.At(i), true
+		default:
+			names = append(names, v.Str())
+		}
+	}
+	return names, rollupRM, ok
+}
+
+func TestTopKFromControlFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "optimization_mode.yaml")
+	err := os.WriteFile(path, []byte(`schema_version: 2
+optimization_profile: aggressive
+config_version: 1
+advanced_phoenix_parameters:
+  control_output: 0.8
+  target_k_value_for_experimental_topk: 2
+  attribute_stripping_intensity_level: high
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		controlFile string
+		want        string
+	}{
+		{"configured K", "", "[worker_4 worker_5 worker_6 -]"},
+		{"K from the control file", path, "[worker_5 worker_6 -]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.ControlFile = tt.controlFile
+			p := newTopKProcessor(cfg, zap.NewNop())
+			if err := p.start(context.Background(), nil); err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = p.shutdown(context.Background()) }()
+
+			out, err := p.processMetrics(context.Background(), batch(6, time.Now()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			names, _, ok := kept(out)
+			if fmt.Sprint(names) != tt.want {
+				t.Errorf("kept %v, want %s", names, tt.want)
+			}
+			if !ok {
+				t.Error("no rollup of the other processes")
+			}
+		})
+	}
+}
+
+func TestTopKRollsUpOthers(t *testing.T) {
+	p := newTopKProcessor(testConfig(), zap.NewNop())
+	now := time.Now()
+	out, err := p.processMetrics(context.Background(), batch(5, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, rm, ok := kept(out)
+	if fmt.Sprint(names) != "[worker_3 worker_4 worker_5 -]" || !ok {
+		t.Fatalf("kept %v (rollup %t), want the top three and a rollup", names, ok)
+	}
+	attrs := rm.Resource().Attributes().AsRaw()
+	if attrs[rollup.CountAttribute] != int64(2) || attrs["host.name"] != "host-1" {
+		t.Errorf("rollup resource = %v, want host-1 with 2 processes", attrs)
+	}
+	ms := rm.ScopeMetrics().At(0).Metrics()
+	if ms.Len() != 1 || ms.At(0).Name() != "process.cpu.time" {
+		t.Fatalf("rollup has %d metrics, want process.cpu.time", ms.Len())
+	}
+	// Workers 1 and 2 counted 10 and 20 seconds, half in each state
+	dps := ms.At(0).Sum().DataPoints()
+	for i := 0; i < dps.Len(); i++ {
+		if v := dps.At(i).DoubleValue(); v != 15 {
+			t.Errorf("rollup process.cpu.time %v = %g, want 15", dps.At(i).Attributes().AsRaw(), v)
+		}
+	}
+
+	// Worker 6 pushes worker 3 out of the top three, into the rollup
+	later := now.Add(10 * time.Second)
+	md := batch(6, later)
+	out, err = p.processMetrics(context.Background(), md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, rm, _ = kept(out)
+	if fmt.Sprint(names) != "[worker_4 worker_5 worker_6 -]" {
+		t.Errorf("kept %v after worker_6 started", names)
+	}
+	if got := rm.Resource().Attributes().AsRaw()[rollup.CountAttribute]; got != int64(3) {
+		t.Errorf("rollup count = %v, want 3", got)
+	}
+}
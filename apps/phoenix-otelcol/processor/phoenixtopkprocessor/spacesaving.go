@@ -0,0 +1,90 @@
+package phoenixtopkprocessor
+
+import "sort"
+
+// spaceSaving is a weighted SpaceSaving summary (Metwally et al.): it
+// tracks at most capacity items and approximates the heaviest ones in a
+// stream with bounded memory. An unmonitored item replaces the lightest
+// monitored one and inherits its count, so counts overestimate by at most
+// the evicted count, recorded as err.
+type spaceSaving struct {
+	capacity int
+	counters map[string]*ssCounter
+}
+
+type ssCounter struct {
+	key   string
+	count float64
+	err   float64
+}
+
+func newSpaceSaving(capacity int) *spaceSaving {
+	return &spaceSaving{capacity: capacity, counters: make(map[string]*ssCounter, capacity)}
+}
+
+// add adds weight w to key. Zero weights only refresh monitored items, so
+// idle processes do not evict busy ones.
+func (s *spaceSaving) add(key string, w float64) {
+	if c, ok := s.counters[key]; ok {
+		c.count += w
+		return
+	}
+	if w <= 0 {
+		return
+	}
+	if len(s.counters) < s.capacity {
+		s.counters[key] = &ssCounter{key: key, count: w}
+		return
+	}
+	min := s.min()
+	delete(s.counters, min.key)
+	s.counters[key] = &ssCounter{key: key, count: min.count + w, err: min.count}
+}
+
+// resize changes the capacity, evicting the lightest items if it shrinks.
+func (s *spaceSaving) resize(capacity int) {
+	s.capacity = capacity
+	for len(s.counters) > capacity {
+		delete(s.counters, s.min().key)
+	}
+}
+
+// decay multiplies every count by f, so older weight counts for less.
+func (s *spaceSaving) decay(f float64) {
+	for _, c := range s.counters {
+		c.count *= f
+		c.err *= f
+	}
+}
+
+// top returns the k monitored items with the highest counts.
+func (s *spaceSaving) top(k int) map[string]bool {
+	cs := make([]*ssCounter, 0, len(s.counters))
+	for _, c := range s.counters {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].count != cs[j].count {
+			return cs[i].count > cs[j].count
+		}
+		return cs[i].key < cs[j].key
+	})
+	if len(cs) > k {
+		cs = cs[:k]
+	}
+	top := make(map[string]bool, len(cs))
+	for _, c := range cs {
+		top[c.key] = true
+	}
+	return top
+}
+
+func (s *spaceSaving) min() *ssCounter {
+	var min *ssCounter
+	for _, c := range s.counters {
+		if min == nil || c.count < min.count {
+			min = c
+		}
+	}
+	return min
+}
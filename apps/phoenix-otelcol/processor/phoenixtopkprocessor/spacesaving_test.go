@@ -0,0 +1,113 @@
+package phoenixtopkprocessor
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func counters(s *spaceSaving) map[string][2]float64 {
+	got := map[string][2]float64{}
+	for k, c := range s.counters {
+		got[k] = [2]float64{c.count, c.err}
+	}
+	return got
+}
+
+func TestSpaceSavingEviction(t *testing.T) {
+	s := newSpaceSaving(2)
+	s.add("a", 5)
+	s.add("b", 3)
+	// An idle unmonitored process does not evict anything
+	s.add("c", 0)
+	s.add("b", 0)
+	if got := fmt.Sprint(counters(s)); got != "map[a:[5 0] b:[3 0]]" {
+		t.Fatalf("counters = %s", got)
+	}
+	// c replaces the lightest item and inherits its count as error
+	s.add("c", 1)
+	if got := fmt.Sprint(counters(s)); got != "map[a:[5 0] c:[4 3]]" {
+		t.Errorf("after eviction counters = %s, want b replaced by c with count 4, error 3", got)
+	}
+	s.resize(1)
+	if got := fmt.Sprint(counters(s)); got != "map[a:[5 0]]" {
+		t.Errorf("after resize counters = %s, want the lightest evicted", got)
+	}
+}
+
+// Every monitored count overestimates the true weight by at most its error,
+// and every item heavier than total/capacity is monitored.
+func TestSpaceSavingErrorBounds(t *testing.T) {
+	const capacity = 8
+	s := newSpaceSaving(capacity)
+	truth := map[string]float64{}
+	var total float64
+	// Weights follow a skewed distribution: item i arrives every i-th step
+	for step := 1; step <= 2000; step++ {
+		for i := 1; i <= 40; i++ {
+			if step%i != 0 {
+				continue
+			}
+			key := fmt.Sprintf("p%02d", i)
+			w := float64(1 + step%3)
+			s.add(key, w)
+			truth[key] += w
+			total += w
+		}
+	}
+	for key, c := range s.counters {
+		if c.count < truth[key] || c.count-c.err > truth[key] {
+			t.Errorf("%s: count %g, error %g, true weight %g", key, c.count, c.err, truth[key])
+		}
+	}
+	for key, w := range truth {
+		if _, ok := s.counters[key]; w > total/capacity && !ok {
+			t.Errorf("%s with weight %g > %g is not monitored", key, w, total/capacity)
+		}
+	}
+	if top := s.top(1); !top["p01"] {
+		t.Errorf("top(1) = %v, want the heaviest item p01", top)
+	}
+}
+
+func TestSpaceSavingDecay(t *testing.T) {
+	s := newSpaceSaving(2)
+	s.add("a", 8)
+	s.add("b", 2)
+	s.add("c", 4) // evicts b: count 6, error 2
+	s.decay(0.5)
+	if got := fmt.Sprint(counters(s)); got != "map[a:[4 0] c:[3 1]]" {
+		t.Errorf("after decay counters = %s", got)
+	}
+	// Recent weight now outranks the decayed history
+	s.add("c", 2)
+	if top := s.top(1); !top["c"] {
+		t.Errorf("top(1) = %v, want c", top)
+	}
+	s.decay(math.Exp2(-10))
+	if c := s.counters["a"].count; c > 0.004 {
+		t.Errorf("a after ten half-lives = %g", c)
+	}
+}
+
+func TestSpaceSavingTop(t *testing.T) {
+	s := newSpaceSaving(10)
+	for key, w := range map[string]float64{"a": 1, "b": 5, "c": 3, "d": 3, "e": 0.5} {
+		s.add(key, w)
+	}
+	tests := []struct {
+		k    int
+		want string
+	}{
+		{0, "map[]"},
+		{1, "map[b:true]"},
+		{2, "map[b:true c:true]"}, // c and d tie; the key breaks it
+		{3, "map[b:true c:true d:true]"},
+		{10, "map[a:true b:true c:true d:true e:true]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(s.top(tt.k)); got != tt.want {
+			t.Errorf("top(%d) = %s, want %s", tt.k, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultExplosionControlAddr keeps the explosion triggers reachable only from
+// inside the generator's container (docker-compose exec), unlike the
+// published admin port.
+const defaultExplosionControlAddr = "127.0.0.1:8898"
+
+// newAdminMux builds the generator's read-only HTTP surface: the Prometheus
+// self-telemetry endpoint, the series manifest and the explosion status.
+func newAdminMux(injector *explosionInjector, manifest *manifestSource) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", selfMetricsHandler())
+	mux.HandleFunc("/manifest", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		// ?series=false returns the totals only
+		withSeries := true
+		if v := r.URL.Query().Get("series"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "invalid series", http.StatusBadRequest)
+				return
+			}
+			withSeries = b
+		}
+		writeJSON(w, manifest.snapshot(withSeries))
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/explosion", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, injector.Status(time.Now()))
+	})
+	return mux
+}
+
+// newExplosionControlMux serves /explosion/start and /explosion/stop. When a
+// token is configured every request must carry it as
+// "Authorization: Bearer <token>".
+func newExplosionControlMux(injector *explosionInjector, token string) *http.ServeMux {
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if token == "" {
+			return true
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or invalid explosion token", http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/explosion/start", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var duration time.Duration
+		if v := r.URL.Query().Get("duration"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				http.Error(w, "invalid duration", http.StatusBadRequest)
+				return
+			}
+			duration = d
+		}
+		var rate float64
+		if v := r.URL.Query().Get("rate"); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f <= 0 {
+				http.Error(w, "invalid rate", http.StatusBadRequest)
+				return
+			}
+			rate = f
+		}
+		injector.Trigger(duration, rate)
+		writeJSON(w, injector.Status(time.Now()))
+	})
+	mux.HandleFunc("/explosion/stop", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		injector.Stop()
+		writeJSON(w, injector.Status(time.Now()))
+	})
+	return mux
+}
+
+// checkExplosionControlAddr requires a token to trigger explosions from
+// beyond loopback, and a listener apart from the admin one.
+func checkExplosionControlAddr(addr, token, adminAddr string) error {
+	if addr == adminAddr {
+		return fmt.Errorf("SYNTHETIC_EXPLOSION_ADDR (%s) must differ from SYNTHETIC_ADMIN_ADDR", addr)
+	}
+	if !isLoopback(addr) && token == "" {
+		return fmt.Errorf("SYNTHETIC_EXPLOSION_TOKEN is required when SYNTHETIC_EXPLOSION_ADDR (%s) is not a loopback address", addr)
+	}
+	return nil
+}
+
+// isLoopback reports whether addr (host:port) only accepts local connections.
+// An empty host listens on every interface.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("WARN (Generator): Failed to write admin response: %v", err)
+	}
+}
+
+// startAdminServer serves handler, named name in logs, on addr until ctx is
+// cancelled.
+func startAdminServer(ctx context.Context, name, addr string, handler http.Handler) {
+	srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		log.Printf("INFO (Generator): %s HTTP server listening on %s", name, addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("ERROR (Generator): %s HTTP server failed: %v", name, err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExplosionControlAddr(t *testing.T) {
+	tests := []struct {
+		addr, token string
+		wantErr     string
+	}{
+		{defaultExplosionControlAddr, "", ""},
+		{"localhost:8898", "", ""},
+		{"[::1]:8898", "", ""},
+		{":8898", "", "SYNTHETIC_EXPLOSION_TOKEN is required"},
+		{"0.0.0.0:8898", "", "SYNTHETIC_EXPLOSION_TOKEN is required"},
+		{"0.0.0.0:8898", "secret", ""},
+		{":8899", "secret", "must differ from SYNTHETIC_ADMIN_ADDR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := checkExplosionControlAddr(tt.addr, tt.token, ":8899")
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("checkExplosionControlAddr() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("checkExplosionControlAddr() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Requests that fail the token check never reach the handler, so a method it
+// rejects tells the two apart.
+func TestExplosionControlToken(t *testing.T) {
+	tests := []struct {
+		name, token, header string
+		want                int
+	}{
+		{"no token configured", "", "", http.StatusMethodNotAllowed},
+		{"missing", "secret", "", http.StatusUnauthorized},
+		{"wrong", "secret", "Bearer guess", http.StatusUnauthorized},
+		{"not bearer", "secret", "secret", http.StatusUnauthorized},
+		{"valid", "secret", "Bearer secret", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newExplosionControlMux(newExplosionInjector(defaultExplosionConfig()), tt.token)
+			for _, path := range []string{"/explosion/start", "/explosion/stop"} {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if tt.header != "" {
+					req.Header.Set("Authorization", tt.header)
+				}
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+				if rec.Code != tt.want {
+					t.Errorf("GET %s = %d, want %d", path, rec.Code, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminMuxCannotTriggerExplosions(t *testing.T) {
+	mux := newAdminMux(newExplosionInjector(defaultExplosionConfig()), &manifestSource{})
+	for _, path := range []string{"/explosion/start", "/explosion/stop"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("POST :8899%s = %d, want 404", path, rec.Code)
+		}
+	}
+}
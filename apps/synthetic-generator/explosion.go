@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// seriesPerProcessResource is the number of series a process emits under one
+// resource (three counters and three gauges).
+const seriesPerProcessResource = 6
+
+// Labels an explosion can make unbounded.
+const (
+	explosionLabelCommandLine = "command_line"
+	explosionLabelPID         = "pid"
+	explosionLabelContainerID = "container_id"
+)
+
+// ExplosionWindow schedules an explosion relative to generator start.
+type ExplosionWindow struct {
+	Start    time.Duration `yaml:"start"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+// ExplosionConfig describes how a cardinality explosion is injected. While an
+// explosion is active, selected processes get a fresh identity every tick so
+// that every tick produces brand new series.
+type ExplosionConfig struct {
+	Schedule []ExplosionWindow `yaml:"schedule"`
+	// ExecNames restricts the exploding processes to these archetypes. Empty
+	// means every process is a candidate.
+	ExecNames          []string `yaml:"exec_names"`
+	NewSeriesPerSecond float64  `yaml:"new_series_per_second"`
+	// Labels made unbounded: command_line, pid and/or container_id.
+	Labels []string `yaml:"labels"`
+	// DefaultDuration applies to signal and HTTP triggers that give none.
+	DefaultDuration time.Duration `yaml:"default_duration"`
+}
+
+func defaultExplosionConfig() ExplosionConfig {
+	return ExplosionConfig{
+		NewSeriesPerSecond: 100,
+		Labels:             []string{explosionLabelCommandLine, explosionLabelPID, explosionLabelContainerID},
+		DefaultDuration:    5 * time.Minute,
+	}
+}
+
+func (c *ExplosionConfig) validate() error {
+	if c.NewSeriesPerSecond <= 0 {
+		return fmt.Errorf("explosion: new_series_per_second must be positive")
+	}
+	if c.DefaultDuration <= 0 {
+		return fmt.Errorf("explosion: default_duration must be positive")
+	}
+	if len(c.Labels) == 0 {
+		return fmt.Errorf("explosion: at least one label is required")
+	}
+	for _, l := range c.Labels {
+		switch l {
+		case explosionLabelCommandLine, explosionLabelPID, explosionLabelContainerID:
+		default:
+			return fmt.Errorf("explosion: unknown label %q", l)
+		}
+	}
+	for _, w := range c.Schedule {
+		if w.Start < 0 || w.Duration <= 0 {
+			return fmt.Errorf("explosion: schedule windows need a non-negative start and a positive duration")
+		}
+	}
+	return nil
+}
+
+// explosionInjector decides when an explosion is active (schedule or manual
+// trigger) and rotates the identity of exploding processes every tick.
+type explosionInjector struct {
+	cfg ExplosionConfig
+
+	mu          sync.Mutex
+	elapsed     time.Duration // Scenario time of the latest tick
+	manualUntil time.Time
+	manualRate  float64
+	wasActive   bool
+	capWarned   bool
+}
+
+func newExplosionInjector(cfg ExplosionConfig) *explosionInjector {
+	return &explosionInjector{cfg: cfg}
+}
+
+// Trigger starts (or extends) a manual explosion. Zero values fall back to the
+// configured default duration and rate.
+func (e *explosionInjector) Trigger(duration time.Duration, rate float64) {
+	if duration <= 0 {
+		duration = e.cfg.DefaultDuration
+	}
+	e.mu.Lock()
+	e.manualUntil = time.Now().Add(duration)
+	e.manualRate = rate
+	e.mu.Unlock()
+	log.Printf("INFO (Generator): Cardinality explosion triggered for %v", duration)
+}
+
+// Stop ends a manual explosion. Scheduled windows are unaffected.
+func (e *explosionInjector) Stop() {
+	e.mu.Lock()
+	e.manualUntil = time.Time{}
+	e.mu.Unlock()
+	log.Println("INFO (Generator): Cardinality explosion stopped")
+}
+
+// explosionStatus is the JSON view of the injector served over HTTP.
+type explosionStatus struct {
+	Active             bool      `json:"active"`
+	Source             string    `json:"source,omitempty"`
+	NewSeriesPerSecond float64   `json:"new_series_per_second,omitempty"`
+	ManualUntil        time.Time `json:"manual_until,omitempty"`
+}
+
+// Status reports whether an explosion is active at now, why, and at what rate.
+// Scheduled windows are evaluated against the scenario time of the latest tick.
+func (e *explosionInjector) Status(now time.Time) explosionStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.statusLocked(now)
+}
+
+func (e *explosionInjector) statusLocked(now time.Time) explosionStatus {
+	if now.Before(e.manualUntil) {
+		rate := e.manualRate
+		if rate <= 0 {
+			rate = e.cfg.NewSeriesPerSecond
+		}
+		return explosionStatus{Active: true, Source: "manual", NewSeriesPerSecond: rate, ManualUntil: e.manualUntil}
+	}
+	for _, w := range e.cfg.Schedule {
+		if e.elapsed >= w.Start && e.elapsed < w.Start+w.Duration {
+			return explosionStatus{Active: true, Source: "schedule", NewSeriesPerSecond: e.cfg.NewSeriesPerSecond}
+		}
+	}
+	return explosionStatus{}
+}
+
+// Apply rotates the identity of enough candidate processes to produce the
+// configured new-series-per-second rate over one tick, or restores exploded
+// processes once the explosion is over. It returns the number of processes
+// given a new identity. elapsed is the scenario time of the current tick.
+// Callers must hold activeProcessesMutex.
+func (e *explosionInjector) Apply(now time.Time, elapsed, tickInterval time.Duration) int {
+	e.mu.Lock()
+	e.elapsed = elapsed
+	status := e.statusLocked(now)
+	wasActive := e.wasActive
+	e.wasActive = status.Active
+	e.mu.Unlock()
+	if status.Active {
+		explosionActive.Set(1)
+	} else {
+		explosionActive.Set(0)
+	}
+
+	if !status.Active {
+		if wasActive {
+			restored := e.restoreAll()
+			log.Printf("INFO (Generator): Cardinality explosion ended, restored %d processes", restored)
+		}
+		return 0
+	}
+	if !wasActive {
+		log.Printf("INFO (Generator): Cardinality explosion active (%s), targeting %.0f new series/s", status.Source, status.NewSeriesPerSecond)
+	}
+
+	candidates := e.candidates()
+	want := int(math.Ceil(status.NewSeriesPerSecond * tickInterval.Seconds() / seriesPerProcessResource))
+	if want > len(candidates) {
+		e.mu.Lock()
+		if !e.capWarned {
+			log.Printf("WARN (Generator): Explosion wants %d new identities per tick but only %d candidate processes exist; capping", want, len(candidates))
+			e.capWarned = true
+		}
+		e.mu.Unlock()
+		want = len(candidates)
+	}
+	for i := 0; i < want; i++ {
+		j := i + rng.Intn(len(candidates)-i)
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+		e.explode(candidates[i])
+	}
+	return want
+}
+
+func (e *explosionInjector) candidates() []*processState {
+	var out []*processState
+	for _, host := range simHosts {
+		for _, proc := range activeProcesses[host.hostname] {
+			if len(e.cfg.ExecNames) == 0 || containsString(e.cfg.ExecNames, proc.archetype.ExecName) {
+				out = append(out, proc)
+			}
+		}
+	}
+	return out
+}
+
+// explode gives proc a never-seen-before identity for the configured labels.
+func (e *explosionInjector) explode(proc *processState) {
+	proc.explosionAttrs = proc.explosionAttrs[:0]
+	for _, label := range e.cfg.Labels {
+		switch label {
+		case explosionLabelCommandLine:
+			proc.explosionAttrs = append(proc.explosionAttrs, semconv.ProcessCommandLineKey.String(
+				fmt.Sprintf("%s --request-id=%016x", proc.cmdLine, rng.Uint64())))
+		case explosionLabelPID:
+			proc.explosionAttrs = append(proc.explosionAttrs, semconv.ProcessPIDKey.Int(100000+rng.Intn(4_000_000)))
+		case explosionLabelContainerID:
+			proc.explosionAttrs = append(proc.explosionAttrs, semconv.ContainerIDKey.String(
+				fmt.Sprintf("cid-eph-%012x", rng.Int63n(1<<48))))
+		}
+	}
+	e.reattach(proc)
+}
+
+func (e *explosionInjector) restoreAll() int {
+	restored := 0
+	for _, host := range simHosts {
+		for _, proc := range activeProcesses[host.hostname] {
+			if len(proc.explosionAttrs) > 0 {
+				proc.explosionAttrs = nil
+				e.reattach(proc)
+				restored++
+			}
+		}
+	}
+	return restored
+}
+
+func (e *explosionInjector) reattach(proc *processState) {
+	proc.otelResource = createOtelResourceForProcess(proc)
+	if err := meterPool.Attach(proc); err != nil {
+		log.Printf("WARN (Generator): Failed to re-create meter for %s (PID %d): %v", proc.execName, proc.pid, err)
+	}
+}
+
+// handleExplosionSignals starts a manual explosion on SIGUSR1 and stops it on
+// SIGUSR2.
+func handleExplosionSignals(ctx context.Context, injector *explosionInjector) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(sigs)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigs:
+			if sig == syscall.SIGUSR1 {
+				injector.Trigger(0, 0)
+			} else {
+				injector.Stop()
+			}
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
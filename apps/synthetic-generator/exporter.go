@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	otlpProtocolGRPC         = "grpc"
+	otlpProtocolHTTPProtobuf = "http/protobuf"
+	otlpMetricsURLPath       = "/v1/metrics"
+)
+
+// otlpExportConfig is the exporter configuration resolved from the standard
+// OTEL_EXPORTER_OTLP_* variables. Every setting can be overridden for metrics
+// only with the matching OTEL_EXPORTER_OTLP_METRICS_* variable.
+type otlpExportConfig struct {
+	protocol    string
+	endpoint    string // host:port
+	urlPath     string // http/protobuf only
+	insecure    bool
+	headers     map[string]string
+	compression string // gzip or none
+	timeout     time.Duration
+	tlsConfig   *tls.Config
+}
+
+// otlpEnv returns the metrics-specific value of an OTEL_EXPORTER_OTLP_* setting,
+// falling back to the generic one. The boolean reports whether the value came
+// from the metrics-specific variable.
+func otlpEnv(name string) (string, bool) {
+	if v := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_METRICS_" + name)); v != "" {
+		return v, true
+	}
+	return strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_" + name)), false
+}
+
+// loadOTLPExportConfig resolves the exporter configuration. It returns nil
+// when no endpoint is configured.
+func loadOTLPExportConfig() (*otlpExportConfig, error) {
+	rawEndpoint, signalSpecific := otlpEnv("ENDPOINT")
+	if rawEndpoint == "" {
+		return nil, nil
+	}
+
+	cfg := &otlpExportConfig{
+		protocol:    otlpProtocolHTTPProtobuf,
+		compression: "gzip", // Cheaper ingest for the benchmark; set "none" to disable
+		timeout:     15 * time.Second,
+	}
+	if v, _ := otlpEnv("PROTOCOL"); v != "" {
+		switch v {
+		case otlpProtocolGRPC, otlpProtocolHTTPProtobuf:
+			cfg.protocol = v
+		default:
+			return nil, fmt.Errorf("unsupported OTLP protocol %q (supported: %s, %s)", v, otlpProtocolGRPC, otlpProtocolHTTPProtobuf)
+		}
+	}
+
+	// Endpoints without a scheme are sent in plaintext unless
+	// OTEL_EXPORTER_OTLP_INSECURE=false says otherwise
+	cfg.insecure = true
+	if v, _ := otlpEnv("INSECURE"); v != "" {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP insecure setting %q: %w", v, err)
+		}
+		cfg.insecure = insecure
+	}
+	endpoint := rawEndpoint
+	if strings.Contains(rawEndpoint, "://") {
+		u, err := url.Parse(rawEndpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP endpoint %q: %w", rawEndpoint, err)
+		}
+		switch u.Scheme {
+		case "http":
+			cfg.insecure = true
+		case "https":
+			cfg.insecure = false
+		default:
+			return nil, fmt.Errorf("invalid OTLP endpoint %q: scheme must be http or https", rawEndpoint)
+		}
+		endpoint = u.Host
+		cfg.urlPath = u.Path
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: missing host", rawEndpoint)
+	}
+	cfg.endpoint = endpoint
+	// The generic endpoint is a base URL; a metrics-specific one is used as is
+	if !signalSpecific || cfg.urlPath == "" {
+		cfg.urlPath = strings.TrimSuffix(cfg.urlPath, "/") + otlpMetricsURLPath
+	}
+
+	if v, _ := otlpEnv("HEADERS"); v != "" {
+		headers, err := parseOTLPHeaders(v)
+		if err != nil {
+			return nil, err
+		}
+		cfg.headers = headers
+	}
+	if v, _ := otlpEnv("COMPRESSION"); v != "" {
+		switch v {
+		case "gzip", "none":
+			cfg.compression = v
+		default:
+			return nil, fmt.Errorf("unsupported OTLP compression %q (supported: gzip, none)", v)
+		}
+	}
+	if v, _ := otlpEnv("TIMEOUT"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms <= 0 {
+			return nil, fmt.Errorf("invalid OTLP timeout %q: expected a positive number of milliseconds", v)
+		}
+		cfg.timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	if !cfg.insecure {
+		tlsConfig, err := loadOTLPTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		cfg.tlsConfig = tlsConfig
+	}
+	return cfg, nil
+}
+
+// parseOTLPHeaders parses the "key1=value1,key2=value2" header list format,
+// with percent-encoded values.
+func parseOTLPHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid OTLP header %q: expected key=value", pair)
+		}
+		value, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP header %q: %w", k, err)
+		}
+		headers[k] = value
+	}
+	return headers, nil
+}
+
+// loadOTLPTLSConfig builds the TLS settings from the CERTIFICATE (CA bundle),
+// CLIENT_CERTIFICATE and CLIENT_KEY variables. Without a CA bundle the system
+// roots are used.
+func loadOTLPTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile, _ := otlpEnv("CERTIFICATE"); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OTLP CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in OTLP CA certificate file %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	certFile, _ := otlpEnv("CLIENT_CERTIFICATE")
+	keyFile, _ := otlpEnv("CLIENT_KEY")
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("OTLP client certificate and client key must be set together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load OTLP client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func (c *otlpExportConfig) String() string {
+	security := "plaintext"
+	if !c.insecure {
+		security = "TLS"
+	}
+	target := c.endpoint
+	if c.protocol == otlpProtocolHTTPProtobuf {
+		target += c.urlPath
+	}
+	headerNames := make([]string, 0, len(c.headers))
+	for k := range c.headers {
+		headerNames = append(headerNames, k)
+	}
+	sort.Strings(headerNames)
+	// Header values are often API keys, so only their names are logged
+	return fmt.Sprintf("%s %s (%s, compression=%s, timeout=%v, headers=%v)",
+		c.protocol, target, security, c.compression, c.timeout, headerNames)
+}
+
+func initMetricsClient() (metricsClient, error) {
+	cfg, err := loadOTLPExportConfig()
+	if err != nil {
+		return nil, fmt.Errorf("synthetic-generator: invalid OTLP exporter configuration: %w", err)
+	}
+	if cfg == nil {
+		log.Println("WARN (Generator): OTEL_EXPORTER_OTLP_ENDPOINT not set. Metrics will not be exported via OTLP from generator.")
+		return nil, nil
+	}
+
+	log.Printf("INFO (Generator): OTLP Exporter targeting: %s", cfg)
+
+	// Add retry configuration with backoff
+	var client metricsClient
+	maxRetries := 5
+
+	for i := 0; i < maxRetries; i++ {
+		client, err = cfg.newMetricsClient()
+		if err == nil {
+			break // Successfully created exporter
+		}
+
+		if i == maxRetries-1 {
+			return nil, fmt.Errorf("synthetic-generator: failed to create OTLP metrics client after %d attempts: %w", maxRetries, err)
+		}
+
+		retryDelay := time.Duration(1<<uint(i)) * time.Second // Exponential backoff
+		log.Printf("WARN (Generator): Failed to create OTLP client (attempt %d/%d): %v. Retrying in %v...",
+			i+1, maxRetries, err, retryDelay)
+		time.Sleep(retryDelay)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var otlpSettings = []string{
+	"ENDPOINT", "PROTOCOL", "INSECURE", "HEADERS", "COMPRESSION", "TIMEOUT",
+	"CERTIFICATE", "CLIENT_CERTIFICATE", "CLIENT_KEY",
+}
+
+func TestLoadOTLPExportConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string // without the OTEL_EXPORTER_OTLP_ prefix
+		want    string            // String() of the config, "" for none
+		wantErr string
+	}{
+		{"no endpoint", map[string]string{"PROTOCOL": "grpc"}, "", ""},
+		{
+			name: "defaults",
+			env:  map[string]string{"ENDPOINT": "collector:4318"},
+			want: "http/protobuf collector:4318/v1/metrics (plaintext, compression=gzip, timeout=15s, headers=[])",
+		},
+		{
+			name: "generic endpoint is a base URL",
+			env:  map[string]string{"ENDPOINT": "https://collector:4318/base/"},
+			want: "http/protobuf collector:4318/base/v1/metrics (TLS, compression=gzip, timeout=15s, headers=[])",
+		},
+		{
+			name: "metrics endpoint is used as is",
+			env:  map[string]string{"ENDPOINT": "http://generic:4318", "METRICS_ENDPOINT": "http://metrics:4318/custom"},
+			want: "http/protobuf metrics:4318/custom (plaintext, compression=gzip, timeout=15s, headers=[])",
+		},
+		{
+			name: "metrics endpoint without a path",
+			env:  map[string]string{"METRICS_ENDPOINT": "http://metrics:4318"},
+			want: "http/protobuf metrics:4318/v1/metrics (plaintext, compression=gzip, timeout=15s, headers=[])",
+		},
+		{
+			name: "metrics settings take precedence",
+			env: map[string]string{
+				"ENDPOINT": "collector:4317",
+				"PROTOCOL": "http/protobuf", "METRICS_PROTOCOL": "grpc",
+				"COMPRESSION": "gzip", "METRICS_COMPRESSION": "none",
+				"TIMEOUT": "1000", "METRICS_TIMEOUT": "500",
+				"HEADERS": "x-generic=1", "METRICS_HEADERS": "x-metrics=2",
+			},
+			want: "grpc collector:4317 (plaintext, compression=none, timeout=500ms, headers=[x-metrics])",
+		},
+		{
+			name: "insecure=false without a scheme",
+			env:  map[string]string{"ENDPOINT": "collector:4317", "PROTOCOL": "grpc", "INSECURE": "false"},
+			want: "grpc collector:4317 (TLS, compression=gzip, timeout=15s, headers=[])",
+		},
+		{"unknown protocol", map[string]string{"ENDPOINT": "c:4318", "METRICS_PROTOCOL": "http/json"}, "", `unsupported OTLP protocol "http/json"`},
+		{"bad scheme", map[string]string{"ENDPOINT": "ftp://c:4318"}, "", "scheme must be http or https"},
+		{"no host", map[string]string{"ENDPOINT": "http:///v1/metrics"}, "", "missing host"},
+		{"bad insecure", map[string]string{"ENDPOINT": "c:4318", "INSECURE": "maybe"}, "", `invalid OTLP insecure setting "maybe"`},
+		{"bad header", map[string]string{"ENDPOINT": "c:4318", "HEADERS": "api-key"}, "", "expected key=value"},
+		{"bad compression", map[string]string{"ENDPOINT": "c:4318", "COMPRESSION": "zstd"}, "", `unsupported OTLP compression "zstd"`},
+		{"timeout not a number", map[string]string{"ENDPOINT": "c:4318", "METRICS_TIMEOUT": "5s"}, "", `invalid OTLP timeout "5s"`},
+		{"zero timeout", map[string]string{"ENDPOINT": "c:4318", "TIMEOUT": "0"}, "", `invalid OTLP timeout "0"`},
+		{"client certificate without key", map[string]string{"ENDPOINT": "https://c:4318", "CLIENT_CERTIFICATE": "client.pem"}, "", "must be set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range otlpSettings {
+				t.Setenv("OTEL_EXPORTER_OTLP_"+name, tt.env[name])
+				t.Setenv("OTEL_EXPORTER_OTLP_METRICS_"+name, tt.env["METRICS_"+name])
+			}
+			cfg, err := loadOTLPExportConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadOTLPExportConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadOTLPExportConfig() error = %v", err)
+			}
+			got := ""
+			if cfg != nil {
+				got = cfg.String()
+			}
+			if got != tt.want {
+				t.Errorf("loadOTLPExportConfig() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOTLPHeaders(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{"", "map[]", ""},
+		{"a=1,b=2", "map[a:1 b:2]", ""},
+		{" api-key = secret%20value , ,x=%3D%2C", "map[api-key:secret value x:=,]", ""},
+		{"a=b=c", "map[a:b=c]", ""},
+		{"plus=a+b", "map[plus:a+b]", ""},
+		{"a=1,novalue", "", `invalid OTLP header "novalue"`},
+		{"=v", "", "expected key=value"},
+		{"a=%zz", "", `invalid OTLP header "a"`},
+	}
+	for _, tt := range tests {
+		got, err := parseOTLPHeaders(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseOTLPHeaders(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(got) != tt.want {
+			t.Errorf("parseOTLPHeaders(%q) = %v (%v), want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -2,28 +2,32 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
 	"os/signal"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/metric"
-	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"phoenix-vnext/pkg/priority"
 )
 
 var (
-	processExecNames = []string{"java_critical_payments", "java_critical_orders", "java_app_frontend", "java_app_backend", "python_api_worker", "python_data_processor", "node_gateway", "nginx_ingress", "postgres_primary", "custom_app_alpha", "custom_app_beta", "sidecar_envoy_proxy", "data_pipeline_job", "cache_redis_server", "log_aggregator_fluentbit", "stress-ng"}
+	// priorityRules classify simulated processes, as the collector's
+	// phoenixpriority processor does
+	priorityRules = priority.Default()
+
 	processOwners    = []string{"payments_user", "orders_user", "app_user", "api_user", "system_user", "data_user", "infra_user", "phoenix_bench_user"}
 	baseHostnames    = []string{"web", "app", "db", "cache", "worker", "stream", "loadgen-k8s"}
 	containerIDs     = make([]string, 150)
@@ -33,8 +37,20 @@ var (
 )
 
 type processState struct {
+	host                    *simHost
 	otelResource            *resource.Resource
 	metricAttrs             attribute.Set
+	meter                   *processMeter
+	archetype               *Archetype
+	tier                    string
+	priority                string // Ground-truth phoenix.priority from the priority rules
+	customAttrs             []attribute.KeyValue
+	explosionAttrs          []attribute.KeyValue // Overrides identity attributes while exploding
+	hostname                string
+	k8sNamespace            string
+	k8sPodName              string
+	k8sNodeName             string
+	containerName           string
 	pid                     int
 	execName                string
 	owner                   string
@@ -49,138 +65,57 @@ type processState struct {
 	isHeavyHitter           bool
 	memLeakRateBytesPerTick float64
 	fdLeakRatePerTick       float64
+	ageTicks                int
+	lifetimeTicks           int // 0 = runs until stopped
+	crashEveryTicks         int // 0 = not crash looping
 }
 
 var (
-	activeProcesses         map[string][]*processState // Keyed by hostname
-	activeProcessesMutex    sync.RWMutex
-	processCPUCounter       metric.Float64Counter
-	processDiskReadCounter  metric.Float64Counter
-	processDiskWriteCounter metric.Float64Counter
+	activeProcesses      map[string][]*processState // Keyed by hostname
+	activeProcessesMutex sync.RWMutex
+	meterPool            *processMeterPool
+	simHosts             []*simHost // Stable host order for deterministic iteration
 )
 
-func initSeedData() {
-	rand.New(rand.NewSource(time.Now().UnixNano()))
+// rng is the generator's single random source. Every random decision that
+// shapes the emitted series draws from it in a fixed order (hosts are walked
+// via simHosts, never by map iteration), so a given SYNTHETIC_SEED reproduces
+// the same series and values.
+var rng *rand.Rand
+
+func initSeedData(seed int64) {
+	rng = rand.New(rand.NewSource(seed))
 	for i := 0; i < len(containerIDs); i++ {
-		containerIDs[i] = fmt.Sprintf("cid-%04d-%x%x", i, rand.Int63n(0xFFFFFF), rand.Int63n(0xFFFFFF))
+		containerIDs[i] = fmt.Sprintf("cid-%04d-%x%x", i, rng.Int63n(0xFFFFFF), rng.Int63n(0xFFFFFF))
 	}
 }
 
-func createOtelResourceForProcess(hostname, k8sNamespace, k8sPodName, k8sNodeName, containerName string) *resource.Resource {
-	return resource.NewWithAttributes(
-		semconv.SchemaURL,
-		semconv.HostNameKey.String(hostname),
-		semconv.ServiceNameKey.String(strings.Split(k8sPodName, "-")[0]), // service from pod prefix
-		semconv.ServiceInstanceIDKey.String(k8sPodName),
+// createOtelResourceForProcess builds the resource a process is exported under.
+// Process identity lives on the resource (as with the hostmetrics process
+// scraper) so the collector's resource_attributes filters can match on it.
+func createOtelResourceForProcess(p *processState) *resource.Resource {
+	attrs := []attribute.KeyValue{
+		semconv.HostNameKey.String(p.hostname),
+		semconv.ServiceNameKey.String(strings.Split(p.k8sPodName, "-")[0]), // service from pod prefix
+		semconv.ServiceInstanceIDKey.String(p.k8sPodName),
 		attribute.String("instrumentation.provider", "synthetic-generator-v3-gu"),
 		attribute.String("benchmark.id", os.Getenv("BENCHMARK_ID")),
 		attribute.String("deployment.environment", os.Getenv("DEPLOYMENT_ENV")),
-		semconv.K8SNamespaceNameKey.String(k8sNamespace),
-		semconv.K8SPodNameKey.String(k8sPodName),
-		semconv.K8SNodeNameKey.String(k8sNodeName),
-		semconv.K8SContainerNameKey.String(containerName),
-	)
-}
-
-func initMeterProvider(ctx context.Context) (*sdkmetric.MeterProvider, error) {
-	otlpEndpointWithScheme := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if otlpEndpointWithScheme == "" {
-		log.Println("WARN (Generator): OTEL_EXPORTER_OTLP_ENDPOINT not set. Metrics will not be exported via OTLP from generator.")
-		return sdkmetric.NewMeterProvider(sdkmetric.WithResource(resource.Default())), nil
-	}
-
-	endpointParts := strings.SplitN(otlpEndpointWithScheme, "://", 2)
-	var exporterEndpoint string
-	if len(endpointParts) == 2 {
-		exporterEndpoint = endpointParts[1]
-	} else {
-		exporterEndpoint = endpointParts[0]
-	}
-
-	log.Printf("INFO (Generator): OTLP Exporter targeting: %s (from OTEL_EXPORTER_OTLP_ENDPOINT: %s)", exporterEndpoint, otlpEndpointWithScheme)
-
-	// Add retry configuration with backoff
-	var exporter *otlpmetrichttp.Exporter
-	maxRetries := 5
-
-	for i := 0; i < maxRetries; i++ {
-		var err error
-		exporter, err = otlpmetrichttp.New(ctx,
-			otlpmetrichttp.WithEndpoint(exporterEndpoint),
-			otlpmetrichttp.WithInsecure(),
-			otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression),
-			otlpmetrichttp.WithTimeout(15*time.Second),
-		)
-
-		if err == nil {
-			break // Successfully created exporter
-		}
-
-		if i == maxRetries-1 {
-			return nil, fmt.Errorf("synthetic-generator: failed to create OTLP metric exporter after %d attempts: %w", maxRetries, err)
-		}
-
-		retryDelay := time.Duration(1<<uint(i)) * time.Second // Exponential backoff
-		log.Printf("WARN (Generator): Failed to create OTLP exporter (attempt %d/%d): %v. Retrying in %v...",
-			i+1, maxRetries, err, retryDelay)
-		time.Sleep(retryDelay)
-	}
-
-	// Get resource configuration with fallback mechanisms
-	res, err := createResource()
-	if err != nil {
-		log.Printf("WARN (Generator): Failed to create resource: %v. Using default resource.", err)
-		res = resource.Default()
+		semconv.K8SNamespaceNameKey.String(p.k8sNamespace),
+		semconv.K8SPodNameKey.String(p.k8sPodName),
+		semconv.K8SNodeNameKey.String(p.k8sNodeName),
+		semconv.K8SContainerNameKey.String(p.containerName),
+		semconv.ProcessExecutableNameKey.String(p.execName),
+		semconv.ProcessOwnerKey.String(p.owner),
+		semconv.ProcessPIDKey.Int(p.pid),
+		semconv.ProcessCommandLineKey.String(p.cmdLine),
 	}
-
-	// Set up the meter provider with memory management constraints
-	memLimit := getMemoryLimit()
-	log.Printf("INFO (Generator): Configuring with memory limit: %d bytes", memLimit)
-
-	mp := sdkmetric.NewMeterProvider(
-		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter,
-			sdkmetric.WithInterval(10*time.Second),
-			sdkmetric.WithTimeout(30*time.Second),
-		)),
-		sdkmetric.WithResource(res),
-		// Set reasonable memory limits for collection
-		sdkmetric.WithView(sdkmetric.NewView(
-			sdkmetric.Instrument{Scope: instrumentation.Scope{Name: "*"}},
-			sdkmetric.Stream{AggregationTemporality: sdkmetric.CumulativeTemporality},
-		)),
-	)
-	return mp, nil
-}
-
-// createResource creates an OpenTelemetry resource with proper error handling
-func createResource() (*resource.Resource, error) {
-	// Create a base resource from environment variables
-	envRes, err := resource.New(context.Background(),
-		resource.WithFromEnv(),
-		resource.WithProcess(),
-		resource.WithOS(),
-		resource.WithContainer(),
-		resource.WithHost(),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource from environment: %w", err)
-	}
-
-	// Add custom attributes
-	customRes, err := resource.Merge(
-		envRes,
-		resource.NewWithAttributes(
-			semconv.SchemaURL,
-			attribute.String("instrumentation.provider", "synthetic-generator-v3-gu"),
-			attribute.String("benchmark.id", os.Getenv("BENCHMARK_ID")),
-			attribute.String("deployment.environment", os.Getenv("DEPLOYMENT_ENV")),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to merge resources: %w", err)
+	if p.containerID != "" {
+		attrs = append(attrs, semconv.ContainerIDKey.String(p.containerID))
 	}
-
-	return customRes, nil
+	attrs = append(attrs, p.customAttrs...)
+	attrs = append(attrs, p.explosionAttrs...) // Last value wins for duplicate keys
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
 }
 
 // getMemoryLimit determines reasonable memory limits based on container constraints
@@ -211,63 +146,15 @@ func getMemoryLimit() int64 {
 
 func generateProcessMetricAttributes(p *processState) attribute.Set {
 	attrs := []attribute.KeyValue{
-		semconv.ProcessExecutableNameKey.String(p.execName),
-		semconv.ProcessOwnerKey.String(p.owner),
-		semconv.ProcessPIDKey.Int(p.pid),
-		semconv.ProcessCommandLineKey.String(p.cmdLine),
-	}
-	if p.containerID != "" {
-		attrs = append(attrs, semconv.ContainerIDKey.String(p.containerID))
-	}
-	tier := "tier3_support_generic"
-	if strings.Contains(p.execName, "critical") {
-		tier = "tier1_critical_core"
-	} else if strings.HasPrefix(p.execName, "java_app") || strings.HasPrefix(p.execName, "python_api") || strings.HasPrefix(p.execName, "node_gateway") {
-		tier = "tier2_application_main"
-	} else if strings.Contains(p.execName, "nginx") || strings.Contains(p.execName, "postgres") {
-		tier = "tier2_infra_support"
+		attribute.String("custom.service.tier_simulated", p.tier),
 	}
-	attrs = append(attrs, attribute.String("custom.service.tier_simulated", tier))
 	if p.isHeavyHitter {
 		attrs = append(attrs, attribute.Bool("custom.process.is_heavy_hitter_simulated", true))
 	}
 	return attribute.NewSet(attrs...)
 }
 
-func observeProcessMemory(_ context.Context, observer metric.Float64Observer) error {
-	activeProcessesMutex.RLock()
-	defer activeProcessesMutex.RUnlock()
-	for _, hostProcs := range activeProcesses {
-		for _, proc := range hostProcs {
-			observer.Observe(proc.memUsageBytes, metric.WithAttributeSet(proc.metricAttrs))
-		}
-	}
-	return nil
-}
-
-func observeProcessThreads(_ context.Context, observer metric.Float64Observer) error {
-	activeProcessesMutex.RLock()
-	defer activeProcessesMutex.RUnlock()
-	for _, hostProcs := range activeProcesses {
-		for _, proc := range hostProcs {
-			observer.Observe(proc.threadCount, metric.WithAttributeSet(proc.metricAttrs))
-		}
-	}
-	return nil
-}
-
-func observeProcessFDs(_ context.Context, observer metric.Float64Observer) error {
-	activeProcessesMutex.RLock()
-	defer activeProcessesMutex.RUnlock()
-	for _, hostProcs := range activeProcesses {
-		for _, proc := range hostProcs {
-			observer.Observe(proc.openFDCount, metric.WithAttributeSet(proc.metricAttrs))
-		}
-	}
-	return nil
-}
-
-func cleanupResources(ctx context.Context, mp *sdkmetric.MeterProvider) {
+func cleanupResources(ctx context.Context, pool *processMeterPool) {
 	log.Println("INFO (Generator): Shutting down and cleaning up resources...")
 
 	// Clear process maps to free memory
@@ -278,20 +165,20 @@ func cleanupResources(ctx context.Context, mp *sdkmetric.MeterProvider) {
 	activeProcesses = nil
 	activeProcessesMutex.Unlock()
 
-	// Shutdown meter provider gracefully
-	if mp != nil {
+	// Flush and shut down the per-process meter providers gracefully
+	if pool != nil {
 		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 		defer cancel()
 
-		if err := mp.Shutdown(ctx); err != nil {
-			log.Printf("ERROR (Generator): Failed to shutdown meter provider: %v", err)
+		if err := pool.Shutdown(ctx); err != nil {
+			log.Printf("ERROR (Generator): Failed to shutdown meter providers: %v", err)
 		}
 	}
 
 	log.Println("INFO (Generator): Cleanup completed")
 }
 
-func setupGracefulShutdown(ctx context.Context, cancel context.CancelFunc, mp *sdkmetric.MeterProvider) {
+func setupGracefulShutdown(ctx context.Context, cancel context.CancelFunc, pool *processMeterPool) {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
@@ -299,7 +186,7 @@ func setupGracefulShutdown(ctx context.Context, cancel context.CancelFunc, mp *s
 		sig := <-sigs
 		log.Printf("INFO (Generator): Received signal %v, initiating graceful shutdown", sig)
 		cancel()
-		cleanupResources(context.Background(), mp)
+		cleanupResources(context.Background(), pool)
 	}()
 }
 
@@ -340,16 +227,251 @@ func monitorResourceUsage(ctx context.Context, interval time.Duration) {
 	}
 }
 
+// simHost is a simulated host (k8s node) that processes are placed on.
+type simHost struct {
+	hostname  string
+	nodeName  string
+	namespace string
+
+	pidMax    int
+	nextPID   int
+	pidsInUse map[int]bool
+}
+
+// newProcess creates the index-th process of archetype a on host h, under
+// the host's next free PID.
+func newProcess(h *simHost, a *Archetype, index int) (*processState, error) {
+	pid, err := h.allocatePID()
+	if err != nil {
+		return nil, err
+	}
+	containerIDVal := ""
+	if rng.Float64() < a.ContainerProbability {
+		containerIDVal = containerIDs[rng.Intn(len(containerIDs))]
+	}
+
+	podNameBase := strings.ReplaceAll(strings.Split(a.ExecName, "_")[0], "-", "")
+	if len(podNameBase) > 12 {
+		podNameBase = podNameBase[:12]
+	}
+	k8sPod := fmt.Sprintf("%s-%s-%x", k8sPodNamePrefix[rng.Intn(len(k8sPodNamePrefix))], podNameBase, rng.Intn(0xfff))
+
+	data := templateData{
+		ExecName:  a.ExecName,
+		AppName:   strings.ReplaceAll(strings.TrimPrefix(a.ExecName, "java_"), "_", "-"),
+		Host:      h.hostname,
+		Node:      h.nodeName,
+		Namespace: h.namespace,
+		Pod:       k8sPod,
+		Index:     index,
+		PID:       pid,
+		HeapMB:    128 + rng.Intn(8)*32,
+	}
+	cmdLine, err := renderTemplate(a.commandLineTmpl, data)
+	if err != nil {
+		h.releasePID(pid)
+		return nil, fmt.Errorf("failed to render command line: %w", err)
+	}
+	var customAttrs []attribute.KeyValue
+	for key, tmpl := range a.attributeTmpls {
+		value, err := renderTemplate(tmpl, data)
+		if err != nil {
+			h.releasePID(pid)
+			return nil, fmt.Errorf("failed to render attribute %s: %w", key, err)
+		}
+		customAttrs = append(customAttrs, attribute.String(key, value))
+	}
+
+	ps := &processState{
+		host:                    h,
+		archetype:               a,
+		customAttrs:             customAttrs,
+		hostname:                h.hostname,
+		k8sNamespace:            h.namespace,
+		k8sPodName:              k8sPod,
+		k8sNodeName:             h.nodeName,
+		containerName:           a.ExecName,
+		pid:                     pid,
+		execName:                a.ExecName,
+		owner:                   a.Owners[rng.Intn(len(a.Owners))],
+		cmdLine:                 cmdLine,
+		containerID:             containerIDVal,
+		memUsageBytes:           a.InitialMemoryMiB.sample() * 1024 * 1024,
+		cpuTimeTotal:            rng.Float64() * float64(100+rng.Intn(3900)),
+		threadCount:             float64(int(a.InitialThreads.sample())),
+		openFDCount:             float64(int(a.InitialOpenFDs.sample())),
+		diskReadBytes:           rng.Float64() * 1024 * 1024 * float64(20+rng.Intn(180)),
+		diskWriteBytes:          rng.Float64() * 1024 * 1024 * float64(10+rng.Intn(90)),
+		isHeavyHitter:           rng.Float64() < a.HeavyHitterRatio,
+		memLeakRateBytesPerTick: 0,
+		fdLeakRatePerTick:       0,
+	}
+	if rng.Float64() < a.MemLeakProbability {
+		ps.memLeakRateBytesPerTick = a.MemLeakMiBPerTick.sample() * 1024 * 1024
+	}
+	if rng.Float64() < a.FDLeakProbability {
+		ps.fdLeakRatePerTick = a.FDLeakPerTick.sample()
+	}
+	ps.classify()
+	ps.startLifecycle()
+
+	ps.otelResource = createOtelResourceForProcess(ps)
+	ps.metricAttrs = generateProcessMetricAttributes(ps)
+	if err := meterPool.Attach(ps); err != nil {
+		h.releasePID(pid)
+		return nil, err
+	}
+	processSpawnsTotal.WithLabelValues(a.ExecName).Inc()
+	return ps, nil
+}
+
+// classify sets the ground-truth priority and tier of p from the priority
+// rules; a tier declared by the archetype takes precedence.
+func (p *processState) classify() {
+	attrs := make(map[string]string, len(p.customAttrs))
+	for _, kv := range p.customAttrs {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	class := priorityRules.Classify(priority.Process{
+		ExecName:    p.execName,
+		Owner:       p.owner,
+		Namespace:   p.k8sNamespace,
+		CommandLine: p.cmdLine,
+		Attributes:  attrs,
+	})
+	p.priority = class.Priority
+	p.tier = p.archetype.Tier
+	if p.tier == "" {
+		p.tier = class.Tier
+	}
+}
+
+// advanceProcess moves proc forward by one tick following its archetype and
+// records the counter deltas. It returns the number of counter points emitted.
+func advanceProcess(ctx context.Context, proc *processState) int64 {
+	a := proc.archetype
+
+	cpuDelta := a.CPUSecondsPerTick.sample() * a.CPUMultiplier.sample()
+	if proc.isHeavyHitter {
+		cpuDelta *= a.HeavyHitterCPUMultiplier.sample()
+	}
+	proc.cpuTimeTotal += cpuDelta
+	proc.meter.cpuCounter.Add(ctx, cpuDelta, metric.WithAttributeSet(proc.metricAttrs))
+
+	readDelta := a.DiskReadKiBPerTick.sample() * 1024 * a.DiskIOMultiplier
+	writeDelta := a.DiskWriteKiBPerTick.sample() * 1024 * a.DiskIOMultiplier
+	if proc.isHeavyHitter {
+		readDelta *= a.HeavyHitterDiskIOMultiplier
+		writeDelta *= a.HeavyHitterDiskIOMultiplier
+	}
+	proc.diskReadBytes += readDelta
+	proc.diskWriteBytes += writeDelta
+	proc.meter.diskReadCounter.Add(ctx, readDelta, metric.WithAttributeSet(proc.metricAttrs))
+	proc.meter.diskWriteCounter.Add(ctx, writeDelta, metric.WithAttributeSet(proc.metricAttrs))
+
+	memChange := (rng.Float64() - 0.49) * float64(10+rng.Intn(30)) * 1024 * 1024
+	if proc.isHeavyHitter {
+		memChange *= 1.2
+	}
+	proc.memUsageBytes += memChange + proc.memLeakRateBytesPerTick
+	if proc.memUsageBytes < (10 * 1024 * 1024) {
+		proc.memUsageBytes = 10 * 1024 * 1024
+	}
+	if memCap := a.MemoryCapMiB * 1024 * 1024; proc.memUsageBytes > memCap {
+		proc.memUsageBytes = memCap
+	}
+
+	proc.threadCount += (rng.Float64() - 0.48) * 4
+	if proc.threadCount < 2 {
+		proc.threadCount = 2
+	}
+	if proc.threadCount > 200 {
+		proc.threadCount = 200
+	}
+	if proc.isHeavyHitter {
+		proc.threadCount += float64(rng.Intn(8))
+	}
+
+	proc.openFDCount += (rng.Float64()-0.47)*10 + proc.fdLeakRatePerTick
+	if proc.openFDCount < 5 {
+		proc.openFDCount = 5
+	}
+	if proc.openFDCount > 900 {
+		proc.openFDCount = 900
+	}
+
+	if rng.Float64() < a.RestartProbability {
+		restartProcess(proc)
+	}
+	proc.meter.recordGauges(proc)
+	return 3
+}
+
+// restartProcess simulates proc being restarted in place with a new PID.
+func restartProcess(proc *processState) {
+	a := proc.archetype
+	processRestartsTotal.WithLabelValues(a.ExecName).Inc()
+	// The old PID is free again, so there is always one to allocate
+	proc.host.releasePID(proc.pid)
+	pid, err := proc.host.allocatePID()
+	if err != nil {
+		log.Printf("WARN (Generator): Failed to restart %s: %v", proc.execName, err)
+		pid = proc.pid
+		proc.host.pidsInUse[pid] = true
+	}
+	proc.pid = pid
+	if rng.Float32() < 0.05 {
+		baseName := strings.Split(proc.execName, "_v")[0]
+		baseName = strings.Split(baseName, "_restarted")[0]
+		proc.execName = fmt.Sprintf("%s_restarted_v%.1f", baseName, (rng.Float32()*2)+1.0)
+	}
+	proc.cmdLine = fmt.Sprintf("/opt/bin/%s --reconfig --new-instance-%d", proc.execName, proc.pid)
+	// The new instance may match other priority rules, and starts without
+	// the identity overrides of an ongoing explosion
+	proc.classify()
+	proc.explosionAttrs = nil
+	proc.cpuTimeTotal = rng.Float64() * 100.0
+	proc.memUsageBytes = rng.Float64() * float64(64+rng.Intn(256)) * 1024 * 1024
+	proc.threadCount = float64(5 + rng.Intn(20))
+	proc.openFDCount = float64(10 + rng.Intn(50))
+	proc.isHeavyHitter = rng.Float64() < a.HeavyHitterRatio
+	proc.memLeakRateBytesPerTick = 0
+	proc.fdLeakRatePerTick = 0
+	if rng.Float64() < a.MemLeakProbability {
+		proc.memLeakRateBytesPerTick = a.MemLeakMiBPerTick.sample() * 1024 * 1024
+	}
+	// A restarted process is a new resource; its old series stop being reported
+	proc.otelResource = createOtelResourceForProcess(proc)
+	proc.metricAttrs = generateProcessMetricAttributes(proc)
+	if err := meterPool.Attach(proc); err != nil {
+		log.Printf("WARN (Generator): Failed to re-create meter for %s (PID %d): %v", proc.execName, proc.pid, err)
+	}
+}
+
 func main() {
+	scenarioPath := flag.String("scenario", os.Getenv("SYNTHETIC_SCENARIO_FILE"), "Path to a YAML/JSON scenario file (defaults to the built-in scenario)")
+	manifestPath := flag.String("manifest", os.Getenv("SYNTHETIC_MANIFEST_FILE"), "Write the ground-truth series manifest to this file after every tick")
+	priorityRulesPath := flag.String("priority-rules", os.Getenv("SYNTHETIC_PRIORITY_RULES_FILE"), "Path to a process priority rule file (defaults to the built-in rules shared with the collector)")
+	flag.Parse()
+
 	// Create a cancellable context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	
-	// Initialize random seed data
-	initSeedData()
-	
+
 	log.Println("INFO (Generator): Phoenix vNext Synthetic Generator starting up...")
 
+	// Initialize random seed data; a fixed SYNTHETIC_SEED makes runs reproducible
+	seed := time.Now().UnixNano()
+	if seedStr := os.Getenv("SYNTHETIC_SEED"); seedStr != "" {
+		parsed, err := strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			log.Fatalf("ERROR (Generator): Invalid SYNTHETIC_SEED value '%s': %v", seedStr, err)
+		}
+		seed = parsed
+	}
+	initSeedData(seed)
+	log.Printf("INFO (Generator): Using random seed %d (set SYNTHETIC_SEED=%d to reproduce this run)", seed, seed)
+
 	// Load and validate configuration from environment variables with defaults
 	processCountPerHostStr := os.Getenv("SYNTHETIC_PROCESS_COUNT_PER_HOST")
 	processCountPerHost, err := strconv.Atoi(processCountPerHostStr)
@@ -377,243 +499,160 @@ func main() {
 		}
 		metricRateS = 15
 	}
-	
-	// Initialize OpenTelemetry meter provider with error handling
-	mp, err := initMeterProvider(ctx)
+
+	adminAddr := os.Getenv("SYNTHETIC_ADMIN_ADDR")
+	if adminAddr == "" {
+		adminAddr = ":8899"
+	}
+	// Explosion triggers get their own listener, loopback-only unless a token is set
+	explosionAddr := os.Getenv("SYNTHETIC_EXPLOSION_ADDR")
+	if explosionAddr == "" {
+		explosionAddr = defaultExplosionControlAddr
+	}
+	explosionToken := os.Getenv("SYNTHETIC_EXPLOSION_TOKEN")
+	if err := checkExplosionControlAddr(explosionAddr, explosionToken, adminAddr); err != nil {
+		log.Fatalf("ERROR (Generator): %v", err)
+	}
+
+	if *priorityRulesPath != "" {
+		if priorityRules, err = priority.Read(*priorityRulesPath); err != nil {
+			log.Fatalf("ERROR (Generator): Failed to load priority rules: %v", err)
+		}
+		log.Printf("INFO (Generator): Using priority rules from %s", *priorityRulesPath)
+	}
+
+	scenario, err := loadScenario(*scenarioPath)
 	if err != nil {
-		log.Fatalf("ERROR (Generator): Failed to initialize meter provider: %v", err)
+		log.Fatalf("ERROR (Generator): Failed to load scenario: %v", err)
+	}
+	scenario.applyEnvDefaults(hostCount, processCountPerHost, metricRateS)
+	if err := scenario.Temporality.applyEnvDefault(os.Getenv("OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE")); err != nil {
+		log.Fatalf("ERROR (Generator): %v", err)
 	}
-	otel.SetMeterProvider(mp)
-	
+	log.Printf("INFO (Generator): Counter temporality: %s", &scenario.Temporality)
+
+	// Initialize the shared OTLP client with error handling
+	client, err := initMetricsClient()
+	if err != nil {
+		log.Fatalf("ERROR (Generator): Failed to initialize metrics client: %v", err)
+	}
+
+	memLimit := getMemoryLimit()
+	log.Printf("INFO (Generator): Configuring with memory limit: %d bytes", memLimit)
+
+	// Every simulated process is exported under its own resource via its own
+	// meter provider, one request per host
+	meterPool = newProcessMeterPool(client, scenario.Temporality)
+
 	// Setup graceful shutdown handler
-	setupGracefulShutdown(ctx, cancel, mp)
-	
+	setupGracefulShutdown(ctx, cancel, meterPool)
+
 	// Start resource usage monitoring in background
 	go monitorResourceUsage(ctx, 60*time.Second)
 
-	meter := otel.Meter("phoenix.v3.ultimate.synthetic.generator")
-	// Create metrics with proper error handling
-	createMetric := func(name, description, unit string) (metric.Float64Counter, error) {
-		counter, err := meter.Float64Counter(
-			name,
-			metric.WithDescription(description),
-			metric.WithUnit(unit),
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create %s counter: %w", name, err)
-		}
-		return counter, nil
-	}
-	
-	// Create all required metrics
-	var instErr error
-	processCPUCounter, instErr = createMetric(
-		"process.cpu.time",
-		"Cumulative CPU time consumed by the process, reported as delta",
-		"s")
-	if instErr != nil {
-		log.Fatalf("ERROR (Generator): %v", instErr)
-	}
-	
-	processDiskReadCounter, instErr = createMetric(
-		"process.disk.io.read_bytes",
-		"Cumulative disk read bytes, reported as delta",
-		"By")
-	if instErr != nil {
-		log.Fatalf("ERROR (Generator): %v", instErr)
-	}
-	
-	processDiskWriteCounter, instErr = createMetric(
-		"process.disk.io.write_bytes",
-		"Cumulative disk write bytes, reported as delta",
-		"By")
-	if instErr != nil {
-		log.Fatalf("ERROR (Generator): %v", instErr)
-	}
-
 	activeProcesses = make(map[string][]*processState)
 	totalProcessesGenerated := 0
+	currentPhase, perHost := scenario.phaseAt(0)
+	if currentPhase >= 0 {
+		log.Printf("INFO (Generator): Scenario timeline has %d phases, starting with '%s'", len(scenario.Phases), scenario.Phases[currentPhase].Name)
+	}
 
-	log.Printf("INFO (Generator): Initializing %d hosts, each with %d processes...", hostCount, processCountPerHost)
-	for h := 0; h < hostCount; h++ {
+	log.Printf("INFO (Generator): Initializing scenario '%s': %d hosts, %d processes per host, %d archetypes...",
+		scenario.Name, scenario.Hosts, scenario.ProcessesPerHost, len(scenario.Archetypes))
+	for h := 0; h < scenario.Hosts; h++ {
 		k8sNodeName := fmt.Sprintf("%s-%s", baseHostnames[h%len(baseHostnames)], k8sNodeSuffix[h%len(k8sNodeSuffix)])
-		hostnameForProcs := k8sNodeName
-
-		activeProcesses[hostnameForProcs] = []*processState{}
-		k8sNs := k8sNamespaces[rand.Intn(len(k8sNamespaces))]
+		host := &simHost{
+			hostname:  k8sNodeName,
+			nodeName:  k8sNodeName,
+			namespace: k8sNamespaces[rng.Intn(len(k8sNamespaces))],
+			pidMax:    scenario.Lifecycle.PIDMax,
+		}
+		simHosts = append(simHosts, host)
+		activeProcesses[host.hostname] = []*processState{}
 
-		for i := 0; i < processCountPerHost; i++ {
+		for i, archetype := range scenario.hostPopulation(perHost) {
 			totalProcessesGenerated++
-			pid := 1000 + totalProcessesGenerated
-			execName := processExecNames[rand.Intn(len(processExecNames))]
-			owner := processOwners[rand.Intn(len(processOwners))]
-			containerIDVal := ""
-			if rand.Float32() < 0.7 {
-				containerIDVal = containerIDs[rand.Intn(len(containerIDs))]
-			}
-
-			podNameBase := strings.ReplaceAll(strings.Split(execName, "_")[0], "-", "")
-			if len(podNameBase) > 12 {
-				podNameBase = podNameBase[:12]
-			}
-			k8sPod := fmt.Sprintf("%s-%s-%x", k8sPodNamePrefix[rand.Intn(len(k8sPodNamePrefix))], podNameBase, rand.Intn(0xfff))
-			containerName := execName
-
-			cmdLine := fmt.Sprintf("/opt/app/%s --config /etc/app/config.yaml --instance %d --pod %s --namespace %s", execName, i%20, k8sPod, k8sNs)
-			if strings.Contains(execName, "java") {
-				heapSize := 128 + rand.Intn(8)*32
-				appNameForCmd := strings.ReplaceAll(strings.ReplaceAll(execName, "java_", ""), "_", "-")
-				cmdLine = fmt.Sprintf("/usr/bin/java -Dapp.name=%s -Dspring.profiles.active=%s -Xms%dm -Xmx%dm -jar /opt/apps/%s.jar --server.port=%d", appNameForCmd, k8sNs, heapSize/2, heapSize, appNameForCmd, 8000+i%100)
-			}
-
-			isHeavy := rand.Float32() < 0.08
-
-			ps := &processState{
-				otelResource:            createOtelResourceForProcess(hostnameForProcs, k8sNs, k8sPod, k8sNodeName, containerName),
-				pid:                     pid,
-				execName:                execName,
-				owner:                   owner,
-				cmdLine:                 cmdLine,
-				containerID:             containerIDVal,
-				memUsageBytes:           rand.Float64() * float64(64+rand.Intn(1024)) * 1024 * 1024,
-				cpuTimeTotal:            rand.Float64() * float64(100+rand.Intn(3900)),
-				threadCount:             float64(5 + rand.Intn(80)),
-				openFDCount:             float64(10 + rand.Intn(300)),
-				diskReadBytes:           rand.Float64() * 1024 * 1024 * float64(20+rand.Intn(180)),
-				diskWriteBytes:          rand.Float64() * 1024 * 1024 * float64(10+rand.Intn(90)),
-				isHeavyHitter:           isHeavy,
-				memLeakRateBytesPerTick: 0,
-				fdLeakRatePerTick:       0,
+			ps, err := newProcess(host, archetype, i)
+			if err != nil {
+				log.Fatalf("ERROR (Generator): Failed to create %s process on %s: %v", archetype.ExecName, host.hostname, err)
 			}
-			if rand.Float32() < 0.02 {
-				ps.memLeakRateBytesPerTick = rand.Float64() * 5 * 1024 * 1024
-				// log.Printf("INFO (Generator): Simulating memory leak for %s (PID %d) on %s at %.2f MB/tick", ps.execName, ps.pid, hostnameForProcs, ps.memLeakRateBytesPerTick/(1024*1024))
-			}
-			if rand.Float32() < 0.01 {
-				ps.fdLeakRatePerTick = rand.Float64() * 3
-				// log.Printf("INFO (Generator): Simulating FD leak for %s (PID %d) on %s at %.0f FDs/tick", ps.execName, ps.pid, hostnameForProcs, ps.fdLeakRatePerTick)
-			}
-
-			ps.metricAttrs = generateProcessMetricAttributes(ps)
-			activeProcesses[hostnameForProcs] = append(activeProcesses[hostnameForProcs], ps)
+			activeProcesses[host.hostname] = append(activeProcesses[host.hostname], ps)
 		}
 	}
 
-	_, instErr = meter.Float64ObservableGauge("process.memory.usage",
-		metric.WithDescription("Resident Set Size of the process"), metric.WithUnit("By"),
-		metric.WithFloat64Callback(observeProcessMemory))
-	if instErr != nil {
-		log.Fatalf("Failed to create process.memory.usage gauge: %v", instErr)
-	}
+	log.Printf("INFO (Generator): Initialized %d hosts, %d total processes. Starting metric emission every %d seconds...", scenario.Hosts, totalProcessesGenerated, scenario.EmitIntervalSeconds)
 
-	_, instErr = meter.Float64ObservableGauge("process.threads",
-		metric.WithDescription("Number of threads in the process"), metric.WithUnit("{threads}"),
-		metric.WithFloat64Callback(observeProcessThreads))
-	if instErr != nil {
-		log.Fatalf("Failed to create process.threads gauge: %v", instErr)
-	}
+	go meterPool.Run(ctx)
 
-	_, instErr = meter.Float64ObservableGauge("process.open_file_descriptors",
-		metric.WithDescription("Number of open file descriptors"), metric.WithUnit("{descriptors}"),
-		metric.WithFloat64Callback(observeProcessFDs))
-	if instErr != nil {
-		log.Fatalf("Failed to create process.open_file_descriptors gauge: %v", instErr)
-	}
+	tickInterval := time.Duration(scenario.EmitIntervalSeconds) * time.Second
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	// Scenario time advances by one interval per tick rather than by wall clock,
+	// so phase changes and scheduled explosions land on the same tick every run
+	var ticks int64
 
-	log.Printf("INFO (Generator): Initialized %d hosts, %d total processes. Starting metric emission every %d seconds...", hostCount, totalProcessesGenerated, metricRateS)
+	// Cardinality explosions run on the scenario schedule or on demand (SIGUSR1/SIGUSR2 or HTTP)
+	injector := newExplosionInjector(scenario.Explosion)
+	go handleExplosionSignals(ctx, injector)
 
-	ticker := time.NewTicker(time.Duration(metricRateS) * time.Second)
-	defer ticker.Stop()
+	// The ground-truth series manifest is always served over HTTP and optionally written to a file
+	manifest := &manifestSource{scenario: scenario.Name, seed: seed}
+	if *manifestPath != "" {
+		log.Printf("INFO (Generator): Writing series manifest to %s after every tick", *manifestPath)
+	}
+	startAdminServer(ctx, "Admin", adminAddr, newAdminMux(injector, manifest))
+	startAdminServer(ctx, "Explosion control", explosionAddr, newExplosionControlMux(injector, explosionToken))
 
 	for {
 		select {
 		case <-ticker.C:
+			ticks++
+			elapsed := time.Duration(ticks) * tickInterval
 			activeProcessesMutex.Lock()
+			phase, target := scenario.phaseAt(elapsed)
+			if phase >= 0 {
+				if phase != currentPhase {
+					currentPhase = phase
+					log.Printf("INFO (Generator): Entering scenario phase '%s' (%d/%d)", scenario.Phases[phase].Name, phase+1, len(scenario.Phases))
+				}
+				var added, removed int
+				for _, host := range simHosts {
+					a, r := reconcileHostPopulation(scenario, host, target)
+					added += a
+					removed += r
+				}
+				if added > 0 || removed > 0 {
+					log.Printf("INFO (Generator): Phase '%s' target %d processes/host: started %d, stopped %d", scenario.Phases[phase].Name, target, added, removed)
+				}
+			}
+			var spawned, exited int
+			for _, host := range simHosts {
+				s, e := advanceHostLifecycle(scenario, host, target)
+				spawned += s
+				exited += e
+			}
+			if spawned > 0 || exited > 0 {
+				log.Printf("INFO (Generator): Process lifecycle: %d exited, %d started", exited, spawned)
+			}
+			if exploded := injector.Apply(time.Now(), elapsed, tickInterval); exploded > 0 {
+				log.Printf("INFO (Generator): Cardinality explosion gave %d processes a new identity this tick", exploded)
+			}
 			var totalMetricPointsEmittedThisTick int64
-			for _, hostProcs := range activeProcesses {
-				for i := range hostProcs {
-					proc := hostProcs[i]
-
-					cpuDelta := rand.Float64()*0.7 + 0.001
-					if proc.isHeavyHitter || strings.Contains(proc.execName, "critical") {
-						cpuDelta *= (2.0 + rand.Float64()*3.0)
-					}
-					if strings.HasPrefix(proc.execName, "sidecar") {
-						cpuDelta *= 0.15
-					}
-					proc.cpuTimeTotal += cpuDelta
-					processCPUCounter.Add(ctx, cpuDelta, metric.WithAttributeSet(proc.metricAttrs))
-					totalMetricPointsEmittedThisTick++
-
-					readDelta := rand.Float64() * 1024 * float64(5+rand.Intn(150))
-					writeDelta := rand.Float64() * 1024 * float64(2+rand.Intn(75))
-					if proc.isHeavyHitter || strings.Contains(proc.execName, "postgres") || strings.Contains(proc.execName, "data_pipeline") {
-						readDelta *= 3
-						writeDelta *= 3
-					}
-					proc.diskReadBytes += readDelta
-					proc.diskWriteBytes += writeDelta
-					processDiskReadCounter.Add(ctx, readDelta, metric.WithAttributeSet(proc.metricAttrs))
-					processDiskWriteCounter.Add(ctx, writeDelta, metric.WithAttributeSet(proc.metricAttrs))
-					totalMetricPointsEmittedThisTick += 2
-
-					memChange := (rand.Float64() - 0.49) * float64(10+rand.Intn(30)) * 1024 * 1024
-					if proc.isHeavyHitter {
-						memChange *= 1.2
-					}
-					proc.memUsageBytes += memChange + proc.memLeakRateBytesPerTick
-					if proc.memUsageBytes < (10 * 1024 * 1024) {
-						proc.memUsageBytes = 10 * 1024 * 1024
-					}
-					if proc.memUsageBytes > (1800 * 1024 * 1024) {
-						proc.memUsageBytes = 1800 * 1024 * 1024
-					} // Max ~1.8GB
-
-					proc.threadCount += (rand.Float64() - 0.48) * 4
-					if proc.threadCount < 2 {
-						proc.threadCount = 2
-					}
-					if proc.threadCount > 200 {
-						proc.threadCount = 200
-					}
-					if proc.isHeavyHitter {
-						proc.threadCount += float64(rand.Intn(8))
-					}
-
-					proc.openFDCount += (rand.Float64()-0.47)*10 + proc.fdLeakRatePerTick
-					if proc.openFDCount < 5 {
-						proc.openFDCount = 5
-					}
-					if proc.openFDCount > 900 {
-						proc.openFDCount = 900
-					}
-
-					if rand.Float32() < 0.0005 {
-						_ = proc.pid
-						_ = proc.execName
-						proc.pid = 70000 + rand.Intn(30000)
-						if rand.Float32() < 0.05 {
-							baseName := strings.Split(proc.execName, "_v")[0]
-							baseName = strings.Split(baseName, "_restarted")[0]
-							proc.execName = fmt.Sprintf("%s_restarted_v%.1f", baseName, (rand.Float32()*2)+1.0)
-						}
-						proc.cmdLine = fmt.Sprintf("/opt/bin/%s --reconfig --new-instance-%d", proc.execName, proc.pid)
-						proc.metricAttrs = generateProcessMetricAttributes(proc)
-						proc.cpuTimeTotal = rand.Float64() * 100.0
-						proc.memUsageBytes = rand.Float64() * float64(64+rand.Intn(256)) * 1024 * 1024
-						proc.threadCount = float64(5 + rand.Intn(20))
-						proc.openFDCount = float64(10 + rand.Intn(50))
-						proc.isHeavyHitter = rand.Float32() < 0.08
-						proc.memLeakRateBytesPerTick = 0
-						proc.fdLeakRatePerTick = 0
-						if rand.Float32() < 0.02 {
-							proc.memLeakRateBytesPerTick = rand.Float64() * 2 * 1024 * 1024
-						}
-						// log.Printf("INFO (Generator): Host %s: Process %s (Old PID %d) 'restarted' as %s PID %d", hostname, oldExecName, oldPID, proc.execName, proc.pid)
-					}
+			for _, host := range simHosts {
+				for _, proc := range activeProcesses[host.hostname] {
+					totalMetricPointsEmittedThisTick += advanceProcess(ctx, proc)
 				}
 			}
+			recordPopulationTelemetry()
+			manifest.tick = ticks
 			activeProcessesMutex.Unlock()
+			// Collecting right after the tick keeps exported values tick-aligned
+			meterPool.Flush(ctx)
+			if *manifestPath != "" {
+				if err := writeManifestFile(*manifestPath, manifest.snapshot(true)); err != nil {
+					log.Printf("WARN (Generator): Failed to write series manifest: %v", err)
+				}
+			}
 			log.Printf("INFO (Generator): Tick completed. Emitted approx %d counter data points. Gauge values updated.", totalMetricPointsEmittedThisTick)
 		case <-ctx.Done():
 			log.Println("INFO (Generator): Shutdown signal received.")
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// Process exit reasons reported in logs and self-telemetry.
+const (
+	exitReasonExit      = "exit"      // Clean exit (exit_probability)
+	exitReasonCompleted = "completed" // Batch job reached its lifetime
+	exitReasonCrash     = "crash"     // Crash-looping process died and was restarted
+)
+
+const (
+	firstPID                = 1001
+	pidWrapStart            = 300 // Like the kernel, PIDs below this are never reused
+	defaultPIDMax           = 32768
+	defaultBirthProbability = 0.5
+)
+
+// LifecycleConfig controls process churn at the scenario level. Per-process
+// behaviour (exits, lifetimes, crash loops) is set on archetypes.
+type LifecycleConfig struct {
+	// PIDMax is where a host's PID counter wraps. Every host has its own PID
+	// space, so the same PIDs show up on every host and, once the counter
+	// wraps, are reused on the same host.
+	PIDMax int `yaml:"pid_max"`
+	// BirthProbability is the per-tick chance that each free weighted slot on
+	// a host (processes_per_host minus running processes) gets a new process,
+	// so the population dips after exits and recovers over a few ticks.
+	BirthProbability float64 `yaml:"birth_probability"`
+}
+
+func defaultLifecycleConfig() LifecycleConfig {
+	return LifecycleConfig{PIDMax: defaultPIDMax, BirthProbability: defaultBirthProbability}
+}
+
+func (c *LifecycleConfig) validate() error {
+	if c.PIDMax <= pidWrapStart {
+		return fmt.Errorf("lifecycle: pid_max must be greater than %d", pidWrapStart)
+	}
+	if c.BirthProbability < 0 || c.BirthProbability > 1 {
+		return fmt.Errorf("lifecycle: birth_probability must be within [0, 1], got %g", c.BirthProbability)
+	}
+	return nil
+}
+
+// allocatePID hands out the next free PID on h, wrapping at pidMax. It fails
+// once every PID of the host is in use. Callers must hold
+// activeProcessesMutex once the tick loop is running.
+func (h *simHost) allocatePID() (int, error) {
+	if h.pidsInUse == nil {
+		h.pidsInUse = make(map[int]bool)
+		h.nextPID = firstPID
+	}
+	for tries := 0; tries < h.pidMax; tries++ {
+		if h.nextPID >= h.pidMax {
+			h.nextPID = pidWrapStart
+		}
+		pid := h.nextPID
+		h.nextPID++
+		if !h.pidsInUse[pid] {
+			h.pidsInUse[pid] = true
+			return pid, nil
+		}
+	}
+	return 0, fmt.Errorf("PID space of %s exhausted (pid_max %d); raise lifecycle.pid_max", h.hostname, h.pidMax)
+}
+
+func (h *simHost) releasePID(pid int) {
+	delete(h.pidsInUse, pid)
+}
+
+// startLifecycle draws the lifetime and crash-loop behaviour of a process
+// that has just started.
+func (proc *processState) startLifecycle() {
+	a := proc.archetype
+	proc.ageTicks = 0
+	proc.lifetimeTicks = int(a.LifetimeTicks.sample())
+	proc.crashEveryTicks = 0
+	if rng.Float64() < a.CrashLoopProbability {
+		proc.crashEveryTicks = int(a.CrashLoopEveryTicks.sample())
+		if proc.crashEveryTicks < 1 {
+			proc.crashEveryTicks = 1
+		}
+	}
+}
+
+// advanceHostLifecycle ages every process on h by one tick: crash-looping
+// processes die and come back under a new PID, finished batch jobs and
+// exiting processes go away (pinned ones are replaced at once, as their
+// controller would), and free weighted slots are refilled by births. Callers
+// must hold activeProcessesMutex.
+func advanceHostLifecycle(scenario *Scenario, h *simHost, perHost int) (spawned, exited int) {
+	procs := activeProcesses[h.hostname]
+	kept := procs[:0]
+	var respawn []*Archetype
+	for _, proc := range procs {
+		proc.ageTicks++
+		a := proc.archetype
+		reason := ""
+		switch {
+		case proc.lifetimeTicks > 0 && proc.ageTicks >= proc.lifetimeTicks:
+			reason = exitReasonCompleted
+		case a.ExitProbability > 0 && rng.Float64() < a.ExitProbability:
+			reason = exitReasonExit
+		case proc.crashEveryTicks > 0 && proc.ageTicks%proc.crashEveryTicks == 0:
+			processExitsTotal.WithLabelValues(a.ExecName, exitReasonCrash).Inc()
+			restartProcess(proc)
+		}
+		if reason == "" {
+			kept = append(kept, proc)
+			continue
+		}
+		stopProcess(proc)
+		processExitsTotal.WithLabelValues(a.ExecName, reason).Inc()
+		exited++
+		if a.CountPerHost > 0 {
+			respawn = append(respawn, a)
+		}
+	}
+	for i := len(kept); i < len(procs); i++ {
+		procs[i] = nil
+	}
+	procs = kept
+
+	for _, a := range respawn {
+		ps, err := newProcess(h, a, len(procs))
+		if err != nil {
+			log.Printf("WARN (Generator): Failed to respawn %s process on %s: %v", a.ExecName, h.hostname, err)
+			continue
+		}
+		procs = append(procs, ps)
+		spawned++
+	}
+
+	free := perHost - len(procs)
+	births := 0
+	for i := 0; i < free; i++ {
+		if rng.Float64() < scenario.Lifecycle.BirthProbability {
+			births++
+		}
+	}
+	procs, n := spawnWeightedProcesses(scenario, h, procs, births)
+	spawned += n
+	activeProcesses[h.hostname] = procs
+	return spawned, exited
+}
+
+// stopProcess releases everything a process that is going away holds.
+func stopProcess(proc *processState) {
+	meterPool.Detach(proc)
+	proc.host.releasePID(proc.pid)
+}
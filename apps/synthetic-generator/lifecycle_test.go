@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// allocate returns the next n PIDs of h.
+func allocate(t *testing.T, h *simHost, n int) string {
+	t.Helper()
+	pids := make([]int, n)
+	for i := range pids {
+		pid, err := h.allocatePID()
+		if err != nil {
+			t.Fatalf("allocatePID() error = %v", err)
+		}
+		pids[i] = pid
+	}
+	return fmt.Sprint(pids)
+}
+
+func TestAllocatePIDWraps(t *testing.T) {
+	h := &simHost{hostname: "host-1", pidMax: 1004}
+	if got := allocate(t, h, 5); got != "[1001 1002 1003 300 301]" {
+		t.Errorf("PIDs = %s, want 1001 up to pid_max, then from 300", got)
+	}
+	// A released PID is not reused before the counter comes back to it
+	h.releasePID(1002)
+	if got := allocate(t, h, 1); got != "[302]" {
+		t.Errorf("PID after a release = %s, want 302", got)
+	}
+}
+
+func TestAllocatePIDSkipsInUse(t *testing.T) {
+	// pid_max below the first PID: the space is 300-304
+	h := &simHost{hostname: "host-1", pidMax: 305}
+	if got := allocate(t, h, 5); got != "[300 301 302 303 304]" {
+		t.Fatalf("PIDs = %s", got)
+	}
+	h.releasePID(301)
+	h.releasePID(303)
+	if got := allocate(t, h, 2); got != "[301 303]" {
+		t.Errorf("PIDs after wrapping = %s, want the released ones", got)
+	}
+
+	_, err := h.allocatePID()
+	if err == nil || !strings.Contains(err.Error(), "PID space of host-1 exhausted (pid_max 305)") {
+		t.Fatalf("allocatePID() on a full host error = %v", err)
+	}
+	// The host recovers once a process goes away
+	h.releasePID(302)
+	if got := allocate(t, h, 1); got != "[302]" {
+		t.Errorf("PID after exhaustion = %s, want 302", got)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// seriesManifest is the ground truth of what the generator is emitting: every
+// live series with its full attribute set (resource and data point attributes
+// merged) plus totals. Pipelines' outputs can be diffed against it to compute
+// recall and precision instead of comparing raw series counts.
+type seriesManifest struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Scenario    string           `json:"scenario"`
+	Seed        int64            `json:"seed"`
+	Tick        int64            `json:"tick"`
+	TotalSeries int              `json:"total_series"`
+	Totals      manifestTotals   `json:"totals"`
+	Series      []manifestSeries `json:"series,omitempty"`
+}
+
+type manifestTotals struct {
+	ByMetric   map[string]int `json:"by_metric"`
+	ByTier     map[string]int `json:"by_tier"`
+	ByPriority map[string]int `json:"by_priority"`
+	ByHost     map[string]int `json:"by_host"`
+}
+
+type manifestSeries struct {
+	Metric      string            `json:"metric"`
+	Attributes  map[string]string `json:"attributes"`
+	Host        string            `json:"host"`
+	Tier        string            `json:"tier"`
+	Priority    string            `json:"priority"`
+	ExecName    string            `json:"exec_name"`
+	HeavyHitter bool              `json:"heavy_hitter"`
+	MemLeak     bool              `json:"mem_leak"`
+	FDLeak      bool              `json:"fd_leak"`
+	Exploded    bool              `json:"exploded,omitempty"`
+}
+
+// manifestSource builds manifests of the running generator.
+type manifestSource struct {
+	scenario string
+	seed     int64
+	tick     int64 // Guarded by activeProcessesMutex
+}
+
+// snapshot builds the manifest of the latest completed tick. Series are
+// listed in host, process and metric order so that seeded runs produce
+// identical manifests. withSeries=false returns the totals only.
+func (m *manifestSource) snapshot(withSeries bool) *seriesManifest {
+	activeProcessesMutex.RLock()
+	defer activeProcessesMutex.RUnlock()
+
+	man := &seriesManifest{
+		GeneratedAt: time.Now().UTC(),
+		Scenario:    m.scenario,
+		Seed:        m.seed,
+		Tick:        m.tick,
+		Totals: manifestTotals{
+			ByMetric:   make(map[string]int),
+			ByTier:     make(map[string]int),
+			ByPriority: make(map[string]int),
+			ByHost:     make(map[string]int),
+		},
+	}
+	for _, host := range simHosts {
+		for _, proc := range activeProcesses[host.hostname] {
+			n := len(processMetricNames)
+			man.TotalSeries += n
+			man.Totals.ByTier[proc.tier] += n
+			man.Totals.ByPriority[proc.priority] += n
+			man.Totals.ByHost[proc.hostname] += n
+			for _, name := range processMetricNames {
+				man.Totals.ByMetric[name]++
+			}
+			if !withSeries {
+				continue
+			}
+			attrs := processSeriesAttributes(proc)
+			for _, name := range processMetricNames {
+				man.Series = append(man.Series, manifestSeries{
+					Metric:      name,
+					Attributes:  attrs,
+					Host:        proc.hostname,
+					Tier:        proc.tier,
+					Priority:    proc.priority,
+					ExecName:    proc.execName,
+					HeavyHitter: proc.isHeavyHitter,
+					MemLeak:     proc.memLeakRateBytesPerTick > 0,
+					FDLeak:      proc.fdLeakRatePerTick > 0,
+					Exploded:    len(proc.explosionAttrs) > 0,
+				})
+			}
+		}
+	}
+	return man
+}
+
+// processSeriesAttributes merges proc's resource and data point attributes
+// the way they identify a series once flattened (e.g. by a Prometheus
+// exporter).
+func processSeriesAttributes(proc *processState) map[string]string {
+	attrs := make(map[string]string)
+	add := func(iter attribute.Iterator) {
+		for iter.Next() {
+			kv := iter.Attribute()
+			attrs[string(kv.Key)] = kv.Value.Emit()
+		}
+	}
+	add(proc.otelResource.Iter())
+	add(proc.metricAttrs.Iter())
+	return attrs
+}
+
+// writeManifestFile atomically replaces path with man.
+func writeManifestFile(path string, man *seriesManifest) error {
+	data, err := json.Marshal(man)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".manifest-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temp manifest file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set manifest permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace manifest file %s: %w", path, err)
+	}
+	return nil
+}
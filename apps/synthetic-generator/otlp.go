@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/encoding/gzip" // Registers the gzip compressor
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// The SDK exporters send one ResourceMetrics per request, so the generator
+// builds its OTLP requests itself to send every process of a host in one.
+
+// metricsClient sends OTLP metrics export requests.
+type metricsClient interface {
+	Export(ctx context.Context, req *colmetricpb.ExportMetricsServiceRequest) error
+	Shutdown(ctx context.Context) error
+}
+
+// newMetricsClient returns the client for the configured protocol.
+func (c *otlpExportConfig) newMetricsClient() (metricsClient, error) {
+	if c.protocol == otlpProtocolGRPC {
+		creds := insecure.NewCredentials()
+		if !c.insecure {
+			creds = credentials.NewTLS(c.tlsConfig)
+		}
+		conn, err := grpc.NewClient(c.endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, err
+		}
+		client := &grpcMetricsClient{conn: conn, client: colmetricpb.NewMetricsServiceClient(conn), timeout: c.timeout}
+		if len(c.headers) > 0 {
+			client.headers = metadata.New(c.headers)
+		}
+		if c.compression == "gzip" {
+			client.callOpts = append(client.callOpts, grpc.UseCompressor("gzip"))
+		}
+		return client, nil
+	}
+
+	scheme := "http"
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !c.insecure {
+		scheme = "https"
+		transport.TLSClientConfig = c.tlsConfig
+	}
+	return &httpMetricsClient{
+		url:     scheme + "://" + c.endpoint + c.urlPath,
+		client:  &http.Client{Transport: transport, Timeout: c.timeout},
+		headers: c.headers,
+		gzip:    c.compression == "gzip",
+	}, nil
+}
+
+type grpcMetricsClient struct {
+	conn     *grpc.ClientConn
+	client   colmetricpb.MetricsServiceClient
+	headers  metadata.MD
+	callOpts []grpc.CallOption
+	timeout  time.Duration
+}
+
+func (c *grpcMetricsClient) Export(ctx context.Context, req *colmetricpb.ExportMetricsServiceRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	if c.headers != nil {
+		ctx = metadata.NewOutgoingContext(ctx, c.headers)
+	}
+	resp, err := c.client.Export(ctx, req, c.callOpts...)
+	if err != nil {
+		return err
+	}
+	return partialSuccessError(resp)
+}
+
+func (c *grpcMetricsClient) Shutdown(context.Context) error {
+	return c.conn.Close()
+}
+
+type httpMetricsClient struct {
+	url     string
+	client  *http.Client
+	headers map[string]string
+	gzip    bool
+}
+
+func (c *httpMetricsClient) Export(ctx context.Context, req *colmetricpb.ExportMetricsServiceRequest) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode OTLP request: %w", err)
+	}
+	if c.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return fmt.Errorf("failed to compress OTLP request: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("failed to compress OTLP request: %w", err)
+		}
+		body = buf.Bytes()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	if c.gzip {
+		httpReq.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return fmt.Errorf("failed to read OTLP response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("OTLP export failed: %s", resp.Status)
+	}
+	var exportResp colmetricpb.ExportMetricsServiceResponse
+	if err := proto.Unmarshal(respBody, &exportResp); err != nil {
+		// Some backends answer 2xx without a protobuf body
+		return nil
+	}
+	return partialSuccessError(&exportResp)
+}
+
+func (c *httpMetricsClient) Shutdown(context.Context) error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+func partialSuccessError(resp *colmetricpb.ExportMetricsServiceResponse) error {
+	if ps := resp.GetPartialSuccess(); ps != nil && ps.GetRejectedDataPoints() > 0 {
+		return fmt.Errorf("OTLP export partially rejected: %d data points (%s)", ps.GetRejectedDataPoints(), ps.GetErrorMessage())
+	}
+	return nil
+}
+
+// exportRequest builds one OTLP request carrying every resource of rms.
+func exportRequest(rms []*metricdata.ResourceMetrics) (*colmetricpb.ExportMetricsServiceRequest, error) {
+	req := &colmetricpb.ExportMetricsServiceRequest{ResourceMetrics: make([]*metricpb.ResourceMetrics, 0, len(rms))}
+	for _, rm := range rms {
+		pb := &metricpb.ResourceMetrics{
+			Resource:  &resourcepb.Resource{Attributes: keyValues(rm.Resource.Iter())},
+			SchemaUrl: rm.Resource.SchemaURL(),
+		}
+		for _, sm := range rm.ScopeMetrics {
+			spb := &metricpb.ScopeMetrics{Scope: scope(sm.Scope), SchemaUrl: sm.Scope.SchemaURL}
+			for _, m := range sm.Metrics {
+				mpb, err := metricProto(m)
+				if err != nil {
+					return nil, err
+				}
+				spb.Metrics = append(spb.Metrics, mpb)
+			}
+			pb.ScopeMetrics = append(pb.ScopeMetrics, spb)
+		}
+		req.ResourceMetrics = append(req.ResourceMetrics, pb)
+	}
+	return req, nil
+}
+
+func scope(s instrumentation.Scope) *commonpb.InstrumentationScope {
+	return &commonpb.InstrumentationScope{Name: s.Name, Version: s.Version}
+}
+
+// metricProto converts the sums and gauges the generator records.
+func metricProto(m metricdata.Metrics) (*metricpb.Metric, error) {
+	pb := &metricpb.Metric{Name: m.Name, Description: m.Description, Unit: m.Unit}
+	switch d := m.Data.(type) {
+	case metricdata.Sum[float64]:
+		pb.Data = &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+			DataPoints:             numberPoints(d.DataPoints),
+			AggregationTemporality: temporalityProto(d.Temporality),
+			IsMonotonic:            d.IsMonotonic,
+		}}
+	case metricdata.Sum[int64]:
+		pb.Data = &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+			DataPoints:             numberPoints(d.DataPoints),
+			AggregationTemporality: temporalityProto(d.Temporality),
+			IsMonotonic:            d.IsMonotonic,
+		}}
+	case metricdata.Gauge[float64]:
+		pb.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: numberPoints(d.DataPoints)}}
+	case metricdata.Gauge[int64]:
+		pb.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: numberPoints(d.DataPoints)}}
+	default:
+		return nil, fmt.Errorf("metric %s: unsupported aggregation %T", m.Name, m.Data)
+	}
+	return pb, nil
+}
+
+func numberPoints[N int64 | float64](dps []metricdata.DataPoint[N]) []*metricpb.NumberDataPoint {
+	out := make([]*metricpb.NumberDataPoint, 0, len(dps))
+	for _, dp := range dps {
+		pb := &metricpb.NumberDataPoint{
+			Attributes:        keyValues(dp.Attributes.Iter()),
+			StartTimeUnixNano: unixNano(dp.StartTime),
+			TimeUnixNano:      unixNano(dp.Time),
+		}
+		switch v := any(dp.Value).(type) {
+		case int64:
+			pb.Value = &metricpb.NumberDataPoint_AsInt{AsInt: v}
+		case float64:
+			pb.Value = &metricpb.NumberDataPoint_AsDouble{AsDouble: v}
+		}
+		out = append(out, pb)
+	}
+	return out
+}
+
+func temporalityProto(t metricdata.Temporality) metricpb.AggregationTemporality {
+	switch t {
+	case metricdata.DeltaTemporality:
+		return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	case metricdata.CumulativeTemporality:
+		return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	}
+	return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func keyValues(iter attribute.Iterator) []*commonpb.KeyValue {
+	out := make([]*commonpb.KeyValue, 0, iter.Len())
+	for iter.Next() {
+		kv := iter.Attribute()
+		out = append(out, &commonpb.KeyValue{Key: string(kv.Key), Value: anyValue(kv.Value)})
+	}
+	return out
+}
+
+func anyValue(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.STRING:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	}
+	// Slices are not used by the generator; send them as their string form
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Phase is one step of a scenario timeline. While a phase is active the
+// generator starts or stops weighted processes on every host until it runs
+// ProcessesPerHost processes; pinned (count_per_host) processes are never
+// stopped.
+type Phase struct {
+	Name string `yaml:"name"`
+	// Duration of the phase. Zero on the last phase means "until shutdown".
+	Duration         time.Duration `yaml:"duration"`
+	ProcessesPerHost int           `yaml:"processes_per_host"`
+	// Ramp interpolates linearly from the previous phase's population to
+	// ProcessesPerHost over Duration instead of jumping at phase start.
+	Ramp bool `yaml:"ramp"`
+}
+
+func (s *Scenario) validatePhases() error {
+	for i, p := range s.Phases {
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("phase-%d", i+1)
+		}
+		if p.ProcessesPerHost < 0 {
+			return fmt.Errorf("phase %q: processes_per_host must not be negative", p.Name)
+		}
+		if p.Duration < 0 || (p.Duration == 0 && i != len(s.Phases)-1) {
+			return fmt.Errorf("phase %q: only the last phase may omit its duration", p.Name)
+		}
+		if p.Ramp && p.Duration == 0 {
+			return fmt.Errorf("phase %q: a ramp needs a duration", p.Name)
+		}
+	}
+	return nil
+}
+
+// phaseAt returns the index of the phase active elapsed into the run and the
+// processes-per-host target at that moment. Once every phase has
+// elapsed the last phase's target is held. It returns -1 when the scenario
+// has no timeline.
+func (s *Scenario) phaseAt(elapsed time.Duration) (int, int) {
+	if len(s.Phases) == 0 {
+		return -1, s.ProcessesPerHost
+	}
+	previous := s.ProcessesPerHost
+	var start time.Duration
+	for i, p := range s.Phases {
+		end := start + p.Duration
+		if p.Duration == 0 || elapsed < end {
+			if !p.Ramp {
+				return i, p.ProcessesPerHost
+			}
+			progress := float64(elapsed-start) / float64(p.Duration)
+			return i, previous + int(progress*float64(p.ProcessesPerHost-previous))
+		}
+		previous = p.ProcessesPerHost
+		start = end
+	}
+	return len(s.Phases) - 1, previous
+}
+
+// reconcileHostPopulation starts or stops weighted processes on h until it
+// runs perHost processes. Callers must hold activeProcessesMutex.
+func reconcileHostPopulation(scenario *Scenario, h *simHost, perHost int) (added, removed int) {
+	target := perHost - scenario.pinnedPerHost()
+	if target < 0 {
+		target = 0
+	}
+	procs := activeProcesses[h.hostname]
+	var weighted []int
+	for i, p := range procs {
+		if p.archetype.CountPerHost == 0 {
+			weighted = append(weighted, i)
+		}
+	}
+
+	if missing := target - len(weighted); missing > 0 {
+		procs, added = spawnWeightedProcesses(scenario, h, procs, missing)
+	}
+
+	if excess := len(weighted) - target; excess > 0 {
+		rng.Shuffle(len(weighted), func(i, j int) { weighted[i], weighted[j] = weighted[j], weighted[i] })
+		doomed := make(map[int]bool, excess)
+		for _, idx := range weighted[:excess] {
+			doomed[idx] = true
+		}
+		kept := procs[:0]
+		for i, p := range procs {
+			if doomed[i] {
+				stopProcess(p)
+				removed++
+				continue
+			}
+			kept = append(kept, p)
+		}
+		for i := len(kept); i < len(procs); i++ {
+			procs[i] = nil
+		}
+		procs = kept
+	}
+
+	activeProcesses[h.hostname] = procs
+	return added, removed
+}
+
+// spawnWeightedProcesses starts n processes of randomly picked weighted
+// archetypes on h and appends them to procs.
+func spawnWeightedProcesses(scenario *Scenario, h *simHost, procs []*processState, n int) ([]*processState, int) {
+	spawned := 0
+	for ; spawned < n; spawned++ {
+		a := scenario.pickArchetype()
+		if a == nil {
+			break
+		}
+		ps, err := newProcess(h, a, len(procs))
+		if err != nil {
+			log.Printf("WARN (Generator): Failed to spawn %s process on %s: %v", a.ExecName, h.hostname, err)
+			break
+		}
+		procs = append(procs, ps)
+	}
+	return procs, spawned
+}
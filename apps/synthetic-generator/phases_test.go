@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPhaseAt(t *testing.T) {
+	sweep := &Scenario{ProcessesPerHost: 100, Phases: []*Phase{
+		{Name: "steady", Duration: 10 * time.Minute, ProcessesPerHost: 150},
+		{Name: "ramp", Duration: 10 * time.Minute, ProcessesPerHost: 600, Ramp: true},
+		{Name: "burst", Duration: 5 * time.Minute, ProcessesPerHost: 1200},
+		{Name: "settle", ProcessesPerHost: 100},
+	}}
+	// Every phase has a duration; the first ramps from the scenario's size
+	bounded := &Scenario{ProcessesPerHost: 100, Phases: []*Phase{
+		{Name: "shrink", Duration: time.Minute, ProcessesPerHost: 40, Ramp: true},
+	}}
+	tests := []struct {
+		name      string
+		scenario  *Scenario
+		elapsed   time.Duration
+		wantPhase int
+		wantHosts int
+	}{
+		{"start", sweep, 0, 0, 150},
+		{"end of a step", sweep, 10*time.Minute - time.Second, 0, 150},
+		{"ramp start", sweep, 10 * time.Minute, 1, 150},
+		{"ramp middle", sweep, 15 * time.Minute, 1, 375},
+		{"ramp end", sweep, 20*time.Minute - time.Second, 1, 599},
+		{"after a ramp", sweep, 20 * time.Minute, 2, 1200},
+		{"open-ended last phase", sweep, 10 * time.Hour, 3, 100},
+		{"ramp down from the scenario size", bounded, 30 * time.Second, 0, 70},
+		{"after the last phase", bounded, time.Hour, 0, 40},
+		{"no timeline", &Scenario{ProcessesPerHost: 100}, time.Hour, -1, 100},
+	}
+	for _, tt := range tests {
+		phase, perHost := tt.scenario.phaseAt(tt.elapsed)
+		if phase != tt.wantPhase || perHost != tt.wantHosts {
+			t.Errorf("%s: phaseAt(%s) = %d, %d, want %d, %d", tt.name, tt.elapsed, phase, perHost, tt.wantPhase, tt.wantHosts)
+		}
+	}
+}
+
+// reconcileHostPopulation only starts and stops weighted processes; pinned
+// ones survive any target.
+func TestReconcileHostPopulation(t *testing.T) {
+	scenario, err := parseScenario([]byte(`
+archetypes:
+  - {exec_name: postgres_primary, count_per_host: 2}
+  - {exec_name: python_api_worker}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng = rand.New(rand.NewSource(1))
+	meterPool = newProcessMeterPool(nil, TemporalityConfig{})
+	activeProcesses = map[string][]*processState{}
+	t.Cleanup(func() { meterPool, activeProcesses = nil, nil })
+
+	h := &simHost{hostname: "host-1", pidMax: defaultPIDMax}
+	for i, a := range scenario.hostPopulation(2) {
+		ps, err := newProcess(h, a, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		activeProcesses[h.hostname] = append(activeProcesses[h.hostname], ps)
+	}
+
+	tests := []struct {
+		perHost                  int
+		wantAdded, wantGone      int
+		wantWeighted, wantPinned int
+	}{
+		{6, 4, 0, 4, 2},
+		{6, 0, 0, 4, 2},
+		{3, 0, 3, 1, 2},
+		{0, 0, 1, 0, 2},
+		{4, 2, 0, 2, 2},
+	}
+	for _, tt := range tests {
+		added, removed := reconcileHostPopulation(scenario, h, tt.perHost)
+		procs := activeProcesses[h.hostname]
+		weighted, pinned := 0, 0
+		for _, p := range procs {
+			if p.archetype.CountPerHost != 0 {
+				pinned++
+			} else {
+				weighted++
+			}
+		}
+		if added != tt.wantAdded || removed != tt.wantGone || weighted != tt.wantWeighted || pinned != tt.wantPinned {
+			t.Errorf("to %d per host: added %d, removed %d, left %d weighted and %d pinned; want %d, %d, %d and %d",
+				tt.perHost, added, removed, weighted, pinned, tt.wantAdded, tt.wantGone, tt.wantWeighted, tt.wantPinned)
+		}
+		// Stopped processes give their PIDs back
+		if len(h.pidsInUse) != len(procs) {
+			t.Errorf("to %d per host: %d PIDs in use by %d processes", tt.perHost, len(h.pidsInUse), len(procs))
+		}
+	}
+}
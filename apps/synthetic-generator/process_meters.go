@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+const (
+	generatorMeterName = "phoenix.v3.ultimate.synthetic.generator"
+	exportConcurrency  = 8
+	exportTimeout      = 30 * time.Second
+	exportQueueSize    = 4
+	// maxResourcesPerRequest bounds OTLP requests (gRPC receivers accept 4 MiB
+	// by default); larger hosts are split across requests.
+	maxResourcesPerRequest = 500
+)
+
+// Metrics every simulated process reports, one series each.
+const (
+	metricCPUTime        = "process.cpu.time"
+	metricDiskReadBytes  = "process.disk.io.read_bytes"
+	metricDiskWriteBytes = "process.disk.io.write_bytes"
+	metricMemoryUsage    = "process.memory.usage"
+	metricThreads        = "process.threads"
+	metricOpenFDs        = "process.open_file_descriptors"
+)
+
+var processMetricNames = []string{
+	metricCPUTime, metricDiskReadBytes, metricDiskWriteBytes,
+	metricMemoryUsage, metricThreads, metricOpenFDs,
+}
+
+// processMeter owns the SDK pipeline of a single simulated process. Each
+// process gets its own MeterProvider so its data points are exported in their
+// own ResourceMetrics rather than under the generator's resource.
+type processMeter struct {
+	// host, pid and execName order the meter's resource in export requests.
+	host     string
+	pid      int
+	execName string
+	provider *sdkmetric.MeterProvider
+	reader   *sdkmetric.ManualReader
+	// Counters whose temporality differs from the default live on a second
+	// provider; their data is merged into the same ResourceMetrics on collect.
+	altProvider *sdkmetric.MeterProvider
+	altReader   *sdkmetric.ManualReader
+
+	cpuCounter       metric.Float64Counter
+	diskReadCounter  metric.Float64Counter
+	diskWriteCounter metric.Float64Counter
+
+	// Gauge values are snapshotted here by the tick loop so the observable
+	// callbacks never need activeProcessesMutex.
+	mu       sync.Mutex
+	attrs    attribute.Set
+	memUsage float64
+	threads  float64
+	openFDs  float64
+	retired  bool
+}
+
+func newProcessMeter(proc *processState, temporality *TemporalityConfig) (*processMeter, error) {
+	newPipeline := func(selector sdkmetric.TemporalitySelector) (*sdkmetric.MeterProvider, *sdkmetric.ManualReader) {
+		reader := sdkmetric.NewManualReader(sdkmetric.WithTemporalitySelector(selector))
+		provider := sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(reader),
+			sdkmetric.WithResource(proc.otelResource),
+		)
+		return provider, reader
+	}
+	pm := &processMeter{host: proc.hostname, pid: proc.pid, execName: proc.execName}
+	pm.provider, pm.reader = newPipeline(temporality.selector)
+	pm.recordGauges(proc)
+
+	meter := pm.provider.Meter(generatorMeterName)
+	defaultCounterTemporality := temporality.selector(sdkmetric.InstrumentKindCounter)
+	var altMeter metric.Meter
+	createCounter := func(name, description, unit string) (metric.Float64Counter, error) {
+		m := meter
+		if t := temporality.counterTemporality(name); t != defaultCounterTemporality {
+			if altMeter == nil {
+				pm.altProvider, pm.altReader = newPipeline(func(sdkmetric.InstrumentKind) metricdata.Temporality { return t })
+				altMeter = pm.altProvider.Meter(generatorMeterName)
+			}
+			m = altMeter
+		}
+		counter, err := m.Float64Counter(name, metric.WithDescription(description), metric.WithUnit(unit))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s counter: %w", name, err)
+		}
+		return counter, nil
+	}
+	var err error
+	if pm.cpuCounter, err = createCounter(metricCPUTime, "CPU time consumed by the process", "s"); err != nil {
+		return nil, err
+	}
+	if pm.diskReadCounter, err = createCounter(metricDiskReadBytes, "Bytes read from disk by the process", "By"); err != nil {
+		return nil, err
+	}
+	if pm.diskWriteCounter, err = createCounter(metricDiskWriteBytes, "Bytes written to disk by the process", "By"); err != nil {
+		return nil, err
+	}
+
+	memGauge, err := meter.Float64ObservableGauge(metricMemoryUsage,
+		metric.WithDescription("Resident Set Size of the process"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create process.memory.usage gauge: %w", err)
+	}
+	threadsGauge, err := meter.Float64ObservableGauge(metricThreads,
+		metric.WithDescription("Number of threads in the process"), metric.WithUnit("{threads}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create process.threads gauge: %w", err)
+	}
+	fdGauge, err := meter.Float64ObservableGauge(metricOpenFDs,
+		metric.WithDescription("Number of open file descriptors"), metric.WithUnit("{descriptors}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create process.open_file_descriptors gauge: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+		if pm.retired {
+			return nil
+		}
+		o.ObserveFloat64(memGauge, pm.memUsage, metric.WithAttributeSet(pm.attrs))
+		o.ObserveFloat64(threadsGauge, pm.threads, metric.WithAttributeSet(pm.attrs))
+		o.ObserveFloat64(fdGauge, pm.openFDs, metric.WithAttributeSet(pm.attrs))
+		return nil
+	}, memGauge, threadsGauge, fdGauge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register gauge callback: %w", err)
+	}
+	return pm, nil
+}
+
+// collect gathers the process's data from every pipeline into one
+// ResourceMetrics.
+func (pm *processMeter) collect(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	if err := pm.reader.Collect(ctx, rm); err != nil {
+		return err
+	}
+	if pm.altReader == nil {
+		return nil
+	}
+	var alt metricdata.ResourceMetrics
+	if err := pm.altReader.Collect(ctx, &alt); err != nil {
+		return err
+	}
+	for _, sm := range alt.ScopeMetrics {
+		if len(rm.ScopeMetrics) > 0 && rm.ScopeMetrics[0].Scope == sm.Scope {
+			rm.ScopeMetrics[0].Metrics = append(rm.ScopeMetrics[0].Metrics, sm.Metrics...)
+			continue
+		}
+		rm.ScopeMetrics = append(rm.ScopeMetrics, sm)
+	}
+	return nil
+}
+
+func (pm *processMeter) shutdown(ctx context.Context) error {
+	err := pm.provider.Shutdown(ctx)
+	if pm.altProvider != nil {
+		err = errors.Join(err, pm.altProvider.Shutdown(ctx))
+	}
+	return err
+}
+
+// recordGauges snapshots the gauge values of proc for the next collection.
+func (pm *processMeter) recordGauges(proc *processState) {
+	pm.mu.Lock()
+	pm.attrs = proc.metricAttrs
+	pm.memUsage = proc.memUsageBytes
+	pm.threads = proc.threadCount
+	pm.openFDs = proc.openFDCount
+	pm.mu.Unlock()
+}
+
+// processMeterPool tracks the meter of every live process plus the meters of
+// replaced processes awaiting their final export. The tick loop collects all
+// of them right after each tick (Flush) and Run exports the collected batches
+// through a single shared client, one request per host, so exported values
+// always line up with tick boundaries.
+type processMeterPool struct {
+	client      metricsClient
+	temporality TemporalityConfig
+	batches     chan []hostBatch
+
+	mu      sync.Mutex
+	meters  map[*processMeter]struct{}
+	retired []*processMeter
+}
+
+// hostBatch is the collected data of one host's processes.
+type hostBatch struct {
+	host      string
+	resources []*metricdata.ResourceMetrics
+}
+
+func newProcessMeterPool(client metricsClient, temporality TemporalityConfig) *processMeterPool {
+	return &processMeterPool{
+		client:      client,
+		temporality: temporality,
+		batches:     make(chan []hostBatch, exportQueueSize),
+		meters:      make(map[*processMeter]struct{}),
+	}
+}
+
+// Attach creates a meter for proc's current resource. Any meter previously
+// attached to proc is retired: its gauges stop reporting and its counters are
+// exported one last time on the next export round.
+func (p *processMeterPool) Attach(proc *processState) error {
+	pm, err := newProcessMeter(proc, &p.temporality)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if proc.meter != nil {
+		p.retireLocked(proc.meter)
+	}
+	p.meters[pm] = struct{}{}
+	proc.meter = pm
+	return nil
+}
+
+// Detach retires the meter of a process that is going away.
+func (p *processMeterPool) Detach(proc *processState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if proc.meter != nil {
+		p.retireLocked(proc.meter)
+	}
+}
+
+func (p *processMeterPool) retireLocked(pm *processMeter) {
+	pm.mu.Lock()
+	pm.retired = true
+	pm.mu.Unlock()
+	delete(p.meters, pm)
+	p.retired = append(p.retired, pm)
+}
+
+// Flush collects every live and retired meter, records the tick's
+// self-telemetry and queues the result for export. Retired meters are shut
+// down once collected. It blocks while the export queue is full so that a slow
+// backend delays ticks rather than dropping data.
+func (p *processMeterPool) Flush(ctx context.Context) {
+	batch := p.collectAll(ctx)
+	recordCollectedTelemetry(batch)
+	if p.client == nil || len(batch) == 0 {
+		return
+	}
+	select {
+	case p.batches <- batch:
+	case <-ctx.Done():
+	}
+}
+
+// Run exports queued batches until ctx is cancelled.
+func (p *processMeterPool) Run(ctx context.Context) {
+	if p.client == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case batch := <-p.batches:
+			p.exportBatch(ctx, batch)
+		}
+	}
+}
+
+func (p *processMeterPool) collectAll(ctx context.Context) []hostBatch {
+	p.mu.Lock()
+	meters := make([]*processMeter, 0, len(p.meters)+len(p.retired))
+	for pm := range p.meters {
+		meters = append(meters, pm)
+	}
+	retired := p.retired
+	p.retired = nil
+	p.mu.Unlock()
+	// Live meters come out of the map in random order; sort every meter so
+	// each round exports hosts and processes in the same order. A retired
+	// meter follows the live one that replaced it.
+	meters = append(meters, retired...)
+	sort.SliceStable(meters, func(i, j int) bool {
+		a, b := meters[i], meters[j]
+		if a.host != b.host {
+			return a.host < b.host
+		}
+		if a.pid != b.pid {
+			return a.pid < b.pid
+		}
+		return a.execName < b.execName
+	})
+
+	var batch []hostBatch
+	for _, pm := range meters {
+		rm := &metricdata.ResourceMetrics{}
+		if err := pm.collect(ctx, rm); err != nil {
+			log.Printf("WARN (Generator): Failed to collect process metrics: %v", err)
+			continue
+		}
+		if len(rm.ScopeMetrics) == 0 {
+			continue
+		}
+		if len(batch) == 0 || batch[len(batch)-1].host != pm.host {
+			batch = append(batch, hostBatch{host: pm.host})
+		}
+		last := &batch[len(batch)-1]
+		last.resources = append(last.resources, rm)
+	}
+	for _, pm := range retired {
+		if err := pm.shutdown(ctx); err != nil {
+			log.Printf("WARN (Generator): Failed to shut down retired meter provider: %v", err)
+		}
+	}
+	return batch
+}
+
+// exportBatch exports the ResourceMetrics of each host in one request, split
+// at maxResourcesPerRequest.
+func (p *processMeterPool) exportBatch(ctx context.Context, batch []hostBatch) {
+	var requests [][]*metricdata.ResourceMetrics
+	for _, hb := range batch {
+		for rms := hb.resources; len(rms) > 0; {
+			n := min(len(rms), maxResourcesPerRequest)
+			requests = append(requests, rms[:n])
+			rms = rms[n:]
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		failedMu sync.Mutex
+		failed   int
+		lastErr  error
+	)
+	work := make(chan []*metricdata.ResourceMetrics)
+	for i := 0; i < min(exportConcurrency, len(requests)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rms := range work {
+				started := time.Now()
+				err := p.export(ctx, rms)
+				recordExport(started, err)
+				if err != nil {
+					failedMu.Lock()
+					failed++
+					lastErr = err
+					failedMu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, rms := range requests {
+		work <- rms
+	}
+	close(work)
+	wg.Wait()
+
+	if failed > 0 {
+		log.Printf("WARN (Generator): %d of %d export requests failed this round. Last error: %v", failed, len(requests), lastErr)
+	}
+}
+
+func (p *processMeterPool) export(ctx context.Context, rms []*metricdata.ResourceMetrics) error {
+	req, err := exportRequest(rms)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+	return p.client.Export(ctx, req)
+}
+
+// Shutdown exports whatever is still queued plus a final collection, then
+// shuts down every meter provider and the shared client.
+func (p *processMeterPool) Shutdown(ctx context.Context) error {
+	if p.client != nil {
+	drain:
+		for {
+			select {
+			case batch := <-p.batches:
+				p.exportBatch(ctx, batch)
+			default:
+				break drain
+			}
+		}
+		p.exportBatch(ctx, p.collectAll(ctx))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for pm := range p.meters {
+		if err := pm.shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	p.meters = make(map[*processMeter]struct{})
+	if p.client != nil {
+		if err := p.client.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultScenarioYAML is used when no -scenario file is given. It reproduces
+// the workload the generator has always produced.
+//
+//go:embed scenarios/default.yaml
+var defaultScenarioYAML []byte
+
+// Range is a uniform distribution over [Min, Max].
+type Range struct {
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+}
+
+func (r Range) sample() float64 {
+	if r.Max <= r.Min {
+		return r.Min
+	}
+	return r.Min + rng.Float64()*(r.Max-r.Min)
+}
+
+func (r Range) validate(field string) error {
+	if r.Min < 0 || r.Max < r.Min {
+		return fmt.Errorf("%s: invalid range [%g, %g]", field, r.Min, r.Max)
+	}
+	return nil
+}
+
+// Archetype describes one kind of simulated process: its identity, how many
+// of it run per host and how its metrics evolve every tick.
+type Archetype struct {
+	ExecName string `yaml:"exec_name"`
+	// Tier is reported as custom.service.tier_simulated. When empty it is
+	// the tier the priority rules give each process.
+	Tier string `yaml:"tier"`
+	// CountPerHost pins an exact number of processes per host. Archetypes
+	// without a count share the rest of processes_per_host by Weight.
+	CountPerHost int     `yaml:"count_per_host"`
+	Weight       float64 `yaml:"weight"`
+
+	Owners               []string          `yaml:"owners"`
+	ContainerProbability float64           `yaml:"container_probability"`
+	CommandLine          string            `yaml:"command_line"`
+	Attributes           map[string]string `yaml:"attributes"`
+
+	HeavyHitterRatio            float64 `yaml:"heavy_hitter_ratio"`
+	HeavyHitterCPUMultiplier    Range   `yaml:"heavy_hitter_cpu_multiplier"`
+	HeavyHitterDiskIOMultiplier float64 `yaml:"heavy_hitter_disk_io_multiplier"`
+
+	CPUSecondsPerTick   Range   `yaml:"cpu_seconds_per_tick"`
+	CPUMultiplier       Range   `yaml:"cpu_multiplier"`
+	DiskReadKiBPerTick  Range   `yaml:"disk_read_kib_per_tick"`
+	DiskWriteKiBPerTick Range   `yaml:"disk_write_kib_per_tick"`
+	DiskIOMultiplier    float64 `yaml:"disk_io_multiplier"`
+
+	InitialMemoryMiB Range   `yaml:"initial_memory_mib"`
+	MemoryCapMiB     float64 `yaml:"memory_cap_mib"`
+	InitialThreads   Range   `yaml:"initial_threads"`
+	InitialOpenFDs   Range   `yaml:"initial_open_fds"`
+
+	MemLeakProbability float64 `yaml:"mem_leak_probability"`
+	MemLeakMiBPerTick  Range   `yaml:"mem_leak_mib_per_tick"`
+	FDLeakProbability  float64 `yaml:"fd_leak_probability"`
+	FDLeakPerTick      Range   `yaml:"fd_leak_per_tick"`
+	RestartProbability float64 `yaml:"restart_probability"`
+
+	// Lifecycle: ExitProbability is the per-tick chance of a clean exit,
+	// LifetimeTicks bounds how long a process (e.g. a batch job) runs, and a
+	// share CrashLoopProbability of processes dies every
+	// CrashLoopEveryTicks ticks and comes back under a new PID.
+	ExitProbability      float64 `yaml:"exit_probability"`
+	LifetimeTicks        Range   `yaml:"lifetime_ticks"`
+	CrashLoopProbability float64 `yaml:"crash_loop_probability"`
+	CrashLoopEveryTicks  Range   `yaml:"crash_loop_every_ticks"`
+
+	commandLineTmpl *template.Template
+	attributeTmpls  map[string]*template.Template
+}
+
+// baseArchetype holds the values every archetype starts from before the
+// scenario's defaults block and its own fields are applied.
+func baseArchetype() Archetype {
+	return Archetype{
+		Weight:                      1,
+		Owners:                      processOwners,
+		ContainerProbability:        0.7,
+		CommandLine:                 "/opt/app/{{.ExecName}} --config /etc/app/config.yaml --instance {{mod .Index 20}} --pod {{.Pod}} --namespace {{.Namespace}}",
+		HeavyHitterRatio:            0.08,
+		HeavyHitterCPUMultiplier:    Range{Min: 2, Max: 5},
+		HeavyHitterDiskIOMultiplier: 3,
+		CPUSecondsPerTick:           Range{Min: 0.001, Max: 0.701},
+		CPUMultiplier:               Range{Min: 1, Max: 1},
+		DiskReadKiBPerTick:          Range{Min: 0, Max: 155},
+		DiskWriteKiBPerTick:         Range{Min: 0, Max: 77},
+		DiskIOMultiplier:            1,
+		InitialMemoryMiB:            Range{Min: 10, Max: 1088},
+		MemoryCapMiB:                1800,
+		InitialThreads:              Range{Min: 5, Max: 85},
+		InitialOpenFDs:              Range{Min: 10, Max: 310},
+		MemLeakProbability:          0.02,
+		MemLeakMiBPerTick:           Range{Min: 0, Max: 5},
+		FDLeakProbability:           0.01,
+		FDLeakPerTick:               Range{Min: 0, Max: 3},
+		RestartProbability:          0.0005,
+		CrashLoopEveryTicks:         Range{Min: 2, Max: 6},
+	}
+}
+
+// Scenario is a declarative synthetic workload loaded from YAML or JSON.
+type Scenario struct {
+	Name                string
+	Hosts               int
+	ProcessesPerHost    int
+	EmitIntervalSeconds int
+	Archetypes          []*Archetype
+	Phases              []*Phase
+	Explosion           ExplosionConfig
+	Temporality         TemporalityConfig
+	Lifecycle           LifecycleConfig
+
+	totalWeight float64
+}
+
+type scenarioFile struct {
+	Name                string      `yaml:"name"`
+	Hosts               int         `yaml:"hosts"`
+	ProcessesPerHost    int         `yaml:"processes_per_host"`
+	EmitIntervalSeconds int         `yaml:"emit_interval_seconds"`
+	Defaults            yaml.Node   `yaml:"defaults"`
+	Archetypes          []yaml.Node `yaml:"archetypes"`
+	Phases              []*Phase    `yaml:"phases"`
+	Explosion           yaml.Node   `yaml:"explosion"`
+	Temporality         yaml.Node   `yaml:"temporality"`
+	Lifecycle           yaml.Node   `yaml:"lifecycle"`
+}
+
+// loadScenario reads the scenario at path, or the built-in default scenario
+// when path is empty. JSON files are accepted as they are valid YAML.
+func loadScenario(path string) (*Scenario, error) {
+	data := defaultScenarioYAML
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read scenario file: %w", err)
+		}
+	}
+	return parseScenario(data)
+}
+
+func parseScenario(data []byte) (*Scenario, error) {
+	var f scenarioFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario: %w", err)
+	}
+
+	defaults := baseArchetype()
+	if !f.Defaults.IsZero() {
+		if err := decodeStrict(&f.Defaults, &defaults); err != nil {
+			return nil, fmt.Errorf("scenario defaults: %w", err)
+		}
+	}
+
+	s := &Scenario{
+		Name:                f.Name,
+		Hosts:               f.Hosts,
+		ProcessesPerHost:    f.ProcessesPerHost,
+		EmitIntervalSeconds: f.EmitIntervalSeconds,
+		Phases:              f.Phases,
+		Explosion:           defaultExplosionConfig(),
+		Lifecycle:           defaultLifecycleConfig(),
+	}
+	if !f.Explosion.IsZero() {
+		if err := decodeStrict(&f.Explosion, &s.Explosion); err != nil {
+			return nil, fmt.Errorf("scenario explosion: %w", err)
+		}
+	}
+	if !f.Temporality.IsZero() {
+		if err := decodeStrict(&f.Temporality, &s.Temporality); err != nil {
+			return nil, fmt.Errorf("scenario temporality: %w", err)
+		}
+	}
+	if !f.Lifecycle.IsZero() {
+		if err := decodeStrict(&f.Lifecycle, &s.Lifecycle); err != nil {
+			return nil, fmt.Errorf("scenario lifecycle: %w", err)
+		}
+	}
+	for i := range f.Archetypes {
+		// Each archetype is decoded on top of a copy of the defaults so that
+		// only the fields it sets are overridden.
+		a := defaults
+		a.Attributes = make(map[string]string, len(defaults.Attributes))
+		for k, v := range defaults.Attributes {
+			a.Attributes[k] = v
+		}
+		if err := decodeStrict(&f.Archetypes[i], &a); err != nil {
+			return nil, fmt.Errorf("scenario archetype %d: %w", i, err)
+		}
+		s.Archetypes = append(s.Archetypes, &a)
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// decodeStrict decodes node into out, rejecting mapping keys that have no
+// matching yaml tag on out so typos in scenario files are not silently ignored.
+func decodeStrict(node *yaml.Node, out interface{}) error {
+	if node.Kind == yaml.MappingNode {
+		known := make(map[string]bool)
+		t := reflect.TypeOf(out).Elem()
+		for i := 0; i < t.NumField(); i++ {
+			if tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]; tag != "" {
+				known[tag] = true
+			}
+		}
+		for i := 0; i < len(node.Content); i += 2 {
+			if key := node.Content[i].Value; !known[key] {
+				return fmt.Errorf("line %d: unknown field %q", node.Content[i].Line, key)
+			}
+		}
+	}
+	return node.Decode(out)
+}
+
+func (s *Scenario) validate() error {
+	if len(s.Archetypes) == 0 {
+		return fmt.Errorf("scenario %q defines no archetypes", s.Name)
+	}
+	if s.Hosts < 0 || s.ProcessesPerHost < 0 || s.EmitIntervalSeconds < 0 {
+		return fmt.Errorf("scenario %q: hosts, processes_per_host and emit_interval_seconds must not be negative", s.Name)
+	}
+	for _, a := range s.Archetypes {
+		if err := a.compile(); err != nil {
+			return fmt.Errorf("archetype %q: %w", a.ExecName, err)
+		}
+		if a.CountPerHost == 0 {
+			s.totalWeight += a.Weight
+		}
+	}
+	if err := s.Explosion.validate(); err != nil {
+		return err
+	}
+	if err := s.Temporality.validate(); err != nil {
+		return err
+	}
+	if err := s.Lifecycle.validate(); err != nil {
+		return err
+	}
+	return s.validatePhases()
+}
+
+// compile validates the archetype and parses its templates.
+func (a *Archetype) compile() error {
+	if a.ExecName == "" {
+		return fmt.Errorf("exec_name is required")
+	}
+	if a.CountPerHost < 0 || a.Weight < 0 {
+		return fmt.Errorf("count_per_host and weight must not be negative")
+	}
+	if len(a.Owners) == 0 {
+		return fmt.Errorf("owners must not be empty")
+	}
+	probabilities := map[string]float64{
+		"container_probability":  a.ContainerProbability,
+		"heavy_hitter_ratio":     a.HeavyHitterRatio,
+		"mem_leak_probability":   a.MemLeakProbability,
+		"fd_leak_probability":    a.FDLeakProbability,
+		"restart_probability":    a.RestartProbability,
+		"exit_probability":       a.ExitProbability,
+		"crash_loop_probability": a.CrashLoopProbability,
+	}
+	for field, p := range probabilities {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("%s must be within [0, 1], got %g", field, p)
+		}
+	}
+	ranges := map[string]Range{
+		"heavy_hitter_cpu_multiplier": a.HeavyHitterCPUMultiplier,
+		"cpu_seconds_per_tick":        a.CPUSecondsPerTick,
+		"cpu_multiplier":              a.CPUMultiplier,
+		"disk_read_kib_per_tick":      a.DiskReadKiBPerTick,
+		"disk_write_kib_per_tick":     a.DiskWriteKiBPerTick,
+		"initial_memory_mib":          a.InitialMemoryMiB,
+		"initial_threads":             a.InitialThreads,
+		"initial_open_fds":            a.InitialOpenFDs,
+		"mem_leak_mib_per_tick":       a.MemLeakMiBPerTick,
+		"fd_leak_per_tick":            a.FDLeakPerTick,
+		"lifetime_ticks":              a.LifetimeTicks,
+		"crash_loop_every_ticks":      a.CrashLoopEveryTicks,
+	}
+	for field, r := range ranges {
+		if err := r.validate(field); err != nil {
+			return err
+		}
+	}
+	if a.MemoryCapMiB <= 0 {
+		return fmt.Errorf("memory_cap_mib must be positive")
+	}
+	var err error
+	if a.commandLineTmpl, err = parseAttributeTemplate("command_line", a.CommandLine); err != nil {
+		return err
+	}
+	a.attributeTmpls = make(map[string]*template.Template, len(a.Attributes))
+	for key, text := range a.Attributes {
+		if a.attributeTmpls[key], err = parseAttributeTemplate(key, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var templateFuncs = template.FuncMap{
+	"add": func(a, b int) int { return a + b },
+	"mod": func(a, b int) int { return a % b },
+	"div": func(a, b int) int { return a / b },
+}
+
+func parseAttributeTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return t, nil
+}
+
+// templateData is the value archetype templates are rendered against.
+type templateData struct {
+	ExecName  string
+	AppName   string
+	Host      string
+	Node      string
+	Namespace string
+	Pod       string
+	Index     int
+	PID       int
+	HeapMB    int
+}
+
+func renderTemplate(t *template.Template, data templateData) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// applyEnvDefaults fills the sizing fields the scenario leaves unset.
+func (s *Scenario) applyEnvDefaults(hostCount, processCountPerHost, metricRateS int) {
+	if s.Hosts == 0 {
+		s.Hosts = hostCount
+	}
+	if s.ProcessesPerHost == 0 {
+		s.ProcessesPerHost = processCountPerHost
+	}
+	if s.EmitIntervalSeconds == 0 {
+		s.EmitIntervalSeconds = metricRateS
+	}
+}
+
+// pickArchetype draws one of the weighted (non-pinned) archetypes.
+func (s *Scenario) pickArchetype() *Archetype {
+	if s.totalWeight <= 0 {
+		return nil
+	}
+	r := rng.Float64() * s.totalWeight
+	var last *Archetype
+	for _, a := range s.Archetypes {
+		if a.CountPerHost != 0 || a.Weight == 0 {
+			continue
+		}
+		last = a
+		if r < a.Weight {
+			return a
+		}
+		r -= a.Weight
+	}
+	return last
+}
+
+// pinnedPerHost is the number of count_per_host processes on every host.
+func (s *Scenario) pinnedPerHost() int {
+	pinned := 0
+	for _, a := range s.Archetypes {
+		pinned += a.CountPerHost
+	}
+	return pinned
+}
+
+// hostPopulation returns the archetype of every process initially started on
+// a host: all pinned counts first, then weighted draws up to perHost.
+func (s *Scenario) hostPopulation(perHost int) []*Archetype {
+	var population []*Archetype
+	for _, a := range s.Archetypes {
+		for i := 0; i < a.CountPerHost; i++ {
+			population = append(population, a)
+		}
+	}
+	for len(population) < perHost {
+		a := s.pickArchetype()
+		if a == nil {
+			break
+		}
+		population = append(population, a)
+	}
+	return population
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The built-in scenario and the ones shipped in configs must always load.
+func TestShippedScenarios(t *testing.T) {
+	paths, err := filepath.Glob("../../configs/generator/scenarios/*.yaml")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no scenarios found: %v", err)
+	}
+	for _, path := range append([]string{""}, paths...) {
+		s, err := loadScenario(path)
+		if err != nil {
+			t.Errorf("%q: %v", path, err)
+			continue
+		}
+		if len(s.Archetypes) == 0 {
+			t.Errorf("%q: no archetypes", path)
+		}
+	}
+}
+
+func TestParseScenarioErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr string
+	}{
+		{"not a mapping", "- a\n- b\n", "failed to parse scenario"},
+		{"unknown top-level field", "hostz: 3\narchetypes: [{exec_name: a}]\n", "field hostz not found"},
+		{"unknown archetype field", "archetypes: [{exec_nmae: a}]\n", `archetype 0: line 1: unknown field "exec_nmae"`},
+		{"unknown defaults field", "defaults: {wieght: 2}\narchetypes: [{exec_name: a}]\n", `defaults: line 1: unknown field "wieght"`},
+		{"unknown explosion field", "archetypes: [{exec_name: a}]\nexplosion: {rate: 5}\n", `explosion: line 2: unknown field "rate"`},
+		{"unknown lifecycle field", "archetypes: [{exec_name: a}]\nlifecycle: {pidmax: 5}\n", `lifecycle: line 2: unknown field "pidmax"`},
+		{"no archetypes", "name: empty\n", `scenario "empty" defines no archetypes`},
+		{"negative hosts", "hosts: -1\narchetypes: [{exec_name: a}]\n", "must not be negative"},
+		{"no exec_name", "archetypes: [{weight: 2}]\n", "exec_name is required"},
+		{"negative weight", "archetypes: [{exec_name: a, weight: -1}]\n", "count_per_host and weight must not be negative"},
+		{"no owners", "archetypes: [{exec_name: a, owners: []}]\n", "owners must not be empty"},
+		{"probability above 1", "archetypes: [{exec_name: a, container_probability: 1.5}]\n", "container_probability must be within [0, 1], got 1.5"},
+		{"inverted range", "archetypes: [{exec_name: a, cpu_multiplier: {min: 5, max: 2}}]\n", "cpu_multiplier: invalid range [5, 2]"},
+		{"negative range", "archetypes: [{exec_name: a, initial_threads: {min: -1, max: 2}}]\n", "initial_threads: invalid range [-1, 2]"},
+		{"no memory cap", "archetypes: [{exec_name: a, memory_cap_mib: 0}]\n", "memory_cap_mib must be positive"},
+		{"bad command line template", "archetypes: [{exec_name: a, command_line: '{{.Index'}]\n", "invalid command_line template"},
+		{"bad attribute template", "archetypes: [{exec_name: a, attributes: {svc: '{{nope}}'}}]\n", "invalid svc template"},
+		{"explosion rate", "archetypes: [{exec_name: a}]\nexplosion: {new_series_per_second: 0}\n", "new_series_per_second must be positive"},
+		{"open-ended middle phase", "archetypes: [{exec_name: a}]\nphases: [{processes_per_host: 5}, {duration: 1m}]\n", `phase "phase-1": only the last phase may omit its duration`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseScenario([]byte(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseScenario() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Archetypes start from the base values, then the defaults block, then their
+// own fields; attribute maps are copied, not shared.
+func TestScenarioDefaults(t *testing.T) {
+	s, err := parseScenario([]byte(`
+defaults:
+  container_probability: 0.2
+  attributes: {team: core}
+archetypes:
+  - exec_name: a
+    container_probability: 0.9
+    attributes: {svc: "{{.AppName}}"}
+  - exec_name: b
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := s.Archetypes[0], s.Archetypes[1]
+	if a.ContainerProbability != 0.9 || b.ContainerProbability != 0.2 {
+		t.Errorf("container_probability = %g, %g, want 0.9 from a and 0.2 from the defaults", a.ContainerProbability, b.ContainerProbability)
+	}
+	if len(a.Attributes) != 2 || len(b.Attributes) != 1 || b.Attributes["team"] != "core" {
+		t.Errorf("attributes = %v, %v, want a to add svc to the defaults' team", a.Attributes, b.Attributes)
+	}
+	if b.MemoryCapMiB != baseArchetype().MemoryCapMiB || b.Weight != 1 {
+		t.Errorf("b = %+v, want the base values", b)
+	}
+	if s.Explosion.NewSeriesPerSecond != defaultExplosionConfig().NewSeriesPerSecond || s.Lifecycle != defaultLifecycleConfig() {
+		t.Errorf("explosion %+v, lifecycle %+v, want the defaults", s.Explosion, s.Lifecycle)
+	}
+}
+
+func TestTemplateMath(t *testing.T) {
+	data := templateData{ExecName: "java_app", AppName: "app", Namespace: "prod", Index: 105, HeapMB: 1025}
+	tests := []struct {
+		text    string
+		want    string
+		wantErr string
+	}{
+		{"-Xms{{div .HeapMB 2}}m -Xmx{{.HeapMB}}m", "-Xms512m -Xmx1025m", ""},
+		{"--server.port={{add 8000 (mod .Index 100)}}", "--server.port=8005", ""},
+		{"/opt/{{.ExecName}} --ns {{.Namespace}}", "/opt/java_app --ns prod", ""},
+		{"{{.Region}}", "", "can't evaluate field Region"},
+		{"{{div .Index 0}}", "", "divide by zero"},
+	}
+	for _, tt := range tests {
+		tmpl, err := parseAttributeTemplate("command_line", tt.text)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.text, err)
+		}
+		got, err := renderTemplate(tmpl, data)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%q: error = %v, want %q", tt.text, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q = %q (%v), want %q", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+// Weighted draws follow the weights and never pick pinned or zero-weight
+// archetypes.
+func TestPickArchetype(t *testing.T) {
+	s, err := parseScenario([]byte(`
+archetypes:
+  - {exec_name: light}
+  - {exec_name: heavy, weight: 3}
+  - {exec_name: pinned, count_per_host: 2, weight: 50}
+  - {exec_name: off, weight: 0}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng = rand.New(rand.NewSource(1))
+	const draws = 40000
+	counts := map[string]int{}
+	for i := 0; i < draws; i++ {
+		counts[s.pickArchetype().ExecName]++
+	}
+	if len(counts) != 2 {
+		t.Fatalf("picked %v, want light and heavy only", counts)
+	}
+	if share := float64(counts["heavy"]) / draws; math.Abs(share-0.75) > 0.01 {
+		t.Errorf("heavy picked %.3f of the time, want 0.75", share)
+	}
+
+	population := s.hostPopulation(6)
+	if len(population) != 6 || population[0].ExecName != "pinned" || population[1].ExecName != "pinned" {
+		t.Errorf("population starts with %s, %s of %d, want both pinned processes first", population[0].ExecName, population[1].ExecName, len(population))
+	}
+
+	pinnedOnly, err := parseScenario([]byte("archetypes: [{exec_name: pinned, count_per_host: 3}]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := pinnedOnly.pickArchetype(); a != nil {
+		t.Errorf("pickArchetype() = %s without weighted archetypes, want nil", a.ExecName)
+	}
+	if got := len(pinnedOnly.hostPopulation(10)); got != 3 {
+		t.Errorf("population of %d, want the 3 pinned processes", got)
+	}
+}
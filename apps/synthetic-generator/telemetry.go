@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// Generator self-telemetry, served in Prometheus/OpenMetrics format at
+// /metrics on the admin listener. These series are the ground truth for what
+// the generator feeds into the pipeline.
+var (
+	selfMetricsRegistry = prometheus.NewRegistry()
+
+	ticksTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "synthetic_generator_ticks_total",
+		Help: "Number of completed emission ticks.",
+	})
+	tickEmittedPoints = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "synthetic_generator_tick_emitted_points",
+		Help: "Data points collected for export in the latest tick.",
+	})
+	emittedPointsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "synthetic_generator_emitted_points_total",
+		Help: "Data points collected for export since startup.",
+	})
+	activeSeries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "synthetic_generator_active_series",
+		Help: "Distinct series collected in the latest tick, by OTel metric name.",
+	}, []string{"metric"})
+	processesByTier = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "synthetic_generator_processes",
+		Help: "Simulated processes currently running, by simulated service tier.",
+	}, []string{"tier"})
+	leakingProcesses = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "synthetic_generator_leaking_processes",
+		Help: "Simulated processes currently leaking, by resource.",
+	}, []string{"resource"})
+	processRestartsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synthetic_generator_process_restarts_total",
+		Help: "Simulated in-place process restarts, by archetype.",
+	}, []string{"exec_name"})
+	processSpawnsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synthetic_generator_process_spawns_total",
+		Help: "Simulated processes started, by archetype.",
+	}, []string{"exec_name"})
+	processExitsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synthetic_generator_process_exits_total",
+		Help: "Simulated process exits, by archetype and reason (exit, completed, crash).",
+	}, []string{"exec_name", "reason"})
+	explosionActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "synthetic_generator_explosion_active",
+		Help: "1 while a cardinality explosion is being injected.",
+	})
+	exportRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synthetic_generator_export_requests_total",
+		Help: "OTLP export requests (one per host), by result.",
+	}, []string{"result"})
+	exportDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "synthetic_generator_export_duration_seconds",
+		Help:    "Latency of individual OTLP export requests.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+	})
+)
+
+func init() {
+	selfMetricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ticksTotal, tickEmittedPoints, emittedPointsTotal, activeSeries,
+		processesByTier, leakingProcesses, processRestartsTotal, processSpawnsTotal,
+		processExitsTotal, explosionActive,
+		exportRequestsTotal, exportDuration,
+	)
+	// Expose both results from the start so failure rates are never "no data"
+	exportRequestsTotal.WithLabelValues("success")
+	exportRequestsTotal.WithLabelValues("failure")
+	leakingProcesses.WithLabelValues("memory")
+	leakingProcesses.WithLabelValues("fds")
+}
+
+func selfMetricsHandler() http.Handler {
+	return promhttp.HandlerFor(selfMetricsRegistry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// recordPopulationTelemetry refreshes the population gauges from
+// activeProcesses. Callers must hold activeProcessesMutex.
+func recordPopulationTelemetry() {
+	tiers := make(map[string]int)
+	var memLeaks, fdLeaks int
+	for _, host := range simHosts {
+		for _, proc := range activeProcesses[host.hostname] {
+			tiers[proc.tier]++
+			if proc.memLeakRateBytesPerTick > 0 {
+				memLeaks++
+			}
+			if proc.fdLeakRatePerTick > 0 {
+				fdLeaks++
+			}
+		}
+	}
+	processesByTier.Reset()
+	for tier, n := range tiers {
+		processesByTier.WithLabelValues(tier).Set(float64(n))
+	}
+	leakingProcesses.WithLabelValues("memory").Set(float64(memLeaks))
+	leakingProcesses.WithLabelValues("fds").Set(float64(fdLeaks))
+}
+
+// recordCollectedTelemetry counts the series and data points in one tick's
+// collected batch.
+func recordCollectedTelemetry(batch []hostBatch) {
+	perMetric := make(map[string]int)
+	total := 0
+	for _, hb := range batch {
+		for _, rm := range hb.resources {
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					n := dataPointCount(m.Data)
+					perMetric[m.Name] += n
+					total += n
+				}
+			}
+		}
+	}
+	ticksTotal.Inc()
+	tickEmittedPoints.Set(float64(total))
+	emittedPointsTotal.Add(float64(total))
+	activeSeries.Reset()
+	for name, n := range perMetric {
+		activeSeries.WithLabelValues(name).Set(float64(n))
+	}
+}
+
+func dataPointCount(data metricdata.Aggregation) int {
+	switch d := data.(type) {
+	case metricdata.Sum[float64]:
+		return len(d.DataPoints)
+	case metricdata.Sum[int64]:
+		return len(d.DataPoints)
+	case metricdata.Gauge[float64]:
+		return len(d.DataPoints)
+	case metricdata.Gauge[int64]:
+		return len(d.DataPoints)
+	case metricdata.Histogram[float64]:
+		return len(d.DataPoints)
+	case metricdata.Histogram[int64]:
+		return len(d.DataPoints)
+	}
+	return 0
+}
+
+func recordExport(started time.Time, err error) {
+	exportDuration.Observe(time.Since(started).Seconds())
+	if err != nil {
+		exportRequestsTotal.WithLabelValues("failure").Inc()
+		return
+	}
+	exportRequestsTotal.WithLabelValues("success").Inc()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// Temporality preferences, named as in OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE.
+const (
+	temporalityCumulative = "cumulative"
+	temporalityDelta      = "delta"
+	temporalityLowMemory  = "lowmemory"
+)
+
+// TemporalityConfig selects the aggregation temporality of the generator's
+// counters. Gauges have no temporality and are unaffected.
+type TemporalityConfig struct {
+	// Default is cumulative, delta or lowmemory. Empty falls back to
+	// OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE, then cumulative.
+	Default string `yaml:"default"`
+	// Instruments overrides the temporality (cumulative or delta) of
+	// individual counters by metric name, e.g. process.cpu.time: delta.
+	Instruments map[string]string `yaml:"instruments"`
+}
+
+func (c *TemporalityConfig) validate() error {
+	switch strings.ToLower(c.Default) {
+	case "", temporalityCumulative, temporalityDelta, temporalityLowMemory:
+	default:
+		return fmt.Errorf("temporality: unknown default %q (supported: cumulative, delta, lowmemory)", c.Default)
+	}
+	for name, t := range c.Instruments {
+		switch name {
+		case metricCPUTime, metricDiskReadBytes, metricDiskWriteBytes:
+		default:
+			return fmt.Errorf("temporality: %q is not a counter of the generator", name)
+		}
+		switch strings.ToLower(t) {
+		case temporalityCumulative, temporalityDelta:
+		default:
+			return fmt.Errorf("temporality: %s must be cumulative or delta, got %q", name, t)
+		}
+	}
+	return nil
+}
+
+// applyEnvDefault uses the standard temporality preference when the scenario
+// sets no default.
+func (c *TemporalityConfig) applyEnvDefault(preference string) error {
+	if c.Default != "" || preference == "" {
+		return nil
+	}
+	switch strings.ToLower(preference) {
+	case temporalityCumulative, temporalityDelta, temporalityLowMemory:
+		c.Default = preference
+		return nil
+	}
+	return fmt.Errorf("unsupported OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE %q (supported: cumulative, delta, lowmemory)", preference)
+}
+
+// selector is the default temporality per instrument kind, following the
+// OTLP exporter specification's preference tables.
+func (c *TemporalityConfig) selector(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	switch strings.ToLower(c.Default) {
+	case temporalityDelta:
+		switch kind {
+		case sdkmetric.InstrumentKindUpDownCounter, sdkmetric.InstrumentKindObservableUpDownCounter:
+			return metricdata.CumulativeTemporality
+		}
+		return metricdata.DeltaTemporality
+	case temporalityLowMemory:
+		switch kind {
+		case sdkmetric.InstrumentKindCounter, sdkmetric.InstrumentKindHistogram:
+			return metricdata.DeltaTemporality
+		}
+	}
+	return metricdata.CumulativeTemporality
+}
+
+// counterTemporality is the temporality the named counter is exported with.
+func (c *TemporalityConfig) counterTemporality(name string) metricdata.Temporality {
+	switch strings.ToLower(c.Instruments[name]) {
+	case temporalityCumulative:
+		return metricdata.CumulativeTemporality
+	case temporalityDelta:
+		return metricdata.DeltaTemporality
+	}
+	return c.selector(sdkmetric.InstrumentKindCounter)
+}
+
+func (c *TemporalityConfig) String() string {
+	def := strings.ToLower(c.Default)
+	if def == "" {
+		def = temporalityCumulative
+	}
+	if len(c.Instruments) == 0 {
+		return def
+	}
+	names := make([]string, 0, len(c.Instruments))
+	for name := range c.Instruments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	overrides := make([]string, 0, len(names))
+	for _, name := range names {
+		overrides = append(overrides, name+"="+strings.ToLower(c.Instruments[name]))
+	}
+	return fmt.Sprintf("%s (overrides: %s)", def, strings.Join(overrides, ", "))
+}
@@ -0,0 +1,135 @@
+// Command controlfile validates optimization_mode.yaml files and migrates
+// older shapes to the current schema.
+//
+//	controlfile validate [-prev FILE] FILE...
+//	controlfile migrate [-template FILE] [-o OUT | -w] FILE
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"phoenix-vnext/pkg/controlfile"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "validate":
+		os.Exit(validate(os.Args[2:]))
+	case "migrate":
+		os.Exit(migrate(os.Args[2:]))
+	case "-h", "-help", "--help", "help":
+		usage()
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: controlfile validate [-prev FILE] FILE...")
+	fmt.Fprintln(os.Stderr, "       controlfile migrate [-template FILE] [-o OUT | -w] FILE")
+	fmt.Fprintf(os.Stderr, "\nvalidate checks files against schema version %d (older files are upgraded first);\n", controlfile.SchemaVersion)
+	fmt.Fprintln(os.Stderr, "-prev also requires config_version to have increased on the given file.")
+	fmt.Fprintln(os.Stderr, "migrate upgrades FILE to the current schema, on top of -template to keep its comments.")
+}
+
+func validate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	prevPath := fs.String("prev", "", "Previous control file; config_version must increase on it")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		usage()
+		return 2
+	}
+	var prev *controlfile.File
+	if *prevPath != "" {
+		var err error
+		if prev, _, err = controlfile.Read(*prevPath); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *prevPath, err)
+			return 1
+		}
+	}
+
+	rc := 0
+	for _, path := range fs.Args() {
+		f, version, err := controlfile.Read(path)
+		if err == nil {
+			err = controlfile.Validate(f, prev)
+		}
+		var verr *controlfile.ValidationError
+		switch {
+		case errors.As(err, &verr):
+			fmt.Printf("%s: INVALID\n", path)
+			for _, p := range verr.Problems {
+				fmt.Printf("  - %s\n", p)
+			}
+			rc = 1
+		case err != nil:
+			fmt.Printf("%s: INVALID\n  - %v\n", path, err)
+			rc = 1
+		case version < controlfile.SchemaVersion:
+			fmt.Printf("%s: OK after migration from schema version %d (run controlfile migrate)\n", path, version)
+		default:
+			fmt.Printf("%s: OK (schema version %d)\n", path, version)
+		}
+	}
+	return rc
+}
+
+func migrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	templatePath := fs.String("template", "", "Template to render onto, keeping its comments (e.g. configs/control/optimization_mode_template.yaml)")
+	out := fs.String("o", "", "Write the migrated file here instead of stdout")
+	inPlace := fs.Bool("w", false, "Replace FILE with the migrated file")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 || (*inPlace && *out != "") {
+		usage()
+		return 2
+	}
+	path := fs.Arg(0)
+	if *inPlace {
+		*out = path
+	}
+
+	f, version, err := controlfile.Read(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return 1
+	}
+	if *out != "" {
+		if err := controlfile.Write(*out, *templatePath, f); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "%s: schema version %d -> %d, written to %s\n", path, version, controlfile.SchemaVersion, *out)
+		return 0
+	}
+	if err := controlfile.Validate(f, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return 1
+	}
+	data, err := controlfile.Marshal(f, *templatePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return 1
+	}
+	os.Stdout.Write(data)
+	return 0
+}
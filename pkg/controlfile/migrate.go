@@ -0,0 +1,151 @@
+package controlfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// V1 is the original control file shape (schema version 1), as documented in
+// the first architecture docs.
+type V1 struct {
+	CurrentMode     string `yaml:"current_mode"`
+	ConfigVersion   int    `yaml:"config_version"`
+	CorrelationID   string `yaml:"correlation_id"`
+	LastUpdated     string `yaml:"last_updated"`
+	TriggerReason   string `yaml:"trigger_reason"`
+	PipelineEnables struct {
+		FullFidelity     *bool `yaml:"full_fidelity"`
+		Optimized        *bool `yaml:"optimized"`
+		ExperimentalTopK *bool `yaml:"experimental_topk"`
+	} `yaml:"pipeline_enables"`
+	AdvancedParameters *struct {
+		TargetKValueForExperimentalTopK int      `yaml:"target_k_value_for_experimental_topk"`
+		OptimisedPipelineKeepAttributes []string `yaml:"optimised_pipeline_keep_attributes"`
+	} `yaml:"advanced_phoenix_parameters"`
+}
+
+// templateTopK is target_k_value_for_experimental_topk in the control file
+// template, given to version 1 files that left it unset.
+const templateTopK = 20
+
+// Upgrade converts a version 1 file to the current schema. Fields version 1
+// did not have take their Default values.
+func (v *V1) Upgrade() *File {
+	f := Default()
+	f.OptimizationProfile = v.CurrentMode
+	f.ConfigVersion = v.ConfigVersion
+	if v.CorrelationID != "" {
+		f.CorrelationID = v.CorrelationID
+	}
+	if v.LastUpdated != "" {
+		f.LastUpdated = v.LastUpdated
+	}
+	f.TriggerReason = "migrated from schema version 1"
+	if v.TriggerReason != "" {
+		f.TriggerReason = v.TriggerReason
+	}
+	f.Pipelines.ExperimentalEnabled = v.CurrentMode == ProfileAggressive
+	if p := v.PipelineEnables.FullFidelity; p != nil {
+		f.Pipelines.FullFidelityEnabled = *p
+	}
+	if p := v.PipelineEnables.Optimized; p != nil {
+		f.Pipelines.OptimizedEnabled = *p
+	}
+	if p := v.PipelineEnables.ExperimentalTopK; p != nil {
+		f.Pipelines.ExperimentalEnabled = *p
+	}
+	if a := v.AdvancedParameters; a != nil {
+		k := a.TargetKValueForExperimentalTopK
+		if k == 0 {
+			k = templateTopK
+		}
+		f.AdvancedParameters = &AdvancedParameters{
+			ControlOutput:                    0.5,
+			TargetKValueForExperimentalTopK:  k,
+			AttributeStrippingIntensityLevel: "medium",
+		}
+		// Version 1 had one list for the optimised pipeline, at no particular
+		// level; it becomes the keep list of every profile at both levels
+		if keep := a.OptimisedPipelineKeepAttributes; len(keep) > 0 {
+			f.AttributeKeepLists = map[string]KeepList{}
+			for _, profile := range []string{ProfileConservative, ProfileBalanced, ProfileAggressive} {
+				f.AttributeKeepLists[profile] = KeepList{Resource: keep, DataPoint: keep}
+			}
+		}
+	}
+	return f
+}
+
+// Parse decodes a control file of any schema version and upgrades it to the
+// current one; version is the version it was written in. Files without
+// schema_version are version 1 if they have current_mode and version 2
+// otherwise. Fields a file omits take their Default values.
+func Parse(data []byte) (f *File, version int, err error) {
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, 0, fmt.Errorf("not a YAML mapping: %w", err)
+	}
+	if raw == nil {
+		return nil, 0, fmt.Errorf("file is empty")
+	}
+	switch node, ok := raw["schema_version"]; {
+	case ok:
+		if err := node.Decode(&version); err != nil {
+			return nil, 0, fmt.Errorf("invalid schema_version: %w", err)
+		}
+	case hasKey(raw, "current_mode"):
+		version = 1
+	default:
+		version = 2
+	}
+
+	switch version {
+	case 1:
+		var v1 V1
+		if err := yaml.Unmarshal(data, &v1); err != nil {
+			return nil, version, fmt.Errorf("invalid schema version 1 file: %w", err)
+		}
+		if v1.CurrentMode == "" {
+			return nil, version, fmt.Errorf("file has no current_mode")
+		}
+		f = v1.Upgrade()
+	case 2:
+		if !hasKey(raw, "optimization_profile") {
+			return nil, version, fmt.Errorf("file has no optimization_profile")
+		}
+		f = Default()
+		if err := yaml.Unmarshal(data, f); err != nil {
+			return nil, version, fmt.Errorf("invalid schema version 2 file: %w", err)
+		}
+		f.SchemaVersion = SchemaVersion
+	default:
+		return nil, version, fmt.Errorf("unsupported schema_version %d (this reader supports up to %d)", version, SchemaVersion)
+	}
+	return f, version, nil
+}
+
+// Read loads and upgrades the control file at path. A missing file returns
+// an error wrapping fs.ErrNotExist.
+func Read(path string) (f *File, version int, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, err
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read control file: %w", err)
+	}
+	f, version, err = Parse(data)
+	if err != nil {
+		return nil, version, fmt.Errorf("control file %s is malformed: %w", path, err)
+	}
+	return f, version, nil
+}
+
+func hasKey(m map[string]yaml.Node, key string) bool {
+	_, ok := m[key]
+	return ok
+}
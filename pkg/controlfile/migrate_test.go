@@ -0,0 +1,109 @@
+package controlfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseV1(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		wantK    int // 0 = no advanced_phoenix_parameters
+		wantKeep []string
+		wantExp  bool
+	}{
+		{
+			name: "K unset",
+			in: `current_mode: balanced
+config_version: 3
+advanced_phoenix_parameters:
+  optimised_pipeline_keep_attributes: [host.name]
+`,
+			wantK:    templateTopK,
+			wantKeep: []string{"host.name"},
+		},
+		{
+			name: "K set",
+			in: `current_mode: aggressive
+advanced_phoenix_parameters:
+  target_k_value_for_experimental_topk: 7
+`,
+			wantK:   7,
+			wantExp: true,
+		},
+		{
+			name: "no advanced parameters",
+			in: `current_mode: conservative
+pipeline_enables: {experimental_topk: true}
+`,
+			wantExp: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, version, err := Parse([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if version != 1 || f.SchemaVersion != SchemaVersion {
+				t.Errorf("Parse() version %d, schema_version %d, want 1 upgraded to %d", version, f.SchemaVersion, SchemaVersion)
+			}
+			if f.Pipelines.ExperimentalEnabled != tt.wantExp {
+				t.Errorf("experimental_enabled = %v, want %v", f.Pipelines.ExperimentalEnabled, tt.wantExp)
+			}
+			a := f.AdvancedParameters
+			if tt.wantK == 0 {
+				if a != nil {
+					t.Errorf("advanced_phoenix_parameters = %+v, want none", a)
+				}
+			} else {
+				if a == nil || a.TargetKValueForExperimentalTopK != tt.wantK {
+					t.Fatalf("advanced_phoenix_parameters = %+v, want K %d", a, tt.wantK)
+				}
+			}
+			// The optimised pipeline's list applies under every profile
+			for _, profile := range []string{ProfileConservative, ProfileBalanced, ProfileAggressive} {
+				keep := f.AttributeKeepLists[profile]
+				if strings.Join(keep.Resource, ",") != strings.Join(tt.wantKeep, ",") || strings.Join(keep.DataPoint, ",") != strings.Join(tt.wantKeep, ",") {
+					t.Errorf("%s keep list = %+v, want %v at both levels", profile, keep, tt.wantKeep)
+				}
+			}
+			// An upgraded file must pass the checks a written one does
+			if err := Validate(f, nil); err != nil {
+				t.Errorf("upgraded file is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseVersions(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		wantVersion int
+		wantErr     string
+	}{
+		{"explicit current", "schema_version: 2\noptimization_profile: balanced\n", 2, ""},
+		{"implicit 2", "optimization_profile: balanced\n", 2, ""},
+		{"implicit 1", "current_mode: balanced\n", 1, ""},
+		{"explicit 1 without mode", "schema_version: 1\nconfig_version: 1\n", 1, "no current_mode"},
+		{"2 without profile", "schema_version: 2\nconfig_version: 1\n", 2, "no optimization_profile"},
+		{"future", "schema_version: 9\n", 9, "unsupported schema_version 9"},
+		{"empty", "", 0, "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, version, err := Parse([]byte(tt.in))
+			if version != tt.wantVersion {
+				t.Errorf("Parse() version = %d, want %d", version, tt.wantVersion)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Parse() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Package controlfile defines the schema of optimization_mode.yaml, the
+// control file the control-actuator writes and otelcol-main reads. It reads
+// every shape the file has had (upgrading older ones), validates files and
+// writes them atomically on top of the commented template.
+package controlfile
+
+import "time"
+
+// SchemaVersion is the current schema_version. Version 1 is the original
+// current_mode/pipeline_enables shape; version 2 introduced
+// optimization_profile and pipelines.
+const SchemaVersion = 2
+
+// Optimisation profiles, from least to most aggressive.
+const (
+	ProfileConservative = "conservative"
+	ProfileBalanced     = "balanced"
+	ProfileAggressive   = "aggressive"
+)
+
+// EpochTimestamp is the "never" value of the timestamp fields.
+const EpochTimestamp = "1970-01-01T00:00:00Z"
+
+// File is the current (version 2) control file.
+type File struct {
+	SchemaVersion              int            `yaml:"schema_version"`
+	OptimizationProfile        string         `yaml:"optimization_profile"`
+	ConfigVersion              int            `yaml:"config_version"`
+	CorrelationID              string         `yaml:"correlation_id"`
+	LastUpdated                string         `yaml:"last_updated"`
+	TriggerReason              string         `yaml:"trigger_reason"`
+	CurrentMetrics             CurrentMetrics `yaml:"current_metrics"`
+	Thresholds                 Thresholds     `yaml:"thresholds"`
+	Pipelines                  Pipelines      `yaml:"pipelines"`
+	LastProfileChangeTimestamp string         `yaml:"last_profile_change_timestamp"`
+	// PIDState is only written in PID mode; otherwise the template's values
+	// are kept. AdvancedParameters are set by the PID controller and left as
+	// they were by other policies.
+	PIDState           *PIDState           `yaml:"pid_state,omitempty"`
+	AdvancedParameters *AdvancedParameters `yaml:"advanced_phoenix_parameters,omitempty"`
+	// AttributeKeepLists are the attributes the phoenixkeep processors keep
+	// under each profile. They are set by the operator and carried over by
+	// the actuator.
+	AttributeKeepLists map[string]KeepList `yaml:"attribute_keep_lists,omitempty"`
+	Override           *Override           `yaml:"override,omitempty"`
+}
+
+// CurrentMetrics is the KPI snapshot that led to the current decision.
+type CurrentMetrics struct {
+	FullTS                     int64   `yaml:"full_ts"`
+	OptimizedTS                int64   `yaml:"optimized_ts"`
+	ExperimentalTS             int64   `yaml:"experimental_ts"`
+	CostReductionRatio         float64 `yaml:"cost_reduction_ratio"`
+	CardinalityExplosionAlerts int64   `yaml:"cardinality_explosion_alerts"`
+	CardinalityRiskProcesses   int64   `yaml:"cardinality_risk_processes"`
+}
+
+// Thresholds are the thresholds in force when the file was written.
+type Thresholds struct {
+	ConservativeMaxTS int64 `yaml:"conservative_max_ts"`
+	AggressiveMinTS   int64 `yaml:"aggressive_min_ts"`
+}
+
+// Pipelines are the pipeline enablement flags read by otelcol-main.
+type Pipelines struct {
+	FullFidelityEnabled bool `yaml:"full_fidelity_enabled"`
+	OptimizedEnabled    bool `yaml:"optimized_enabled"`
+	ExperimentalEnabled bool `yaml:"experimental_enabled"`
+}
+
+// PIDState is the PID controller memory, persisted so an actuator restart
+// resumes with the same integral instead of from zero.
+type PIDState struct {
+	Output          float64 `yaml:"output"`
+	Integral        float64 `yaml:"integral"`
+	Derivative      float64 `yaml:"derivative"`
+	LastMeasurement float64 `yaml:"last_measurement"`
+	UpdatedAt       string  `yaml:"updated_at"`
+}
+
+// AdvancedParameters are the continuous knobs derived from the PID output.
+type AdvancedParameters struct {
+	ControlOutput                    float64 `yaml:"control_output"`
+	TargetKValueForExperimentalTopK  int     `yaml:"target_k_value_for_experimental_topk"`
+	AttributeStrippingIntensityLevel string  `yaml:"attribute_stripping_intensity_level"`
+}
+
+// KeepList is the attribute allow-list of one profile. Attributes not
+// listed are stripped; an empty level is left alone.
+type KeepList struct {
+	Resource  []string `yaml:"resource,omitempty"`
+	DataPoint []string `yaml:"data_point,omitempty"`
+}
+
+// Override pins the optimisation profile until ExpiresAt. Once it expires or
+// is cleared it stays in the file with active: false as a record of the last
+// override.
+type Override struct {
+	Active    bool   `yaml:"active" json:"active"`
+	Profile   string `yaml:"profile" json:"profile"`
+	Reason    string `yaml:"reason" json:"reason"`
+	By        string `yaml:"by" json:"by"`
+	SetAt     string `yaml:"set_at" json:"set_at"`
+	ExpiresAt string `yaml:"expires_at" json:"expires_at"`
+}
+
+// Default is the file a reader assumes when the control file is missing;
+// fields absent from a file read keep these values.
+func Default() *File {
+	return &File{
+		SchemaVersion:              SchemaVersion,
+		OptimizationProfile:        ProfileConservative,
+		CorrelationID:              "template-init-cid",
+		LastUpdated:                EpochTimestamp,
+		TriggerReason:              "initial_template_state",
+		Thresholds:                 Thresholds{ConservativeMaxTS: 15000, AggressiveMinTS: 25000},
+		Pipelines:                  Pipelines{FullFidelityEnabled: true, OptimizedEnabled: true},
+		LastProfileChangeTimestamp: EpochTimestamp,
+	}
+}
+
+// IsProfile reports whether p is one of the three profiles.
+func IsProfile(p string) bool {
+	switch p {
+	case ProfileConservative, ProfileBalanced, ProfileAggressive:
+		return true
+	}
+	return false
+}
+
+// LastProfileChange parses last_profile_change_timestamp. The zero time is
+// returned when it is unset or invalid.
+func (f *File) LastProfileChange() time.Time {
+	t, err := time.Parse(time.RFC3339, f.LastProfileChangeTimestamp)
+	if err != nil || t.Unix() <= 0 {
+		return time.Time{}
+	}
+	return t
+}
+
+// ActiveAt reports whether the override pins the profile at now. An override
+// with an unparsable expiry is treated as expired.
+func (o *Override) ActiveAt(now time.Time) bool {
+	if o == nil || !o.Active || !IsProfile(o.Profile) {
+		return false
+	}
+	expires, err := time.Parse(time.RFC3339, o.ExpiresAt)
+	return err == nil && now.Before(expires)
+}
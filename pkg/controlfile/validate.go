@@ -0,0 +1,117 @@
+package controlfile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationError lists every problem found in a control file.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid control file: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks f against the current schema: enum values, ranges and
+// RFC3339 timestamps. When prev (the file f replaces) is given,
+// config_version must also have increased.
+func Validate(f, prev *File) error {
+	var problems []string
+	addf := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	timestamp := func(field, v string) {
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			addf("%s %q is not an RFC3339 timestamp", field, v)
+		}
+	}
+
+	if f.SchemaVersion != SchemaVersion {
+		addf("schema_version is %d, want %d", f.SchemaVersion, SchemaVersion)
+	}
+	if !IsProfile(f.OptimizationProfile) {
+		addf("optimization_profile %q is not conservative, balanced or aggressive", f.OptimizationProfile)
+	}
+	if f.ConfigVersion < 0 {
+		addf("config_version %d is negative", f.ConfigVersion)
+	}
+	if prev != nil && f.ConfigVersion <= prev.ConfigVersion {
+		addf("config_version %d does not increase on the previous %d", f.ConfigVersion, prev.ConfigVersion)
+	}
+	if f.CorrelationID == "" {
+		addf("correlation_id is empty")
+	}
+	timestamp("last_updated", f.LastUpdated)
+	timestamp("last_profile_change_timestamp", f.LastProfileChangeTimestamp)
+
+	m := f.CurrentMetrics
+	if m.FullTS < 0 || m.OptimizedTS < 0 || m.ExperimentalTS < 0 || m.CardinalityExplosionAlerts < 0 || m.CardinalityRiskProcesses < 0 {
+		addf("current_metrics must not be negative")
+	}
+	if m.CostReductionRatio < 0 || m.CostReductionRatio > 1 {
+		addf("current_metrics.cost_reduction_ratio %g is outside [0, 1]", m.CostReductionRatio)
+	}
+	if t := f.Thresholds; t.ConservativeMaxTS <= 0 || t.AggressiveMinTS <= t.ConservativeMaxTS {
+		addf("thresholds must satisfy 0 < conservative_max_ts (%d) < aggressive_min_ts (%d)", t.ConservativeMaxTS, t.AggressiveMinTS)
+	}
+
+	if s := f.PIDState; s != nil {
+		if s.Output < 0 || s.Output > 1 {
+			addf("pid_state.output %g is outside [0, 1]", s.Output)
+		}
+		timestamp("pid_state.updated_at", s.UpdatedAt)
+	}
+	if p := f.AdvancedParameters; p != nil {
+		if p.ControlOutput < 0 || p.ControlOutput > 1 {
+			addf("advanced_phoenix_parameters.control_output %g is outside [0, 1]", p.ControlOutput)
+		}
+		if p.TargetKValueForExperimentalTopK < 1 {
+			addf("advanced_phoenix_parameters.target_k_value_for_experimental_topk %d is below 1", p.TargetKValueForExperimentalTopK)
+		}
+		switch p.AttributeStrippingIntensityLevel {
+		case "low", "medium", "high":
+		default:
+			addf("advanced_phoenix_parameters.attribute_stripping_intensity_level %q is not low, medium or high", p.AttributeStrippingIntensityLevel)
+		}
+	}
+	profiles := make([]string, 0, len(f.AttributeKeepLists))
+	for profile := range f.AttributeKeepLists {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+	for _, profile := range profiles {
+		keep := f.AttributeKeepLists[profile]
+		if !IsProfile(profile) {
+			addf("attribute_keep_lists: %q is not conservative, balanced or aggressive", profile)
+		}
+		for _, k := range keep.Resource {
+			if k == "" {
+				addf("attribute_keep_lists.%s.resource has an empty attribute", profile)
+			}
+		}
+		for _, k := range keep.DataPoint {
+			if k == "" {
+				addf("attribute_keep_lists.%s.data_point has an empty attribute", profile)
+			}
+		}
+	}
+	if o := f.Override; o != nil && o.Active {
+		if !IsProfile(o.Profile) {
+			addf("override.profile %q is not conservative, balanced or aggressive", o.Profile)
+		}
+		if o.By == "" {
+			addf("override.by is empty")
+		}
+		timestamp("override.set_at", o.SetAt)
+		timestamp("override.expires_at", o.ExpiresAt)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package controlfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Marshal renders f on top of the template at templatePath, so the
+// template's layout and comments are kept. An empty templatePath renders f
+// on its own.
+func Marshal(f *File, templatePath string) ([]byte, error) {
+	var doc yaml.Node
+	if templatePath != "" {
+		tmplData, err := os.ReadFile(templatePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read control file template: %w", err)
+		}
+		if err := yaml.Unmarshal(tmplData, &doc); err != nil {
+			return nil, fmt.Errorf("control file template %s is malformed: %w", templatePath, err)
+		}
+		if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("control file template %s is not a YAML mapping", templatePath)
+		}
+	} else {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	var values yaml.Node
+	if err := values.Encode(f); err != nil {
+		return nil, fmt.Errorf("failed to encode control file: %w", err)
+	}
+	mergeMapping(doc.Content[0], &values)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode control file: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode control file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Write validates f, renders it with Marshal and atomically replaces path.
+func Write(path, templatePath string, f *File) error {
+	if err := Validate(f, nil); err != nil {
+		return err
+	}
+	data, err := Marshal(f, templatePath)
+	if err != nil {
+		return err
+	}
+	// Never publish a file a reader could not parse back
+	check, _, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("generated control file is invalid: %w", err)
+	}
+	if err := Validate(check, nil); err != nil {
+		return fmt.Errorf("generated control file is invalid: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// wholeMappings are written as a whole instead of being merged into the
+// template's, so entries removed from a file do not come back from it.
+var wholeMappings = map[string]bool{"attribute_keep_lists": true}
+
+// mergeMapping sets every key of src on dst, recursing into nested mappings
+// and appending keys dst does not have. Comments on dst are preserved.
+func mergeMapping(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, val := src.Content[i], src.Content[i+1]
+		var existing *yaml.Node
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value == key.Value {
+				existing = dst.Content[j+1]
+				break
+			}
+		}
+		switch {
+		case existing == nil:
+			dst.Content = append(dst.Content, key, val)
+		case existing.Kind == yaml.MappingNode && val.Kind == yaml.MappingNode && !wholeMappings[key.Value]:
+			mergeMapping(existing, val)
+		default:
+			style := existing.Style
+			existing.Kind, existing.Tag, existing.Value, existing.Content = val.Kind, val.Tag, val.Value, val.Content
+			existing.Style = val.Style
+			if val.Tag == "!!str" && style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+				existing.Style = style
+			}
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file next to path, syncs it and
+// renames it over path so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create control file directory %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary control file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary control file: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set control file permissions: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary control file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary control file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace control file %s: %w", path, err)
+	}
+	return nil
+}
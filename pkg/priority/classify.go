@@ -0,0 +1,64 @@
+package priority
+
+// Process is what rules match on.
+type Process struct {
+	ExecName    string
+	Owner       string
+	Namespace   string
+	CommandLine string
+	// Attributes are other resource attributes; only those named by
+	// AttributeKeys are looked at.
+	Attributes map[string]string
+}
+
+// Class is the outcome of classifying a process.
+type Class struct {
+	// Rule is the name of the matching rule; empty for the default.
+	Rule     string
+	Priority string
+	Tier     string
+	Policy   string
+}
+
+// Classify returns the class of the first rule p matches, or the default.
+func (r *Rules) Classify(p Process) Class {
+	for _, rule := range r.rules {
+		if rule.matches(p) {
+			return Class{Rule: rule.name, Priority: rule.outcome.Priority, Tier: rule.outcome.Tier, Policy: rule.outcome.Policy}
+		}
+	}
+	return r.fallback
+}
+
+// AttributeKeys returns the resource attributes rules match on, besides the
+// dedicated Process fields.
+func (r *Rules) AttributeKeys() []string {
+	return r.attrKeys
+}
+
+// StripAttributes returns the attributes removed under PolicyStrip.
+func (r *Rules) StripAttributes() []string {
+	return r.strip
+}
+
+func (c *compiledRule) matches(p Process) bool {
+	if c.execName != nil && !c.execName.MatchString(p.ExecName) {
+		return false
+	}
+	if c.owner != nil && !c.owner.MatchString(p.Owner) {
+		return false
+	}
+	if c.namespace != nil && !c.namespace.MatchString(p.Namespace) {
+		return false
+	}
+	if c.commandLine != nil && !c.commandLine.MatchString(p.CommandLine) {
+		return false
+	}
+	for k, re := range c.attributes {
+		v, ok := p.Attributes[k]
+		if !ok || !re.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,5 @@
+module phoenix-vnext/pkg/priority
+
+go 1.22.3
+
+require gopkg.in/yaml.v3 v3.0.1
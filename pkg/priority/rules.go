@@ -0,0 +1,208 @@
+// Package priority classifies processes by a declarative rule file, so the
+// synthetic generator's ground-truth labels and the collector's
+// phoenix.priority attribute come from the same rules. See
+// default_rules.yaml for the file format.
+package priority
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the current schema_version of rule files.
+const SchemaVersion = 1
+
+// Priorities, from most to least important.
+const (
+	Critical = "critical"
+	High     = "high"
+	Medium   = "medium"
+	Low      = "low"
+)
+
+// Policies.
+const (
+	// PolicyKeep keeps every attribute of the process.
+	PolicyKeep = "keep"
+	// PolicyStrip removes the rule file's strip_attributes.
+	PolicyStrip = "strip"
+)
+
+//go:embed default_rules.yaml
+var defaultRules []byte
+
+// File is a rule file as written.
+type File struct {
+	SchemaVersion   int      `yaml:"schema_version"`
+	StripAttributes []string `yaml:"strip_attributes"`
+	Rules           []Rule   `yaml:"rules"`
+	Default         Outcome  `yaml:"default"`
+}
+
+// Rule classifies the processes matching all of Match.
+type Rule struct {
+	Name    string `yaml:"name"`
+	Match   Match  `yaml:"match"`
+	Outcome `yaml:",inline"`
+}
+
+// Match holds the regular expressions a process must match. Empty fields
+// match anything.
+type Match struct {
+	ExecName    string            `yaml:"exec_name"`
+	Owner       string            `yaml:"owner"`
+	Namespace   string            `yaml:"namespace"`
+	CommandLine string            `yaml:"command_line"`
+	Attributes  map[string]string `yaml:"attributes"`
+}
+
+// Outcome is what a rule assigns.
+type Outcome struct {
+	Priority string `yaml:"priority"`
+	Tier     string `yaml:"tier"`
+	Policy   string `yaml:"policy"`
+}
+
+// Rules are a validated rule file, ready to classify processes.
+type Rules struct {
+	strip    []string
+	rules    []compiledRule
+	fallback Class
+	attrKeys []string
+}
+
+type compiledRule struct {
+	name                                    string
+	execName, owner, namespace, commandLine *regexp.Regexp
+	attributes                              map[string]*regexp.Regexp
+	outcome                                 Outcome
+}
+
+// Default returns the rules of the embedded default_rules.yaml.
+func Default() *Rules {
+	r, err := Parse(defaultRules)
+	if err != nil {
+		panic(fmt.Sprintf("embedded default_rules.yaml is invalid: %v", err))
+	}
+	return r
+}
+
+// Parse decodes and validates a rule file. Unknown keys are rejected so a
+// misspelled match field does not silently match everything.
+func Parse(data []byte) (*Rules, error) {
+	var f File
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("not a YAML rule file: %w", err)
+	}
+	return Compile(&f)
+}
+
+// Read loads the rule file at path. A missing file returns an error
+// wrapping fs.ErrNotExist.
+func Read(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read priority rules: %w", err)
+	}
+	r, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("priority rules %s are invalid: %w", path, err)
+	}
+	return r, nil
+}
+
+// Compile validates f and compiles its regular expressions. Every problem
+// found is reported.
+func Compile(f *File) (*Rules, error) {
+	var errs []error
+	if f.SchemaVersion != SchemaVersion {
+		errs = append(errs, fmt.Errorf("schema_version is %d, want %d", f.SchemaVersion, SchemaVersion))
+	}
+	compile := func(field, expr string) *regexp.Regexp {
+		if expr == "" {
+			return nil
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+		return re
+	}
+
+	r := &Rules{strip: f.StripAttributes}
+	keys := map[string]bool{}
+	names := map[string]bool{}
+	for i, rule := range f.Rules {
+		field := fmt.Sprintf("rules[%d]", i)
+		if rule.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is empty", field))
+		} else if names[rule.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate name %q", field, rule.Name))
+		}
+		names[rule.Name] = true
+		errs = append(errs, rule.Outcome.validate(field)...)
+		c := compiledRule{
+			name:        rule.Name,
+			execName:    compile(field+".match.exec_name", rule.Match.ExecName),
+			owner:       compile(field+".match.owner", rule.Match.Owner),
+			namespace:   compile(field+".match.namespace", rule.Match.Namespace),
+			commandLine: compile(field+".match.command_line", rule.Match.CommandLine),
+			outcome:     rule.Outcome,
+		}
+		if len(rule.Match.Attributes) > 0 {
+			c.attributes = map[string]*regexp.Regexp{}
+			for k, expr := range rule.Match.Attributes {
+				c.attributes[k] = compile(field+".match.attributes."+k, expr)
+				keys[k] = true
+			}
+		}
+		r.rules = append(r.rules, c)
+	}
+	errs = append(errs, f.Default.validate("default")...)
+	r.fallback = Class{Priority: f.Default.Priority, Tier: f.Default.Tier, Policy: f.Default.Policy}
+
+	for k := range keys {
+		r.attrKeys = append(r.attrKeys, k)
+	}
+	sort.Strings(r.attrKeys)
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (o Outcome) validate(field string) []error {
+	var errs []error
+	if !IsPriority(o.Priority) {
+		errs = append(errs, fmt.Errorf("%s: priority %q is not critical, high, medium or low", field, o.Priority))
+	}
+	switch o.Policy {
+	case PolicyKeep, PolicyStrip:
+	default:
+		errs = append(errs, fmt.Errorf("%s: policy %q is not keep or strip", field, o.Policy))
+	}
+	return errs
+}
+
+// IsPriority reports whether p is one of the known priorities.
+func IsPriority(p string) bool {
+	switch p {
+	case Critical, High, Medium, Low:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package priority
+
+import (
+	"strings"
+	"testing"
+)
+
+// The generator used to derive tiers with substring checks while the
+// collector matched anchored regexps, so these processes were classified
+// differently on each side. Owner and namespace never took part in the
+// default classification and must not now.
+func TestDefaultRules(t *testing.T) {
+	rules := Default()
+	tests := []struct {
+		name string
+		p    Process
+		want Class
+	}{
+		{
+			// Generator: tier1 (contains "critical"); collector: high
+			name: "critical application",
+			p:    Process{ExecName: "java_app_critical", Owner: "app_user", Namespace: "prod-apps"},
+			want: Class{Rule: "critical_core", Priority: Critical, Tier: "tier1_critical_core", Policy: PolicyKeep},
+		},
+		{
+			// Generator: tier1; collector: low (no java_app prefix)
+			name: "critical archetype",
+			p:    Process{ExecName: "java_critical_payments", Owner: "payments_user", Namespace: "prod-critical"},
+			want: Class{Rule: "critical_core", Priority: Critical, Tier: "tier1_critical_core", Policy: PolicyKeep},
+		},
+		{
+			name: "application prefix",
+			p:    Process{ExecName: "python_api_worker", Owner: "api_user", Namespace: "staging-apps"},
+			want: Class{Rule: "application_main", Priority: High, Tier: "tier2_application_main", Policy: PolicyKeep},
+		},
+		{
+			// Both sides anchored the application names
+			name: "application name not at the start",
+			p:    Process{ExecName: "legacy_java_app", Owner: "app_user"},
+			want: Class{Priority: Low, Tier: "tier3_support_generic", Policy: PolicyStrip},
+		},
+		{
+			// Generator: tier3 (no data_pipeline); collector: medium
+			name: "data pipeline",
+			p:    Process{ExecName: "data_pipeline_job", Owner: "data_user", Namespace: "prod-apps"},
+			want: Class{Rule: "infra_support", Priority: Medium, Tier: "tier2_infra_support", Policy: PolicyStrip},
+		},
+		{
+			// Generator: tier2 infra (substring); collector: low (anchored)
+			name: "infra name not at the start",
+			p:    Process{ExecName: "ingress-nginx-controller", Owner: "infra_user", Namespace: "infra-services"},
+			want: Class{Rule: "infra_support", Priority: Medium, Tier: "tier2_infra_support", Policy: PolicyStrip},
+		},
+		{
+			name: "privileged owner in a critical namespace",
+			p:    Process{ExecName: "kworker/0:1", Owner: "root", Namespace: "prod-critical"},
+			want: Class{Priority: Low, Tier: "tier3_support_generic", Policy: PolicyStrip},
+		},
+		{
+			name: "owner named like a rule",
+			p:    Process{ExecName: "cache_redis_server", Owner: "critical_user", Namespace: "nginx"},
+			want: Class{Priority: Low, Tier: "tier3_support_generic", Policy: PolicyStrip},
+		},
+		{
+			name: "unknown process",
+			p:    Process{},
+			want: Class{Priority: Low, Tier: "tier3_support_generic", Policy: PolicyStrip},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.Classify(tt.p); got != tt.want {
+				t.Errorf("Classify(%+v) = %+v, want %+v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRulesMetadata(t *testing.T) {
+	rules := Default()
+	if got := rules.AttributeKeys(); len(got) != 0 {
+		t.Errorf("AttributeKeys() = %v, want none", got)
+	}
+	if got := strings.Join(rules.StripAttributes(), ","); got != "process.command_line,process.pid,process.owner" {
+		t.Errorf("StripAttributes() = %s", got)
+	}
+}
+
+const ownerNamespaceRules = `
+schema_version: 1
+rules:
+  - name: prod_root
+    match: {owner: "^root$", namespace: "^prod-"}
+    priority: critical
+    tier: t1
+    policy: keep
+  - name: prod
+    match: {namespace: "^prod-"}
+    priority: high
+    tier: t2
+    policy: keep
+  - name: envoy_sidecar
+    match: {exec_name: "envoy", attributes: {k8s.container.name: "^istio-proxy$"}}
+    priority: low
+    tier: t3
+    policy: strip
+default: {priority: medium, tier: t2, policy: strip}
+`
+
+func TestOwnerAndNamespaceMatching(t *testing.T) {
+	rules, err := Parse([]byte(ownerNamespaceRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		p    Process
+		want string
+	}{
+		{"all conditions", Process{ExecName: "sshd", Owner: "root", Namespace: "prod-critical"}, "prod_root"},
+		{"owner anchored", Process{Owner: "rootless", Namespace: "prod-critical"}, "prod"},
+		{"namespace only", Process{Owner: "app_user", Namespace: "prod-apps"}, "prod"},
+		{"owner without namespace", Process{Owner: "root", Namespace: "staging-apps"}, ""},
+		{"attribute", Process{ExecName: "sidecar_envoy_proxy", Attributes: map[string]string{"k8s.container.name": "istio-proxy"}}, "envoy_sidecar"},
+		{"attribute missing", Process{ExecName: "sidecar_envoy_proxy"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.Classify(tt.p); got.Rule != tt.want {
+				t.Errorf("Classify(%+v) matched %q, want %q", tt.p, got.Rule, tt.want)
+			}
+		})
+	}
+	if got := rules.AttributeKeys(); len(got) != 1 || got[0] != "k8s.container.name" {
+		t.Errorf("AttributeKeys() = %v, want [k8s.container.name]", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse([]byte(`
+schema_version: 2
+rules:
+  - name: a
+    match: {exec_name: "("}
+    priority: urgent
+    tier: t
+    policy: keep
+  - name: a
+    priority: low
+    tier: t
+    policy: drop
+default: {priority: low, tier: t, policy: strip}
+`))
+	if err == nil {
+		t.Fatal("Parse() accepted an invalid rule file")
+	}
+	for _, want := range []string{
+		"schema_version is 2",
+		"rules[0].match.exec_name",
+		`rules[0]: priority "urgent"`,
+		`rules[1]: duplicate name "a"`,
+		`rules[1]: policy "drop"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse() error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestParseUnknownFields(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			name: "misspelled match field",
+			in:   "schema_version: 1\nrules:\n  - {name: a, match: {exec_nmae: java}, priority: high, tier: t, policy: keep}\ndefault: {priority: low, tier: t, policy: strip}\n",
+			want: "field exec_nmae not found",
+		},
+		{
+			name: "misspelled outcome field",
+			in:   "schema_version: 1\nrules: []\ndefault: {priority: low, teir: t, policy: strip}\n",
+			want: "field teir not found",
+		},
+		{
+			name: "unknown top-level field",
+			in:   "schema_version: 1\nstrip: [process.pid]\ndefault: {priority: low, tier: t, policy: strip}\n",
+			want: "field strip not found",
+		},
+		{"empty file", "", "schema_version is 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -125,7 +125,7 @@ if [ ! -f "$CONTROL_FILE_PATH_INIT" ]; then
   echo "INFO: Creating initial control file: $CONTROL_FILE_PATH_INIT from $TEMPLATE_FILE_PATH_INIT"
   
   # Use envsubst for simple substitution if yq is not preferred for init script
-  # More robust: update-control-file.sh can initialize it on first run if template is just a source.
+  # More robust: the control-actuator can initialize it on first run if template is just a source.
   # For init, a simple copy of template is often enough, letting actuator fill dynamic values.
   cp "$TEMPLATE_FILE_PATH_INIT" "$CONTROL_FILE_PATH_INIT"
 